@@ -3,9 +3,22 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"kcl-lang.io/cli/pkg/doc"
+	"kcl-lang.io/cli/pkg/options"
+	"kcl-lang.io/cli/pkg/source"
+	"kcl-lang.io/cli/pkg/testing/watch"
 	"kcl-lang.io/kcl-go/pkg/tools/gen"
 	"kcl-lang.io/kpm/pkg/api"
 )
@@ -14,9 +27,36 @@ const (
 	docDesc = `This command shows documentation for KCL modules or symbols.
 `
 	docExample = `  # Generate document for current package
-  kcl doc generate`
+  kcl doc generate
+
+  # Show the documentation of a schema
+  kcl doc show k8s.api.apps.v1.Deployment`
+
+	docShowDesc = `This command shows the documentation of a KCL symbol in the terminal.
+
+The symbol is '.' for the current package, a package import path, or a schema
+such as 'k8s.api.apps.v1.Deployment', resolved through the dependencies of the
+package in kcl.mod. The dependency named by the symbol and the ones imported by
+the package are loaded. An unambiguous suffix of a schema of the package, such as
+'App' or 'models.App', also selects it. A schema is shown with its docstring, its attributes with their types,
+defaults and required flags, its inheritance chain and its examples.
+`
+	docShowExample = `  # Show the schemas of the current package
+  kcl doc show .
+
+  # Show the documentation of a schema of a dependency
+  kcl doc show k8s.api.apps.v1.Deployment
+
+  # Show the documentation of a schema as JSON
+  kcl doc show k8s.api.apps.v1.Deployment --format json`
 
 	docGenDesc = `This command generates documents for KCL modules.
+
+The site format generates a static site in the docs/ folder: an index page
+listing the schemas of the package and of its dependencies, a page per schema
+linking to its base, referenced and source schemas, the JSON Schema of each
+schema and a prebuilt search index. With '--watch', the site is regenerated
+on each change of the package and served on '--addr'.
 `
 	docGenExample = `  # Generate Markdown document for current package
   kcl doc generate
@@ -31,7 +71,13 @@ const (
   kcl doc generate --file-path <package path>
 
   # Generate Markdown document for specific package to a <target directory>
-  kcl doc generate --file-path <package path> --target <target directory>`
+  kcl doc generate --file-path <package path> --target <target directory>
+
+  # Generate a static site with source links to the repository
+  kcl doc generate --format site --source-url "https://github.com/org/repo/blob/main/{path}#L{line}"
+
+  # Generate a static site and serve it on localhost, regenerating it on change
+  kcl doc generate --format site --watch`
 )
 
 // NewDocCmd returns the doc command.
@@ -46,6 +92,7 @@ func NewDocCmd() *cobra.Command {
 	}
 
 	cmd.AddCommand(NewDocGenerateCmd())
+	cmd.AddCommand(NewDocShowCmd())
 
 	return cmd
 }
@@ -53,12 +100,19 @@ func NewDocCmd() *cobra.Command {
 // NewDocGenerateCmd returns the doc generate command.
 func NewDocGenerateCmd() *cobra.Command {
 	o := gen.GenOpts{}
+	site := &docSiteOptions{}
 	cmd := &cobra.Command{
 		Use:     "generate",
 		Short:   "Generates documents from code and examples",
 		Long:    docGenDesc,
 		Example: docGenExample,
 		RunE: func(*cobra.Command, []string) error {
+			if o.Format == siteFormat {
+				return generateSite(&o, site)
+			}
+			if site.Watch {
+				return fmt.Errorf("--watch requires the %s format", siteFormat)
+			}
 			genContext, err := o.ValidateComplete()
 			if err != nil {
 				fmt.Println(fmt.Errorf("generate failed: %s", err))
@@ -91,7 +145,7 @@ func NewDocGenerateCmd() *cobra.Command {
 outside of the KCL package root directory.
 If not specified, the current work directory will be used as the KCL package root.`)
 	cmd.Flags().StringVar(&o.Format, "format", string(gen.Markdown),
-		`The document format to generate. Supported values: markdown, html, openapi.`)
+		`The document format to generate. Supported values: markdown, html, openapi, site.`)
 	cmd.Flags().StringVar(&o.Target, "target", "",
 		`If not specified, the current work directory will be used. A docs/ folder will be created under the target directory.`)
 	cmd.Flags().StringVar(&o.TemplateDir, "template", "",
@@ -100,6 +154,174 @@ If not specified, the current work directory will be used as the KCL package roo
 		`Do not generate documentation for deprecated schemas.`)
 	cmd.Flags().BoolVar(&o.EscapeHtml, "escape-html", false,
 		`Whether to escape html symbols when the output format is markdown. Always scape when the output format is html. Default to false.`)
+	cmd.Flags().StringVar(&site.SourceURL, "source-url", "",
+		`The template of the source links of the site, where {path} is the file path relative to the package root and {line} the line.`)
+	cmd.Flags().BoolVar(&site.Watch, "watch", false,
+		`Serve the site and regenerate it on each change of the package.`)
+	cmd.Flags().StringVar(&site.Addr, "addr", "localhost:8000",
+		`The address to serve the site on with --watch.`)
+
+	return cmd
+}
+
+// NewDocShowCmd returns the doc show command.
+func NewDocShowCmd() *cobra.Command {
+	var path, format string
+	cmd := &cobra.Command{
+		Use:     "show <symbol>",
+		Short:   "Show the documentation of a symbol",
+		Long:    docShowDesc,
+		Example: docShowExample,
+		Args:    cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if format != options.Text && format != options.Json {
+				return fmt.Errorf("invalid format %q, must be one of text or json", format)
+			}
+			symbol := doc.CurrentPackage
+			if len(args) > 0 {
+				symbol = args[0]
+			}
+			if path == "" {
+				pwd, err := os.Getwd()
+				if err != nil {
+					return err
+				}
+				path = pwd
+			}
+			idx, err := options.LoadDocIndex(path, docShowDeps(path, symbol))
+			if err != nil {
+				return err
+			}
+			sym, err := idx.Lookup(symbol)
+			if err != nil {
+				return err
+			}
+			if format == options.Json {
+				return doc.WriteJSON(os.Stdout, sym)
+			}
+			return doc.WriteText(os.Stdout, sym)
+		},
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().StringVar(&path, "file-path", "",
+		`Relative or absolute path to the KCL package root. If not specified, the current work directory will be used.`)
+	cmd.Flags().StringVar(&format, "format", options.Text,
+		`The output format. Supported values: text, json.`)
 
 	return cmd
 }
+
+// docShowDeps returns the filter of the dependencies loaded to show the
+// symbol: the dependency named by the symbol and the ones imported by the
+// package in path. All the dependencies are loaded when the sources of the
+// package cannot be parsed.
+func docShowDeps(path, symbol string) func(dep string) bool {
+	head, _, _ := strings.Cut(symbol, ".")
+	imported := map[string]bool{head: true}
+	dirs, err := source.PackageDirs([]string{filepath.Join(path, "...")})
+	if err != nil {
+		return nil
+	}
+	for _, dir := range dirs {
+		pkg, err := source.LoadPackage(dir)
+		if err != nil {
+			return nil
+		}
+		for _, f := range pkg.Files {
+			for _, imp := range f.Imports {
+				first, _, _ := strings.Cut(imp.Path, ".")
+				imported[first] = true
+			}
+		}
+	}
+	// Dependency names are imported with underscores, such as `import my_dep`
+	// for `my-dep`.
+	return func(dep string) bool {
+		return imported[dep] || imported[strings.ReplaceAll(dep, "-", "_")]
+	}
+}
+
+// siteFormat is the document format of the static site.
+const siteFormat = "site"
+
+// docSiteOptions holds the options of the site format.
+type docSiteOptions struct {
+	// SourceURL is the template of the source links.
+	SourceURL string
+	// Watch serves the site and regenerates it on change.
+	Watch bool
+	// Addr is the address to serve the site on.
+	Addr string
+}
+
+// generateSite generates the static site of the package in the docs folder
+// of the target directory.
+func generateSite(o *gen.GenOpts, site *docSiteOptions) error {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	path, target := o.Path, o.Target
+	if path == "" {
+		path = pwd
+	}
+	if target == "" {
+		target = pwd
+	}
+	out := filepath.Join(target, "docs")
+	build := func() error {
+		idx, err := options.LoadDocIndex(path, nil)
+		if err != nil {
+			return err
+		}
+		return doc.WriteSite(idx, out, doc.SiteOptions{Title: idx.Modules[0].Name, SourceURL: site.SourceURL})
+	}
+	if err := build(); err != nil {
+		return fmt.Errorf("doc generate failed: %w", err)
+	}
+	fmt.Printf("doc generate complete and check generated docs in %s\n", out)
+	if !site.Watch {
+		return nil
+	}
+	return serveSite(path, out, site.Addr, build)
+}
+
+// serveSite serves the site in dir on addr and rebuilds it on each change of
+// the package in path until interrupted.
+func serveSite(path, dir, addr string, build func() error) error {
+	w, err := watch.New(path)
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: http.FileServer(http.Dir(dir))}
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+	fmt.Printf("serving the docs on http://%s, watching for changes...\n", ln.Addr())
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintln(os.Stderr, err)
+			stop()
+		}
+	}()
+	for {
+		if _, err := w.Wait(ctx); err != nil {
+			if ctx.Err() != nil {
+				return srv.Shutdown(context.Background())
+			}
+			return err
+		}
+		start := time.Now()
+		if err := build(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		fmt.Printf("[%s] docs regenerated (%s)\n", start.Format("15:04:05"), time.Since(start).Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDocShowDeps(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"kcl.mod":       "[package]\nname = \"app\"\n",
+		"main.k":        "import k8s.api.apps.v1\n\napp = v1.Deployment {}\n",
+		"models/app.k":  "import my_lib.models\nimport .base\n",
+		"models/base.k": "schema Base:\n    name: str\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	include := docShowDeps(dir, "helloworld.App")
+	if include == nil {
+		t.Fatal("expected a filter of the dependencies")
+	}
+	for dep, want := range map[string]bool{"k8s": true, "my-lib": true, "helloworld": true, "konfig": false} {
+		if got := include(dep); got != want {
+			t.Errorf("include(%q) = %v, want %v", dep, got, want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"kcl-lang.io/cli/pkg/options"
+)
+
+const (
+	exportDesc = `This command converts the schemas of a KCL package to other formats.
+
+Supported conversion modes:
+- jsonschema:      convert KCL schemas to JSON Schema
+- openapi:         convert KCL schemas to an OpenAPI 3.1 document
+- crd:             convert KCL schemas to Kubernetes CRDs
+- gostruct:        convert KCL schemas to Go structs
+- typescript:      convert KCL schemas to TypeScript interfaces
+
+Docstrings, defaults and enums are kept, and the check expressions comparing
+attributes or their length with numbers, matching regexes or testing list
+membership become constraints where the target can express them. The other
+checks are kept as comments or the 'x-kcl-checks' JSON Schema extension.
+
+One file is written per schema in the output directory, or with '--bundle',
+a single file named after the module, or '--output'. The output '-' writes
+the bundle to the standard output.
+`
+	exportExample = `  # Export the schemas of the current package to JSON Schema files
+  kcl export -m jsonschema
+
+  # Export the schemas of a package to an OpenAPI document
+  kcl export -m openapi --bundle -o openapi.yaml path/to/pkg
+
+  # Export the schemas as Kubernetes CRDs of a group
+  kcl export -m crd --crd-group apps.example.com --crd-version v1alpha1 -o crds
+
+  # Print the Go structs of the schemas
+  kcl export -m gostruct -o -
+
+  # Export the schemas to TypeScript interfaces
+  kcl export -m typescript -o src/models`
+)
+
+// NewExportCmd returns the export command.
+func NewExportCmd() *cobra.Command {
+	o := options.NewExportOptions()
+	cmd := &cobra.Command{
+		Use:     "export",
+		Short:   "KCL export tool",
+		Long:    exportDesc,
+		Example: exportExample,
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				o.Path = args[0]
+			}
+			return o.Run()
+		},
+		SilenceUsage: true,
+	}
+
+	cmd.Args = cobra.MaximumNArgs(1)
+	cmd.Flags().StringVarP(&o.Mode, "mode", "m", o.Mode,
+		"Specify the export mode: jsonschema, openapi, crd, gostruct or typescript")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", "",
+		"Specify the output directory, or the output file path with --bundle")
+	cmd.Flags().BoolVarP(&o.Force, "force", "f", false,
+		"Force overwrite output files")
+	cmd.Flags().BoolVar(&o.Bundle, "bundle", false,
+		"Write all the schemas to a single file")
+	cmd.Flags().StringVar(&o.Group, "crd-group", o.Group,
+		"Specify the API group of the CRDs")
+	cmd.Flags().StringVar(&o.Version, "crd-version", o.Version,
+		"Specify the API version of the CRDs")
+	cmd.Flags().StringVarP(&o.Package, "package", "p", "",
+		"Specify the Go package name. Default is the module name")
+
+	return cmd
+}
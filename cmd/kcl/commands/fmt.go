@@ -7,43 +7,85 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
-	kcl "kcl-lang.io/kcl-go"
+	"kcl-lang.io/cli/pkg/options"
 )
 
 const (
 	fmtDesc = `This command formats all kcl files of the current crate.
+
+With '--check' or '--dry-run', the files requiring formatting are reported and
+the command fails without modifying them. With '--diff', a unified diff of the
+changes is printed instead of writing the files.
+
+The path '-' reads KCL source from stdin and writes the formatted code to
+stdout, which editor integrations can use.
+
+The style is configured by a '.kclfmt.yaml' file or the '[fmt]' section of
+'kcl.mod', discovered by walking up from each formatted path. In a directory
+containing both, '.kclfmt.yaml' takes precedence. The supported options are:
+
+  indent_width     Number of spaces of an indentation level, 4 by default.
+  max_line_length  Length beyond which single-line lists and dicts are
+                   wrapped, one element per line. 0 disables wrapping.
+  sort_imports     Sort the leading import statements by path.
+  group_imports    Group system, external and relative imports, sorted.
+  trailing_comma   'always', 'never' or 'preserve' trailing commas in
+                   multi-line lists and dicts.
+  quote_style      'double', 'single' or 'preserve' string quotes.
+
+With '--lines <start>:<end>', only the top-level statements overlapping the
+1-based inclusive line range of a single file are formatted, and the rest of
+the file is left byte-identical. With '--output json', the changes are printed
+as a JSON list of text edits using the 0-based positions of the language
+server protocol, and the file is not modified.
 `
 	fmtExample = `  # Format the single file
   kcl fmt /path/to/file.k
 
   # Format all files in this folder recursively
-  kcl fmt ./...`
+  kcl fmt ./...
+
+  # Check the formatting and print the required changes
+  kcl fmt ./... --check --diff
+
+  # Format all files except the generated ones
+  kcl fmt ./... --exclude 'gen/*' --exclude '*_gen.k'
+
+  # Format the code read from stdin
+  cat main.k | kcl fmt -
+
+  # Format the statements overlapping lines 10 to 40
+  kcl fmt main.k --lines 10:40
+
+  # Print the text edits formatting lines 10 to 40 of the code read from stdin
+  cat main.k | kcl fmt - --lines 10:40 --output json
+
+  # Format with the style of a given config file
+  kcl fmt ./... --config ../style/.kclfmt.yaml`
 )
 
 // NewFmtCmd returns the fmt command.
 func NewFmtCmd() *cobra.Command {
-	o := new(kcl.FormatPathOptions)
+	o := options.NewFmtOptions()
 	cmd := &cobra.Command{
 		Use:     "fmt",
 		Short:   "KCL format tool",
 		Long:    fmtDesc,
 		Example: fmtExample,
 		RunE: func(_ *cobra.Command, args []string) error {
-			var changedPaths []string
 			if len(args) == 0 {
 				args = append(args, ".")
 			}
-			for _, p := range args {
-				paths, err := kcl.FormatPathWithOptions(p, kcl.FormatPathOptions{
-					DryRun: o.DryRun,
-				})
-				if err != nil {
-					return err
-				}
-				changedPaths = append(changedPaths, paths...)
+			o.Paths = args
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			changedPaths, err := o.Run()
+			if err != nil {
+				return err
 			}
-			if len(changedPaths) > 0 {
-				fmt.Println(strings.Join(changedPaths, "\n"))
+			if len(changedPaths) > 0 && !o.Diff && o.Output != options.Json {
+				fmt.Fprintln(o.Writer, strings.Join(changedPaths, "\n"))
 			}
 			if o.DryRun && len(changedPaths) > 0 {
 				return fmt.Errorf("%d KCL file(s) require formatting", len(changedPaths))
@@ -55,6 +97,12 @@ func NewFmtCmd() *cobra.Command {
 
 	flags := cmd.Flags()
 	flags.BoolVar(&o.DryRun, "dry-run", false, "Report files requiring formatting without modifying them.")
+	flags.BoolVar(&o.DryRun, "check", false, "Report files requiring formatting without modifying them, same as --dry-run.")
+	flags.BoolVar(&o.Diff, "diff", false, "Print a unified diff of the formatting changes instead of modifying the files.")
+	flags.StringVar(&o.Config, "config", "", "Formatter config file, a '.kclfmt.yaml' file or a 'kcl.mod' file with a [fmt] section, overriding the discovered one.")
+	flags.StringVar(&o.Lines, "lines", "", "Format only the statements overlapping the line range <start>:<end> of a single file.")
+	flags.StringVar(&o.Output, "output", options.Text, "Specify the output format, text or json for the text edits of a line range.")
+	flags.StringArrayVar(&o.Excludes, "exclude", []string{}, "Skip the files or directories matching the glob pattern.")
 
 	return cmd
 }
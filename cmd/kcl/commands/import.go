@@ -17,15 +17,25 @@ Supported conversion modes:
 - gostruct:        convert Go struct to KCL schema
 - jsonschema:      convert JSON schema to KCL schema
 - terraformschema: convert Terraform schema to KCL schema
-- openapi:         convert OpenAPI spec to KCL schema
+- openapi:         convert OpenAPI 2.0 and 3.x specs, split across files or not, to KCL schema
 - crd:             convert Kubernetes CRD to KCL schema
+- helm:            convert Helm chart values to a KCL package with a Values schema
+- k8s:             convert Kubernetes manifests to instances of the k8s module schemas
+- protobuf:        convert protocol buffers messages and enums to KCL schemas and types
+- compose:         convert Docker Compose files to instances of typed compose schemas
 - auto:            automatically detect the input format
 
-Input can be a local file path or an HTTP/HTTPS URL.
+Input can be a local file path or an HTTP/HTTPS URL. The documents of URLs are
+cached by ETag, separately for each token and set of headers, and the token and
+headers can be set with the KCL_IMPORT_TOKEN and KCL_IMPORT_HEADERS environment
+variables.
 `
 	importExample = `  # Generate KCL models from OpenAPI spec
   kcl import -m openapi swagger.json
 
+  # Generate KCL models of the Pet schema of an OpenAPI 3 spec split across files, and their dependencies
+  kcl import -m openapi api/openapi.yaml --include '^Pet$'
+
   # Generate KCL models from Kubernetes CRD
   kcl import -m crd crd.yaml
 
@@ -44,12 +54,37 @@ Input can be a local file path or an HTTP/HTTPS URL.
   # Generate KCL models from Terraform provider schema
   kcl import -m terraformschema schema.json
 
+  # Generate a KCL package with the Values schema of a Helm chart directory or archive
+  kcl import -m helm ./nginx
+  kcl import -m helm nginx-0.1.0.tgz -o nginx-values
+
+  # Generate KCL code instantiating the k8s module schemas from Kubernetes manifests
+  kcl import -m k8s manifests/ -o app
+
+  # Generate KCL models from protocol buffers files, resolving imports in the proto path
+  kcl import -m protobuf api/acme/v1/config.proto --proto-path api -o models
+
+  # Generate KCL code instantiating typed compose schemas from a Docker Compose file
+  kcl import -m compose docker-compose.yml
+
+  # Infer a single Config schema describing many sample data files
+  kcl import --infer-schema samples/*.yaml --schema-name Config -o config.k
+
+  # Generate a KCL package from a directory tree of JSON, YAML and TOML files
+  kcl import -r ./configs --output-dir ./kcl
+
   # Generate KCL models from Go structs
   kcl import -m gostruct schema.go
 
   # Generate KCL models from a remote JSON URL
   kcl import -m json https://api.github.com/meta
 
+  # Generate KCL models from an OpenAPI spec of an authenticated server, following its $refs
+  kcl import -m openapi https://specs.example.com/api.yaml --token $TOKEN --ca-file ca.pem
+
+  # Generate KCL models from the cached documents of URLs only
+  kcl import -m openapi https://specs.example.com/api.yaml --offline
+
   # Generate KCL models from a raw GitHub YAML file
   kcl import https://raw.githubusercontent.com/kcl-lang/cli/main/examples/settings/settings.yaml`
 )
@@ -73,13 +108,43 @@ func NewImportCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&o.Mode, "mode", "m", "auto",
 		"Specify the import mode. Default is mode")
 	cmd.Flags().StringVarP(&o.Output, "output", "o", "",
-		"Specify the output file path")
+		"Specify the output file path, or the output package directory of the helm, k8s and protobuf modes")
+	cmd.Flags().StringVar(&o.OutputDir, "output-dir", "",
+		"Specify the output package directory of a tree of configs, keeping its structure")
+	cmd.Flags().BoolVarP(&o.Recursive, "recursive", "r", false,
+		"Import the files of the input directories recursively")
 	cmd.Flags().BoolVarP(&o.Force, "force", "f", false,
 		"Force overwrite output file")
 	cmd.Flags().BoolVarP(&o.SkipValidation, "skip-validation", "s", false,
 		"Skips validation of spec prior to generation")
 	cmd.Flags().StringVarP(&o.ModelPackage, "package", "p", "models",
 		"The package to save the models. Default is models")
+	cmd.Flags().StringVar(&o.K8sVersion, "k8s-version", "",
+		"The version of the k8s module whose schemas are instantiated and added by the k8s mode. Default is the latest")
+	cmd.Flags().StringSliceVar(&o.ProtoPath, "proto-path", []string{},
+		"The directories in which the imports of the protobuf mode are resolved")
+	cmd.Flags().BoolVar(&o.InferSchema, "infer-schema", false,
+		"Infer a single schema describing all the input data files")
+	cmd.Flags().StringVar(&o.SchemaName, "schema-name", "Config",
+		"The name of the schema inferred by --infer-schema. Default is Config")
+	cmd.Flags().StringArrayVar(&o.Include, "include", []string{},
+		"The regexes of the names of the schemas generated by the openapi mode, with their dependencies")
+	cmd.Flags().StringArrayVar(&o.Exclude, "exclude", []string{},
+		"The regexes of the names of the schemas not generated by the openapi mode")
+	cmd.Flags().StringArrayVarP(&o.Headers, "header", "H", []string{},
+		"The `Name: value` headers of the requests of URL inputs")
+	cmd.Flags().StringVar(&o.Token, "token", "",
+		"The bearer token of the requests of URL inputs")
+	cmd.Flags().BoolVar(&o.InsecureSkipTLSVerify, "insecure-skip-tls-verify", false,
+		"Skip the verification of the certificates of the servers of URL inputs")
+	cmd.Flags().StringVar(&o.CAFile, "ca-file", "",
+		"The PEM file of the additional certificate authorities of the servers of URL inputs")
+	cmd.Flags().StringVar(&o.CacheDir, "cache-dir", "",
+		"The cache directory of the documents of URL inputs. Default is the user cache directory")
+	cmd.Flags().BoolVar(&o.Offline, "offline", false,
+		"Read the documents of URL inputs from the cache only")
+	cmd.Flags().StringVar(&o.ComposePackage, "compose-package", "",
+		"The package of the compose schemas instantiated by the compose mode, instead of generated schemas")
 
 	return cmd
 }
@@ -3,14 +3,65 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/spf13/cobra"
+	"kcl-lang.io/cli/pkg/fs"
+	"kcl-lang.io/cli/pkg/lint"
 	"kcl-lang.io/cli/pkg/options"
+	"kcl-lang.io/cli/pkg/source"
+	kcl "kcl-lang.io/kcl-go"
+	"kcl-lang.io/kpm/pkg/api"
 )
 
 const (
 	lintDesc = `This command lints the kcl code. 'kcl lint' takes multiple input for arguments.
 
-For example, 'kcl lint path/to/kcl.k' will lint the file named path/to/kcl.k 
+For example, 'kcl lint path/to/kcl.k' will lint the file named path/to/kcl.k
+
+The code is compiled first, then the lint rules listed by '--list-rules' are
+run on the local packages. The severity of each rule is configured in the
+[lint] section of kcl.mod, where 'off' disables a rule:
+
+  [lint]
+  exclude = ["vendor/*"]
+
+  [lint.rules]
+  missing-docstring = "off"
+  unused-import = "error"
+
+A '# kcl-lint: disable=RULE[,RULE]' comment suppresses the findings of its
+line when trailing code, or of the next statement line otherwise. The
+'# kcl-lint: disable-file=RULE' comment suppresses them in the whole file and
+'all' matches any rule. The command fails when a finding has the error
+severity.
+
+With '--fix', the automatic fixes of the findings, such as removing unused
+imports, sorting imports or adding missing option() defaults, are applied as
+text edits, and the modified files are formatted like 'kcl fmt' does. Each
+applied fix is reported. The packages are compiled again after the fixes and
+the fixes are reverted if the rendered output changed. With '--fix-dry-run',
+the changes are printed as a unified diff without modifying the files.
+
+With '--rules', the public top-level lambdas of a KCL package are run as
+additional rules named after the lambdas. Each lambda receives a view of the
+linted package, a dict with the 'path' of the package and its 'schemas', each
+with a 'name', 'package', 'doc', 'file', 'line' and 'attributes' holding the
+'name', 'type', 'doc', 'default', 'required' and 'line' of each attribute. It
+returns a list of findings, dicts with a 'message' and either a 'file' and a
+'line', or the 'schema' and optionally the 'attribute' they refer to:
+
+  no_docs = lambda pkg {
+      [{schema = s.name, message = "${s.name} has no docstring"} for s in pkg.schemas if not s.doc]
+  }
+
+Custom rules are configured and suppressed like the built-in rules.
 `
 	lintExample = `  # Lint a single file and output YAML
   kcl lint path/to/kcl.k
@@ -22,31 +73,339 @@ For example, 'kcl lint path/to/kcl.k' will lint the file named path/to/kcl.k
   kcl lint oci://ghcr.io/kcl-lang/helloworld
 
   # Lint the current package
-  kcl lint`
+  kcl lint
+
+  # Lint all the packages recursively and output the findings as JSON
+  kcl lint ./... --output-format json
+
+  # Fix the findings with automatic fixes
+  kcl lint ./... --fix
+
+  # Print the changes of the automatic fixes without applying them
+  kcl lint ./... --fix-dry-run
+
+  # Run the custom rules of a KCL package
+  kcl lint ./... --rules path/to/rules
+
+  # List the lint rules
+  kcl lint --list-rules`
 )
 
+// LintOptions holds the options for the lint command.
+type LintOptions struct {
+	*options.RunOptions
+	// OutputFormat is the findings format, text or json.
+	OutputFormat string
+	// Disable are the IDs of the rules to disable.
+	Disable []string
+	// ListRules lists the rules instead of linting.
+	ListRules bool
+	// Fix applies the automatic fixes of the findings.
+	Fix bool
+	// FixDryRun prints the changes of the automatic fixes without applying
+	// them.
+	FixDryRun bool
+	// Rules is the directory of a KCL package of custom rules.
+	Rules string
+}
+
 // NewLintCmd returns the lint command.
 func NewLintCmd() *cobra.Command {
-	o := options.NewRunOptions()
+	o := &LintOptions{RunOptions: options.NewRunOptions()}
 	cmd := &cobra.Command{
 		Use:     "lint",
 		Short:   "Lint KCL codes.",
 		Long:    lintDesc,
 		Example: lintExample,
 		RunE: func(_ *cobra.Command, args []string) error {
-			if err := o.Complete(args); err != nil {
-				return err
+			if o.ListRules {
+				rules, err := lintRules(o)
+				if err != nil {
+					return err
+				}
+				return lint.WriteRules(os.Stdout, rules)
 			}
-			if err := o.Validate(); err != nil {
-				return err
-			}
-			o.CompileOnly = true
-			return o.Run()
+			return runLint(o, args)
 		},
 		SilenceUsage: true,
 	}
 
-	appendLangFlags(o, cmd.Flags())
+	appendLangFlags(o.RunOptions, cmd.Flags())
+	cmd.Flags().StringVar(&o.OutputFormat, "output-format", options.Text,
+		"Specify the findings format, text or json")
+	cmd.Flags().StringSliceVar(&o.Disable, "disable", []string{},
+		"Specify the lint rules to disable")
+	cmd.Flags().BoolVar(&o.ListRules, "list-rules", false,
+		"List the lint rules and their default severity")
+	cmd.Flags().BoolVar(&o.Fix, "fix", false,
+		"Apply the automatic fixes of the findings")
+	cmd.Flags().BoolVar(&o.FixDryRun, "fix-dry-run", false,
+		"Print the changes of the automatic fixes without applying them")
+	cmd.Flags().StringVar(&o.Rules, "rules", "",
+		"Specify a KCL package of custom rules written as lambdas")
 
 	return cmd
 }
+
+func runLint(o *LintOptions, args []string) error {
+	if o.OutputFormat != options.Text && o.OutputFormat != options.Json {
+		return fmt.Errorf("invalid output format %q, must be one of text or json", o.OutputFormat)
+	}
+	// Recursive package patterns are compiled package by package.
+	var entries, patterns []string
+	for _, arg := range args {
+		if strings.HasSuffix(arg, "...") {
+			patterns = append(patterns, arg)
+		} else {
+			entries = append(entries, arg)
+		}
+	}
+	dirs, err := source.PackageDirs(patterns)
+	if err != nil {
+		return err
+	}
+	var groups [][]string
+	if len(entries) > 0 || len(patterns) == 0 {
+		groups = append(groups, entries)
+	}
+	for _, dir := range dirs {
+		groups = append(groups, []string{dir})
+	}
+	for _, group := range groups {
+		if err := compileOnly(o.RunOptions, group); err != nil {
+			return err
+		}
+	}
+	targets := lintTargets(entries, dirs)
+	if len(targets) == 0 {
+		return nil
+	}
+	rules, err := lintRules(o)
+	if err != nil {
+		return err
+	}
+	// Each target is linted with the config of its module.
+	configs := map[string]*lint.Config{}
+	for i, t := range targets {
+		root := source.FindModRoot(t.Dir)
+		config, ok := configs[root]
+		if !ok {
+			if config, err = loadLintConfig(root); err != nil {
+				return err
+			}
+			for _, id := range o.Disable {
+				config.SetSeverity(id, lint.Off)
+			}
+			if err := config.Validate(rules); err != nil {
+				return err
+			}
+			configs[root] = config
+		}
+		targets[i].Config = config
+	}
+	linter := lint.NewLinter(rules, nil)
+	var findings []lint.Finding
+	if o.Fix || o.FixDryRun {
+		findings, err = fixLint(o, linter, targets, groups)
+	} else {
+		findings, err = linter.Lint(targets)
+	}
+	if err != nil {
+		return err
+	}
+	if o.OutputFormat == options.Json {
+		err = lint.WriteJSON(os.Stdout, findings)
+	} else {
+		err = lint.WriteText(os.Stdout, findings)
+	}
+	if err != nil {
+		return err
+	}
+	if n := lint.Count(findings, lint.Error); n > 0 {
+		return fmt.Errorf("%d lint error(s) found", n)
+	}
+	return nil
+}
+
+// fixLint applies the automatic fixes and returns the fixed findings followed
+// by the remaining ones. The fixes are reverted when the rendered output of
+// the compile groups changed.
+func fixLint(o *LintOptions, linter *lint.Linter, targets []lint.Target, groups [][]string) ([]lint.Finding, error) {
+	fmtOpts := options.NewFmtOptions()
+	report, err := linter.Fix(targets, fmtOpts.FormatCode)
+	if err != nil {
+		return nil, err
+	}
+	findings := append(report.Fixed, report.Remaining...)
+	if len(report.Files) == 0 {
+		return findings, nil
+	}
+	if o.FixDryRun {
+		// Keep the JSON output parseable.
+		w := os.Stdout
+		if o.OutputFormat == options.Json {
+			w = os.Stderr
+		}
+		for _, f := range report.Files {
+			fmt.Fprint(w, f.Diff())
+		}
+		return findings, nil
+	}
+	var before []string
+	for _, group := range groups {
+		out, err := render(o.RunOptions, group)
+		if err != nil {
+			return nil, err
+		}
+		before = append(before, out)
+	}
+	restore := func() error {
+		for _, f := range report.Files {
+			if err := os.WriteFile(f.Path, f.Old, 0644); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, f := range report.Files {
+		if err := os.WriteFile(f.Path, f.New, 0644); err != nil {
+			return nil, errors.Join(err, restore())
+		}
+	}
+	for i, group := range groups {
+		out, err := render(o.RunOptions, group)
+		if err == nil && out != before[i] {
+			err = fmt.Errorf("the output of %s changed", strings.Join(group, " "))
+		}
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("the fixes were reverted: %w", err), restore())
+		}
+	}
+	return findings, nil
+}
+
+// lintRules returns the built-in rules followed by the custom rules.
+func lintRules(o *LintOptions) ([]*lint.Rule, error) {
+	rules := lint.Rules()
+	if o.Rules == "" {
+		return rules, nil
+	}
+	set, err := lint.LoadCustomRules(o.Rules)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range set.Rules {
+		for _, builtin := range rules {
+			if r.ID == builtin.ID {
+				return nil, fmt.Errorf("custom rule %s conflicts with a built-in rule", r.ID)
+			}
+		}
+	}
+	set.Describe = func(p *source.Package) (*lint.View, error) {
+		kpmPkg, err := api.GetKclPackage(p.Dir)
+		if err != nil {
+			return nil, err
+		}
+		spec, err := kpmPkg.ExportSwaggerV2Spec()
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(spec)
+		if err != nil {
+			return nil, err
+		}
+		return lint.ViewFromOpenAPI(data, p)
+	}
+	set.Evaluate = func(files []string, program string) (string, error) {
+		tmp, err := os.MkdirTemp("", "kcl-lint")
+		if err != nil {
+			return "", err
+		}
+		defer os.RemoveAll(tmp)
+		entry := filepath.Join(tmp, "rules.k")
+		if err := os.WriteFile(entry, []byte(program), 0644); err != nil {
+			return "", err
+		}
+		depsOpt, err := options.LoadDepsFrom(o.Rules, true)
+		if err != nil {
+			return "", err
+		}
+		r, err := kcl.RunFiles(append(append([]string{}, files...), entry), kcl.WithWorkDir(o.Rules), *depsOpt)
+		if err != nil {
+			return "", err
+		}
+		return r.GetRawJsonResult(), nil
+	}
+	return append(rules, set.Rules...), nil
+}
+
+// render returns the output of running the entries.
+func render(base *options.RunOptions, entries []string) (string, error) {
+	var buf bytes.Buffer
+	o := *base
+	o.Entries = nil
+	o.Output = ""
+	o.Writer = &buf
+	if err := o.Complete(entries); err != nil {
+		return "", err
+	}
+	if err := o.Validate(); err != nil {
+		return "", err
+	}
+	if err := o.Run(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// compileOnly compiles the entries to report compile errors.
+func compileOnly(base *options.RunOptions, entries []string) error {
+	o := *base
+	o.Entries = nil
+	if err := o.Complete(entries); err != nil {
+		return err
+	}
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	o.CompileOnly = true
+	return o.Run()
+}
+
+// lintTargets returns the local packages to run the lint rules on. Remote
+// entries such as OCI packages are only compiled.
+func lintTargets(entries, dirs []string) []lint.Target {
+	if len(entries) == 0 && len(dirs) == 0 {
+		entries = []string{"."}
+	}
+	var targets []lint.Target
+	files := map[string][]string{}
+	for _, e := range entries {
+		switch {
+		case fs.IsDir(e):
+			dirs = append(dirs, e)
+		case fs.FileExists(e) && source.IsKclFile(e):
+			dir := filepath.Dir(e)
+			if _, ok := files[dir]; !ok {
+				targets = append(targets, lint.Target{Dir: dir})
+			}
+			files[dir] = append(files[dir], e)
+		}
+	}
+	for i := range targets {
+		targets[i].Files = files[targets[i].Dir]
+	}
+	for _, dir := range dirs {
+		targets = append(targets, lint.Target{Dir: dir})
+	}
+	return targets
+}
+
+// loadLintConfig reads the lint config of the module in root.
+func loadLintConfig(root string) (*lint.Config, error) {
+	modFile := filepath.Join(root, source.ModFile)
+	if !fs.FileExists(modFile) {
+		return &lint.Config{}, nil
+	}
+	return lint.LoadConfig(modFile)
+}
@@ -13,9 +13,15 @@ const (
   # Init one kcl module
   kcl mod init
 
+  # Search modules in the registry
+  kcl mod search k8s
+
   # Add dependencies for the current module
   kcl mod add k8s
 
+  # Remove dependencies of the current module
+  kcl mod remove k8s
+
   # Pull external packages to local
   kcl mod pull k8s
 
@@ -67,7 +73,9 @@ func NewModCmd() *cobra.Command {
 	cmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Set the quiet mode (no output)")
 
 	cmd.AddCommand(NewModInitCmd(cli))
+	cmd.AddCommand(NewModSearchCmd(cli))
 	cmd.AddCommand(NewModAddCmd(cli))
+	cmd.AddCommand(NewModRemoveCmd(cli))
 	cmd.AddCommand(NewModPkgCmd(cli))
 	cmd.AddCommand(NewModMetadataCmd(cli))
 	cmd.AddCommand(NewModPushCmd(cli))
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"kcl-lang.io/cli/pkg/mod"
+	"kcl-lang.io/kpm/pkg/client"
+	"kcl-lang.io/kpm/pkg/env"
+	"kcl-lang.io/kpm/pkg/reporter"
+)
+
+const (
+	modRemoveDesc = `This command removes dependencies from kcl.mod and kcl.mod.lock.
+
+A dependency still imported by the module is not removed unless --force is given.
+The source files which cannot be parsed are reported and not checked for imports.
+The dependencies in kcl.mod.lock no longer required by the remaining ones are removed as well.
+`
+	modRemoveExample = `  # Remove the dependency k8s of the current module
+  kcl mod remove k8s
+
+  # Remove several dependencies, even if they are still imported
+  kcl mod remove k8s helloworld --force`
+)
+
+// NewModRemoveCmd returns the mod remove command.
+func NewModRemoveCmd(cli *client.KpmClient) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "remove <name>...",
+		Short:   "remove dependencies",
+		Long:    modRemoveDesc,
+		Example: modRemoveExample,
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return ModRemove(cli, args)
+		},
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "remove the dependencies even if they are still imported")
+
+	return cmd
+}
+
+func ModRemove(cli *client.KpmClient, args []string) error {
+	// acquire the lock of the package cache.
+	err := cli.AcquirePackageCacheLock()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// release the lock of the package cache after the function returns.
+		releaseErr := cli.ReleasePackageCacheLock()
+		if releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+	}()
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		return reporter.NewErrorEvent(reporter.Bug, err, "internal bugs, please contact us to fix it.")
+	}
+
+	globalPkgPath, err := env.GetAbsPkgPath()
+	if err != nil {
+		return err
+	}
+
+	result, err := mod.Remove(&mod.RemoveOptions{
+		Path:  pwd,
+		Home:  globalPkgPath,
+		Names: args,
+		Force: force,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, err := range result.Skipped {
+		reporter.ReportMsgTo(fmt.Sprintf("warning: %v", err), cli.GetLogWriter())
+	}
+	for _, name := range result.Removed {
+		reporter.ReportMsgTo(fmt.Sprintf("removed dependency '%s'", name), cli.GetLogWriter())
+	}
+	if !result.Complete {
+		reporter.ReportMsgTo("some dependencies are not downloaded, run 'kcl mod update' to prune the unused ones from kcl.mod.lock", cli.GetLogWriter())
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"kcl-lang.io/cli/pkg/mod"
+	"kcl-lang.io/cli/pkg/options"
+	"kcl-lang.io/kpm/pkg/client"
+	"kcl-lang.io/kpm/pkg/env"
+	"kcl-lang.io/kpm/pkg/reporter"
+)
+
+const (
+	modSearchDesc = `This command searches the modules whose name contains the query.
+
+The modules are searched in the default OCI registry, with the credentials stored
+by 'kcl registry login' if any, and in the Git indexes given by '--git-index'.
+For each module, the latest version, its publication date and its description
+are printed. The modules which cannot be read are skipped with a warning.
+`
+	modSearchExample = `  # Search the modules of kubernetes
+  kcl mod search k8s
+
+  # Search the modules and print them as JSON
+  kcl mod search k8s --format json
+
+  # Search the modules in the default registry and in a Git index
+  kcl mod search k8s --git-index https://github.com/kcl-lang/modules`
+)
+
+// NewModSearchCmd returns the mod search command.
+func NewModSearchCmd(cli *client.KpmClient) *cobra.Command {
+	var format string
+	var gitIndexes []string
+	cmd := &cobra.Command{
+		Use:     "search <query>",
+		Short:   "search modules",
+		Long:    modSearchDesc,
+		Example: modSearchExample,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return ModSearch(cli, args[0], format, gitIndexes)
+		},
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().StringVar(&format, "format", options.Table, "The output format. Supported values: table, json.")
+	cmd.Flags().StringSliceVar(&gitIndexes, "git-index", []string{}, "The URL of a Git index to search, in a directory per module.")
+	cmd.Flags().BoolVar(&insecureSkipTLSverify, "insecure-skip-tls-verify", false, "skip tls certificate checks for the registry")
+
+	return cmd
+}
+
+func ModSearch(cli *client.KpmClient, query, format string, gitIndexes []string) error {
+	if format != options.Table && format != options.Json {
+		return fmt.Errorf("invalid format: %s, supported values: table, json", format)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: insecureSkipTLSverify}
+	scheme := "https"
+	if cli.GetSettings().DefaultOciPlainHttp() {
+		scheme = "http"
+	}
+	sources := []mod.Searcher{&mod.Registry{
+		URL:    scheme + "://" + cli.GetSettings().DefaultOciRegistry(),
+		Repo:   cli.GetSettings().DefaultOciRepo(),
+		Client: &http.Client{Transport: transport},
+		// Use the credentials stored by 'kcl registry login', as pulls do.
+		Credential: func(host string) (string, string, error) {
+			creds, err := cli.GetCredsClient()
+			if err != nil {
+				return "", "", err
+			}
+			cred, err := creds.Credential(host)
+			if err != nil {
+				return "", "", err
+			}
+			return cred.Username, cred.Password, nil
+		},
+	}}
+	if len(gitIndexes) > 0 {
+		globalPkgPath, err := env.GetAbsPkgPath()
+		if err != nil {
+			return err
+		}
+		for _, index := range gitIndexes {
+			sources = append(sources, &mod.GitIndex{
+				URL:      index,
+				CacheDir: filepath.Join(globalPkgPath, ".kpm", "index"),
+			})
+		}
+	}
+
+	modules, err := mod.Search(query, sources...)
+	if err != nil && len(modules) == 0 {
+		return err
+	} else if err != nil {
+		// Report the sources and the modules which failed, and the other modules.
+		reporter.ReportMsgTo(err.Error(), cli.GetLogWriter())
+	}
+
+	if format == options.Json {
+		return mod.WriteJSON(os.Stdout, modules)
+	}
+	if len(modules) == 0 {
+		reporter.ReportMsgTo(fmt.Sprintf("no modules found for '%s'", query), cli.GetLogWriter())
+		return nil
+	}
+	return mod.WriteTable(os.Stdout, modules)
+}
@@ -3,13 +3,21 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
 
 	"github.com/acarl005/stripansi"
 	"github.com/spf13/cobra"
 	"kcl-lang.io/cli/pkg/options"
+	"kcl-lang.io/cli/pkg/source"
+	"kcl-lang.io/cli/pkg/testing/bench"
+	"kcl-lang.io/cli/pkg/testing/snapshot"
+	"kcl-lang.io/cli/pkg/testing/watch"
 	kcl "kcl-lang.io/kcl-go"
 	"kcl-lang.io/kcl-go/pkg/tools/testing"
 )
@@ -20,6 +28,25 @@ const (
 'KCL test' re-compiles each package along with any files with names matching
 the file pattern "*_test.k". These additional files can contain test functions
 that starts with "test_*".
+
+Snapshot tests compare the rendered YAML of a package with a checked-in file.
+A snapshot is declared with a '# kcl-test: snapshot <name> [entry.k...]'
+comment and stored in '__snapshots__/<name>.yaml' next to the declaring file.
+Without entries, a declaration in a test file renders the package files and a
+declaration in any other file renders that file. The comparison ignores key
+order and scalar formatting.
+
+With '--watch', the command keeps running and re-tests the packages whose
+files, or the local packages they transitively import, changed since the
+last run.
+
+With '--bench', the command also runs the benchmark functions that start with
+"bench_*" and match the given regular expression. Each benchmark is evaluated
+repeatedly until its time budget is used, and the time per evaluation is
+reported. The cost of compiling the package is measured separately and not
+included. Allocations are reported when they are visible to the Go runtime.
+Results can be saved with '--bench-save' and compared with a saved baseline
+with '--bench-compare'.
 `
 	testExample = `  # Test whole current package recursively
   kcl test ./...
@@ -31,12 +58,48 @@ that starts with "test_*".
   kcl test ./... --fail-fast
 
   # Test with the regex expression filter 'test_func'
-  kcl test ./... --run test_func`
+  kcl test ./... --run test_func
+
+  # Rewrite the snapshot files with the current rendered output
+  kcl test ./... --update-snapshots
+
+  # Re-run the affected tests whenever a file changes
+  kcl test ./... --watch
+
+  # Run all the benchmarks and save the results as a baseline
+  kcl test ./... --bench . --bench-save bench.json
+
+  # Run the benchmarks matching 'render' for 3s each and compare with a baseline
+  kcl test ./... --bench render --bench-time 3s --bench-compare bench.json`
 )
 
+// TestOptions holds the options for the test command.
+type TestOptions struct {
+	kcl.TestOptions
+	// UpdateSnapshots rewrites the snapshot files that do not match.
+	UpdateSnapshots bool
+	// Watch re-runs the affected tests when files change.
+	Watch bool
+	// Bench is the regular expression selecting the benchmarks to run.
+	Bench string
+	// BenchTime is the time budget of each benchmark.
+	BenchTime time.Duration
+	// BenchCompare is the baseline JSON file the results are compared with.
+	BenchCompare string
+	// BenchSave is the file the results are saved to as a baseline.
+	BenchSave string
+}
+
+// testSummary is the outcome of a test run.
+type testSummary struct {
+	Passed  int
+	Failed  int
+	Skipped int
+}
+
 // NewTestCmd returns the test command.
 func NewTestCmd() *cobra.Command {
-	o := new(kcl.TestOptions)
+	o := new(TestOptions)
 	runOpts := options.NewRunOptions()
 	cmd := &cobra.Command{
 		Use:     "test",
@@ -60,45 +123,198 @@ func NewTestCmd() *cobra.Command {
 		"Exist when meet the first fail test case in the test process.")
 	flags.StringVar(&o.RunRegRxp, "run", "",
 		"If specified, only run tests containing this string in their names.")
+	flags.BoolVar(&o.UpdateSnapshots, "update-snapshots", false,
+		"Rewrite the snapshot files with the rendered output instead of comparing them.")
+	flags.BoolVarP(&o.Watch, "watch", "w", false,
+		"Watch the workspace and re-run the tests of the affected packages on change.")
+	flags.StringVar(&o.Bench, "bench", "",
+		"Run the benchmarks matching this regular expression, e.g. '.' for all of them.")
+	flags.DurationVar(&o.BenchTime, "bench-time", bench.DefaultBudget,
+		"Time budget of each benchmark.")
+	flags.StringVar(&o.BenchCompare, "bench-compare", "",
+		"Compare the benchmark results with a baseline JSON file.")
+	flags.StringVar(&o.BenchSave, "bench-save", "",
+		"Save the benchmark results to a baseline JSON file.")
 	appendRunnerFlags(runOpts, flags)
 
 	return cmd
 }
 
-func test(o *kcl.TestOptions, runOpts *options.RunOptions) error {
+func test(o *TestOptions, runOpts *options.RunOptions) error {
+	if o.Watch {
+		return watchTests(o, runOpts)
+	}
+	_, err := runTests(o, runOpts)
+	return err
+}
+
+func runTests(o *TestOptions, runOpts *options.RunOptions) (*testSummary, error) {
+	summary := &testSummary{}
 	pwd, err := os.Getwd()
 	if err != nil {
-		return err
+		return summary, err
 	}
 	depsOpt, err := options.LoadDepsFrom(pwd, runOpts.Quiet)
 	if err != nil {
-		return err
+		return summary, err
 	}
+	compileOpt := options.CompileOptionFromCli(runOpts).Option
 	result, err := kcl.Test(
-		o,
-		*options.CompileOptionFromCli(runOpts).Option,
+		&o.TestOptions,
+		*compileOpt,
 		*depsOpt,
 	)
 	if err != nil {
 		if runOpts.NoStyle {
 			err = errors.New(stripansi.Strip(err.Error()))
 		}
-		return err
+		return summary, err
+	}
+	dirs, err := source.PackageDirs(o.PkgList)
+	if err != nil {
+		return summary, err
+	}
+	snaps, err := snapshot.Discover(dirs)
+	if err != nil {
+		return summary, err
+	}
+	var benches []*bench.Benchmark
+	if o.Bench != "" {
+		if benches, err = bench.Discover(dirs, o.Bench); err != nil {
+			return summary, err
+		}
 	}
-	if len(result.Info) == 0 {
+	if len(result.Info) == 0 && len(snaps) == 0 && len(benches) == 0 {
 		fmt.Println("no test files")
-		return nil
-	} else {
+		return summary, nil
+	}
+	if len(result.Info) > 0 {
 		reporter := testing.DefaultReporter(os.Stdout)
-		err := reporter.Report(&result)
+		if err := reporter.Report(&result); err != nil {
+			return summary, err
+		}
+		for _, info := range result.Info {
+			switch {
+			case info.Skip():
+				summary.Skipped++
+			case info.ErrMessage != "":
+				summary.Failed++
+			default:
+				summary.Passed++
+			}
+		}
+	}
+	if len(snaps) > 0 {
+		render := func(entries []string) (string, error) {
+			r, err := kcl.RunFiles(entries, *compileOpt, *depsOpt)
+			if err != nil {
+				return "", err
+			}
+			return r.GetRawYamlResult(), nil
+		}
+		results := snapshot.Run(snaps, render, o.UpdateSnapshots)
+		failed := snapshot.Report(os.Stdout, results)
+		summary.Failed += failed
+		summary.Passed += len(results) - failed
+	}
+	if summary.Failed > 0 {
+		return summary, errors.New("")
+	}
+	if len(benches) > 0 {
+		if err := runBenchmarks(benches, o, compileOpt, depsOpt); err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+// runBenchmarks measures the benchmarks, prints the results and compares or
+// saves them as requested.
+func runBenchmarks(benches []*bench.Benchmark, o *TestOptions, compileOpt *kcl.Option, depsOpt *kcl.Option) error {
+	tmp, err := os.MkdirTemp("", "kcl-bench")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	exec := func(b *bench.Benchmark, n int) error {
+		entry := filepath.Join(tmp, "bench.k")
+		if err := os.WriteFile(entry, []byte(b.Program(n)), 0644); err != nil {
+			return err
+		}
+		_, err := kcl.RunFiles(append(append([]string{}, b.Files...), entry), *compileOpt, *depsOpt)
+		return err
+	}
+	var results []*bench.Result
+	for _, b := range benches {
+		r, err := bench.Run(b, exec, o.BenchTime)
+		if err != nil {
+			return fmt.Errorf("benchmark %s failed: %w", b.ID(), err)
+		}
+		bench.Report(os.Stdout, []*bench.Result{r})
+		results = append(results, r)
+	}
+	if o.BenchCompare != "" {
+		baseline, err := bench.LoadBaseline(o.BenchCompare)
 		if err != nil {
 			return err
 		}
-		for _, info := range result.Info {
-			if info.ErrMessage != "" && !info.Skip() {
-				return errors.New("")
+		bench.Compare(os.Stdout, baseline, results)
+	}
+	if o.BenchSave != "" {
+		return bench.SaveBaseline(o.BenchSave, results)
+	}
+	return nil
+}
+
+// watchTests runs the tests once, then re-runs the tests of the packages
+// affected by each change of the workspace until interrupted.
+func watchTests(o *TestOptions, runOpts *options.RunOptions) error {
+	dirs, err := source.PackageDirs(o.PkgList)
+	if err != nil {
+		return err
+	}
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	w, err := watch.New(source.FindModRoot(pwd))
+	if err != nil {
+		return err
+	}
+	cycle := func(pkgs []string) {
+		opts := *o
+		opts.PkgList = pkgs
+		start := time.Now()
+		summary, err := runTests(&opts, runOpts)
+		if err != nil && err.Error() != "" {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		status := "PASS"
+		if err != nil {
+			status = "FAIL"
+		}
+		fmt.Printf("[%s] %s: %d passed, %d failed, %d skipped in %d package(s) (%s)\n",
+			start.Format("15:04:05"), status, summary.Passed, summary.Failed, summary.Skipped,
+			len(pkgs), time.Since(start).Round(time.Millisecond))
+		fmt.Println("watching for changes...")
+	}
+	cycle(dirs)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	for {
+		change, err := w.Wait(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
 			}
+			return err
+		}
+		// Newly added packages are picked up when they match the patterns.
+		if dirs, err = source.PackageDirs(o.PkgList); err != nil {
+			return err
+		}
+		if affected := w.Affected(change, dirs); len(affected) > 0 {
+			cycle(affected)
 		}
-		return nil
 	}
 }
@@ -4,10 +4,12 @@ go 1.26
 
 require (
 	github.com/acarl005/stripansi v0.0.0-20180116102854-5a71ef0e047d
+	github.com/go-git/go-git/v5 v5.19.1
 	github.com/onsi/ginkgo/v2 v2.32.1
 	github.com/onsi/gomega v1.42.1
 	github.com/spf13/cobra v1.10.2
 	github.com/stretchr/testify v1.12.0
+	k8s.io/apimachinery v0.28.3
 	kcl-lang.io/kcl-go v0.12.4
 	kcl-lang.io/kcl-openapi v0.10.3
 	kcl-lang.io/kcl-plugin v0.11.0
@@ -135,7 +137,6 @@ require (
 	github.com/getkin/kin-openapi v0.145.0 // indirect
 	github.com/go-git/gcfg v1.5.1-0.20230307220236-3a3c6141e376 // indirect
 	github.com/go-git/go-billy/v5 v5.9.0 // indirect
-	github.com/go-git/go-git/v5 v5.19.1 // indirect
 	github.com/go-logr/logr v1.4.3 // indirect
 	github.com/go-logr/stdr v1.2.2 // indirect
 	github.com/go-openapi/analysis v0.23.0 // indirect
@@ -211,7 +212,6 @@ require (
 	gotest.tools/v3 v3.5.2
 	k8s.io/api v0.28.3 // indirect
 	k8s.io/apiextensions-apiserver v0.24.1 // indirect
-	k8s.io/apimachinery v0.28.3 // indirect
 	k8s.io/client-go v0.28.3 // indirect
 	k8s.io/klog/v2 v2.100.1 // indirect
 	k8s.io/kube-openapi v0.0.0-20230717233707-2695361300d9 // indirect
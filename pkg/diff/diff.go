@@ -0,0 +1,212 @@
+// Copyright The KCL Authors. All rights reserved.
+
+// Package diff computes line-based differences between texts and renders
+// them in the unified diff format.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OpKind is the kind of a diff operation.
+type OpKind int
+
+const (
+	// Equal denotes a line present in both texts.
+	Equal OpKind = iota
+	// Delete denotes a line only present in the old text.
+	Delete
+	// Insert denotes a line only present in the new text.
+	Insert
+)
+
+// Op is a single line of an edit script.
+type Op struct {
+	Kind OpKind
+	Text string
+	// A and B are the 0-based line indexes in the old and new texts. The
+	// index of the side a line is missing from points to the next line.
+	A, B int
+}
+
+// DefaultContext is the number of context lines of unified diff hunks.
+const DefaultContext = 3
+
+// SplitLines splits text into lines keeping the line terminators, so that a
+// missing trailing newline is reported as a difference.
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Lines returns the shortest edit script transforming a into b using the
+// Myers difference algorithm.
+func Lines(a, b []string) []Op {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+	offset := max
+	v := make([]int, 2*max+2)
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, offset)
+			}
+		}
+	}
+	return nil
+}
+
+func backtrack(a, b []string, trace [][]int, offset int) []Op {
+	var ops []Op
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, Op{Kind: Equal, Text: a[x], A: x, B: y})
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, Op{Kind: Insert, Text: b[y], A: x, B: y})
+		} else {
+			x--
+			ops = append(ops, Op{Kind: Delete, Text: a[x], A: x, B: y})
+		}
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// Unified returns the unified diff between the texts a and b, using the
+// given names in the file header lines. It returns an empty string when the
+// texts are equal.
+func Unified(fromName, toName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	ops := Lines(SplitLines(a), SplitLines(b))
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for _, h := range hunks(ops, DefaultContext) {
+		writeHunk(&sb, ops[h[0]:h[1]])
+	}
+	return sb.String()
+}
+
+// hunks groups the changes of the edit script into ranges of ops including
+// up to context unchanged lines around each change.
+func hunks(ops []Op, context int) [][2]int {
+	var result [][2]int
+	for i := 0; i < len(ops); i++ {
+		if ops[i].Kind == Equal {
+			continue
+		}
+		start := max(i-context, 0)
+		end := i
+		for end < len(ops) {
+			if ops[end].Kind != Equal {
+				end++
+				continue
+			}
+			// Extend through runs of equal lines short enough to join
+			// two changes in the same hunk.
+			j := end
+			for j < len(ops) && ops[j].Kind == Equal {
+				j++
+			}
+			if j < len(ops) && j-end <= 2*context {
+				end = j
+				continue
+			}
+			end = min(end+context, len(ops))
+			break
+		}
+		if n := len(result); n > 0 && start <= result[n-1][1] {
+			result[n-1][1] = end
+		} else {
+			result = append(result, [2]int{start, end})
+		}
+		i = end - 1
+	}
+	return result
+}
+
+func writeHunk(sb *strings.Builder, ops []Op) {
+	var aStart, bStart, aLen, bLen int
+	aStart, bStart = ops[0].A, ops[0].B
+	for _, op := range ops {
+		switch op.Kind {
+		case Equal:
+			aLen++
+			bLen++
+		case Delete:
+			aLen++
+		case Insert:
+			bLen++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(aStart, aLen), hunkRange(bStart, bLen))
+	for _, op := range ops {
+		prefix := " "
+		switch op.Kind {
+		case Delete:
+			prefix = "-"
+		case Insert:
+			prefix = "+"
+		}
+		sb.WriteString(prefix)
+		sb.WriteString(op.Text)
+		if !strings.HasSuffix(op.Text, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
@@ -0,0 +1,51 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: "",
+		},
+		{
+			name: "change in the middle",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			b:    "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			want: "--- a\n+++ b\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "insert into empty",
+			a:    "",
+			b:    "a\n",
+			want: "--- a\n+++ b\n@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			name: "missing trailing newline",
+			a:    "a\n",
+			b:    "a",
+			want: "--- a\n+++ b\n@@ -1 +1 @@\n-a\n+a\n\\ No newline at end of file\n",
+		},
+		{
+			name: "separate hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			b:    "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			want: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+ten\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("a", "b", tt.a, tt.b); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package doc
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"kcl-lang.io/cli/pkg/source"
+)
+
+// Check is a check expression of a schema.
+type Check struct {
+	Expr    string `json:"expr"`
+	Message string `json:"message,omitempty"`
+}
+
+// Constraint is a check of a schema expressed as a JSON Schema keyword of an
+// attribute.
+type Constraint struct {
+	Attribute string `json:"attribute"`
+	// Keyword is a JSON Schema keyword such as `minimum`, `minLength`,
+	// `pattern` or `enum`. The length keywords apply to strings and are
+	// mapped to the item and property counts of lists and dicts.
+	Keyword string `json:"keyword"`
+	Value   any    `json:"value"`
+}
+
+// Constraints returns the constraints expressed by the checks of the schema,
+// and the checks that cannot be expressed as constraints.
+func (s *Schema) Constraints() ([]Constraint, []*Check) {
+	var constraints []Constraint
+	var rest []*Check
+	for _, c := range s.Checks {
+		if cs, ok := parseConstraints(c.Expr); ok {
+			constraints = append(constraints, cs...)
+		} else {
+			rest = append(rest, c)
+		}
+	}
+	return constraints, rest
+}
+
+var (
+	identRe      = regexp.MustCompile(`^[A-Za-z_$][\w$]*$`)
+	lenRe        = regexp.MustCompile(`^len\(\s*([A-Za-z_$][\w$]*)\s*\)$`)
+	regexMatchRe = regexp.MustCompile(`^regex\.match\(\s*([A-Za-z_$][\w$]*)\s*,\s*(.+)\)$`)
+	compareOpRe  = regexp.MustCompile(`(<=|>=|==|<|>)`)
+)
+
+// parseConstraints parses a check expression made of comparisons of an
+// attribute or its length with numbers, chained comparisons, regex matches
+// and `in` list tests joined with `and`.
+func parseConstraints(expr string) ([]Constraint, bool) {
+	var constraints []Constraint
+	for _, part := range source.SplitTop(expr, " and ") {
+		part = strings.TrimSpace(part)
+		if m := regexMatchRe.FindStringSubmatch(part); m != nil {
+			pattern, ok := source.StringLiteral(strings.TrimSpace(m[2]))
+			if !ok {
+				return nil, false
+			}
+			constraints = append(constraints, Constraint{Attribute: m[1], Keyword: "pattern", Value: pattern})
+			continue
+		}
+		if parts := source.SplitTop(part, " in "); len(parts) == 2 {
+			name, list := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+			if !identRe.MatchString(name) || !strings.HasPrefix(list, "[") || !strings.HasSuffix(list, "]") {
+				return nil, false
+			}
+			values, ok := literals(source.SplitTop(list[1:len(list)-1], ","))
+			if !ok {
+				return nil, false
+			}
+			constraints = append(constraints, Constraint{Attribute: name, Keyword: "enum", Value: values})
+			continue
+		}
+		cs, ok := parseComparison(part)
+		if !ok {
+			return nil, false
+		}
+		constraints = append(constraints, cs...)
+	}
+	return constraints, len(constraints) > 0
+}
+
+// parseComparison parses a possibly chained comparison such as `x >= 1` or
+// `0 < len(x) <= 10`.
+func parseComparison(expr string) ([]Constraint, bool) {
+	if strings.ContainsAny(expr, `"'`) {
+		return nil, false
+	}
+	ops := compareOpRe.FindAllString(expr, -1)
+	operands := compareOpRe.Split(expr, -1)
+	if len(ops) == 0 {
+		return nil, false
+	}
+	var constraints []Constraint
+	for i, op := range ops {
+		left, right := strings.TrimSpace(operands[i]), strings.TrimSpace(operands[i+1])
+		if _, ok := number(left); ok {
+			// Normalize `1 < x` to `x > 1`.
+			left, right = right, left
+			op = map[string]string{"<": ">", ">": "<", "<=": ">=", ">=": "<=", "==": "=="}[op]
+		}
+		value, ok := number(right)
+		if !ok {
+			return nil, false
+		}
+		if m := lenRe.FindStringSubmatch(left); m != nil {
+			n, ok := value.(int64)
+			if !ok {
+				return nil, false
+			}
+			switch op {
+			case ">=":
+				constraints = append(constraints, Constraint{m[1], "minLength", n})
+			case ">":
+				constraints = append(constraints, Constraint{m[1], "minLength", n + 1})
+			case "<=":
+				constraints = append(constraints, Constraint{m[1], "maxLength", n})
+			case "<":
+				constraints = append(constraints, Constraint{m[1], "maxLength", n - 1})
+			case "==":
+				constraints = append(constraints, Constraint{m[1], "minLength", n}, Constraint{m[1], "maxLength", n})
+			}
+			continue
+		}
+		if !identRe.MatchString(left) {
+			return nil, false
+		}
+		keyword := map[string]string{">=": "minimum", ">": "exclusiveMinimum", "<=": "maximum", "<": "exclusiveMaximum", "==": "const"}[op]
+		constraints = append(constraints, Constraint{left, keyword, value})
+	}
+	return constraints, true
+}
+
+// number parses an int or float literal.
+func number(text string) (any, bool) {
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return n, true
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f, true
+	}
+	return nil, false
+}
+
+// literals parses string, number and boolean literals.
+func literals(texts []string) ([]any, bool) {
+	var values []any
+	for _, t := range texts {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if s, ok := source.StringLiteral(t); ok {
+			values = append(values, s)
+		} else if n, ok := number(t); ok {
+			values = append(values, n)
+		} else if t == "True" || t == "False" {
+			values = append(values, t == "True")
+		} else {
+			return nil, false
+		}
+	}
+	return values, len(values) > 0
+}
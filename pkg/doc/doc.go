@@ -0,0 +1,409 @@
+// Copyright The KCL Authors. All rights reserved.
+
+// Package doc builds the documentation model of KCL modules from the OpenAPI
+// spec exported by `api.GetKclPackage` and the source files of the modules,
+// and renders it as a static site, JSON Schemas or terminal text.
+package doc
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"kcl-lang.io/cli/pkg/fs"
+	"kcl-lang.io/cli/pkg/source"
+)
+
+// Module is a documented KCL module.
+type Module struct {
+	// Name is the module name, used as the import path prefix of the
+	// packages of dependencies.
+	Name string `json:"name"`
+	// Dir is the module root directory.
+	Dir string `json:"dir"`
+	// Dep reports whether the module is a dependency of the documented
+	// module.
+	Dep     bool      `json:"dep"`
+	Schemas []*Schema `json:"schemas"`
+}
+
+// Schema is the documentation of a schema.
+type Schema struct {
+	// ID is the import path of the package of the schema followed by the
+	// schema name, such as `k8s.api.apps.v1.Deployment`, or only the name
+	// for the root package of the documented module.
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Package string `json:"package"`
+	// Module is the name of the module defining the schema.
+	Module string `json:"module"`
+	Doc    string `json:"doc"`
+	// Base is the ID of the base schema, if any.
+	Base       string       `json:"base,omitempty"`
+	Attributes []*Attribute `json:"attributes"`
+	Examples   []*Example   `json:"examples,omitempty"`
+	Checks     []*Check     `json:"checks,omitempty"`
+	// File and Line locate the schema statement, when its source is found.
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+// Summary returns the first line of the schema docstring.
+func (s *Schema) Summary() string {
+	summary, _, _ := strings.Cut(strings.TrimSpace(s.Doc), "\n")
+	return strings.TrimSpace(summary)
+}
+
+// Attribute is the documentation of a schema attribute.
+type Attribute struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Refs are the IDs of the schemas referenced by the type.
+	Refs     []string `json:"refs,omitempty"`
+	Doc      string   `json:"doc,omitempty"`
+	Default  string   `json:"default,omitempty"`
+	Required bool     `json:"required"`
+	// Enum are the allowed values of the attribute, if restricted.
+	Enum []any `json:"enum,omitempty"`
+	Line int   `json:"line,omitempty"`
+
+	// schema is the JSON Schema of the attribute type.
+	schema map[string]any
+}
+
+// Example is a schema example of the docstring.
+type Example struct {
+	Name    string `json:"name"`
+	Summary string `json:"summary,omitempty"`
+	Value   string `json:"value"`
+}
+
+// specType is the subset of the OpenAPI types of the spec read by the doc
+// model.
+type specType struct {
+	Type                 string                  `json:"type"`
+	Format               string                  `json:"format"`
+	Ref                  string                  `json:"$ref"`
+	Description          string                  `json:"description"`
+	Default              any                     `json:"default"`
+	Enum                 []any                   `json:"enum"`
+	Required             []string                `json:"required"`
+	Properties           map[string]*specType    `json:"properties"`
+	Items                *specType               `json:"items"`
+	AdditionalProperties *specType               `json:"additionalProperties"`
+	Examples             map[string]*specExample `json:"examples"`
+}
+
+type specExample struct {
+	Summary     string `json:"summary"`
+	Description string `json:"description"`
+	Value       any    `json:"value"`
+}
+
+const refPrefix = "#/definitions/"
+
+// LoadModule builds the documentation of the module in dir from its Swagger
+// v2 spec. The schemas are completed with the locations, base schemas and
+// declared types found in the source files.
+func LoadModule(name, dir string, dep bool, spec []byte) (*Module, error) {
+	doc := struct {
+		Definitions map[string]*specType `json:"definitions"`
+	}{}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("invalid spec of module %s: %w", name, err)
+	}
+	m := &Module{Name: name, Dir: dir, Dep: dep, Schemas: []*Schema{}}
+	keys := make([]string, 0, len(doc.Definitions))
+	for key := range doc.Definitions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	packages := map[string]*source.Package{}
+	for _, key := range keys {
+		def := doc.Definitions[key]
+		s := &Schema{ID: m.id(key), Module: name, Doc: strings.TrimSpace(def.Description), Attributes: []*Attribute{}}
+		s.Package, s.Name = splitID(s.ID)
+		required := map[string]bool{}
+		for _, r := range def.Required {
+			required[r] = true
+		}
+		for _, attr := range sortedKeys(def.Properties) {
+			prop := def.Properties[attr]
+			a := &Attribute{
+				Name:     attr,
+				Type:     m.typeString(prop),
+				Refs:     m.refs(prop, nil),
+				Doc:      strings.TrimSpace(prop.Description),
+				Required: required[attr],
+				schema:   m.jsonType(prop),
+			}
+			if prop.Default != nil {
+				a.Default = fmt.Sprint(prop.Default)
+			}
+			a.Enum = prop.Enum
+			s.Attributes = append(s.Attributes, a)
+		}
+		for _, ex := range sortedKeys(def.Examples) {
+			e := def.Examples[ex]
+			value, ok := e.Value.(string)
+			if !ok {
+				data, _ := json.MarshalIndent(e.Value, "", "  ")
+				value = string(data)
+			}
+			s.Examples = append(s.Examples, &Example{Name: ex, Summary: strings.TrimSpace(e.Summary), Value: strings.TrimSpace(value)})
+		}
+		m.locate(s, packages)
+		m.Schemas = append(m.Schemas, s)
+	}
+	return m, nil
+}
+
+// id returns the schema ID of a definition key or reference of the module
+// spec. The keys of the packages of dependencies are relative to the
+// dependency, so they are prefixed with the module name.
+func (m *Module) id(key string) string {
+	key = strings.ReplaceAll(strings.TrimPrefix(key, refPrefix), "/", ".")
+	key = strings.TrimPrefix(key, "__main__.")
+	if !m.Dep || key == m.Name || strings.HasPrefix(key, m.Name+".") {
+		return key
+	}
+	if pkg, _ := splitID(key); pkg != "" && !fs.IsDir(m.packageDir(pkg)) {
+		return key
+	}
+	return m.Name + "." + key
+}
+
+// packageDir returns the directory of the package with the import path pkg
+// in the module.
+func (m *Module) packageDir(pkg string) string {
+	if m.Dep && pkg == m.Name {
+		pkg = ""
+	} else if m.Dep {
+		pkg = strings.TrimPrefix(pkg, m.Name+".")
+	}
+	if pkg == "" {
+		return m.Dir
+	}
+	return filepath.Join(append([]string{m.Dir}, strings.Split(pkg, ".")...)...)
+}
+
+// locate completes the schema with its source.
+func (m *Module) locate(s *Schema, packages map[string]*source.Package) {
+	dir := m.packageDir(s.Package)
+	pkg, ok := packages[dir]
+	if !ok {
+		pkg, _ = source.LoadPackage(dir)
+		packages[dir] = pkg
+	}
+	if pkg == nil {
+		return
+	}
+	for _, f := range pkg.Files {
+		src := f.Schema(s.Name)
+		if src == nil {
+			continue
+		}
+		s.File, s.Line = f.Path, src.Line
+		if src.Base != "" {
+			s.Base = resolve(f, s.Package, src.Base)
+		}
+		for _, c := range src.Checks {
+			s.Checks = append(s.Checks, &Check{Expr: c.Expr, Message: c.Message})
+		}
+		for _, a := range s.Attributes {
+			sa := src.Attribute(a.Name)
+			if sa == nil {
+				continue
+			}
+			a.Line = sa.Line
+			if sa.Type != "" {
+				a.Type = sa.Type
+			}
+			// Unions of literal types restrict the values.
+			if values, ok := literals(source.SplitTop(sa.Type, "|")); ok && len(a.Enum) == 0 && strings.Contains(sa.Type, "|") {
+				a.Enum = values
+			}
+			if a.Default == "" {
+				a.Default = sa.Default
+			}
+		}
+		return
+	}
+}
+
+// resolve returns the ID of the schema referenced as ref in the file of the
+// package pkg.
+func resolve(f *source.File, pkg, ref string) string {
+	i := strings.LastIndex(ref, ".")
+	if i < 0 {
+		return joinID(pkg, ref)
+	}
+	alias, name := ref[:i], ref[i+1:]
+	for _, imp := range f.Imports {
+		if imp.Name() != alias {
+			continue
+		}
+		path := imp.Path
+		if strings.HasPrefix(path, ".") {
+			// Relative imports are resolved from the package, each extra
+			// leading dot going up one package.
+			rel := strings.TrimLeft(path, ".")
+			parts := strings.Split(pkg, ".")
+			if pkg == "" {
+				parts = nil
+			}
+			up := len(path) - len(rel) - 1
+			parts = parts[:max(len(parts)-up, 0)]
+			path = strings.Join(append(parts, rel), ".")
+		}
+		return joinID(path, name)
+	}
+	return ref
+}
+
+// typeString returns the KCL type of an OpenAPI type.
+func (m *Module) typeString(t *specType) string {
+	if t == nil {
+		return "any"
+	}
+	if t.Ref != "" {
+		return m.id(t.Ref)
+	}
+	switch t.Type {
+	case "string":
+		return "str"
+	case "integer":
+		return "int"
+	case "number":
+		return "float"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[" + m.typeString(t.Items) + "]"
+	case "object":
+		if t.AdditionalProperties != nil {
+			return "{str:" + m.typeString(t.AdditionalProperties) + "}"
+		}
+		return "{str:}"
+	case "":
+		return "any"
+	}
+	return t.Type
+}
+
+// refs appends the IDs of the schemas referenced by the type to ids.
+func (m *Module) refs(t *specType, ids []string) []string {
+	if t == nil {
+		return ids
+	}
+	if t.Ref != "" {
+		ids = append(ids, m.id(t.Ref))
+	}
+	ids = m.refs(t.Items, ids)
+	return m.refs(t.AdditionalProperties, ids)
+}
+
+// Index is the documentation of a module and its dependencies.
+type Index struct {
+	Modules []*Module
+	schemas map[string]*Schema
+}
+
+// NewIndex returns the index of the modules. The first module defining a
+// schema ID wins, so the documented module should come first.
+func NewIndex(modules ...*Module) *Index {
+	idx := &Index{Modules: modules, schemas: map[string]*Schema{}}
+	for _, m := range modules {
+		for _, s := range m.Schemas {
+			if _, ok := idx.schemas[s.ID]; !ok {
+				idx.schemas[s.ID] = s
+			}
+		}
+	}
+	return idx
+}
+
+// Schema returns the schema with the ID, or nil.
+func (idx *Index) Schema(id string) *Schema {
+	return idx.schemas[id]
+}
+
+// Schemas returns the indexed schemas sorted by ID.
+func (idx *Index) Schemas() []*Schema {
+	schemas := make([]*Schema, 0, len(idx.schemas))
+	for _, s := range idx.schemas {
+		schemas = append(schemas, s)
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].ID < schemas[j].ID })
+	return schemas
+}
+
+// Chain returns the base schemas of the schema, closest first. Bases missing
+// from the index end the chain.
+func (idx *Index) Chain(s *Schema) []*Schema {
+	var chain []*Schema
+	seen := map[string]bool{s.ID: true}
+	for s.Base != "" && !seen[s.Base] {
+		base := idx.schemas[s.Base]
+		if base == nil {
+			break
+		}
+		seen[base.ID] = true
+		chain = append(chain, base)
+		s = base
+	}
+	return chain
+}
+
+// splitID splits a schema ID into its package and name.
+func splitID(id string) (string, string) {
+	if i := strings.LastIndex(id, "."); i >= 0 {
+		return id[:i], id[i+1:]
+	}
+	return "", id
+}
+
+func joinID(pkg, name string) string {
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Load returns the index of the module in dir and of its dependencies, given
+// by name and local path. The spec function returns the Swagger v2 spec of
+// the module in a directory.
+func Load(dir string, deps map[string]string, spec func(dir string) ([]byte, error)) (*Index, error) {
+	data, err := spec(dir)
+	if err != nil {
+		return nil, err
+	}
+	root, err := LoadModule(source.ModName(source.FindModRoot(dir)), dir, false, data)
+	if err != nil {
+		return nil, err
+	}
+	modules := []*Module{root}
+	for _, name := range sortedKeys(deps) {
+		data, err := spec(deps[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to load dependency %s: %w", name, err)
+		}
+		m, err := LoadModule(name, deps[name], true, data)
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, m)
+	}
+	return NewIndex(modules...), nil
+}
@@ -0,0 +1,231 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package doc
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const appSpec = `{"swagger": "2.0", "definitions": {
+	"App": {"type": "object", "description": "An application.", "required": ["port"], "properties": {
+		"port": {"type": "integer", "default": "80"},
+		"deployment": {"$ref": "#/definitions/k8s.api.apps.v1.Deployment", "description": "The deployment."}
+	}, "examples": {"Default": {"summary": "A web app", "value": "app = App {name = \"web\"}"}}},
+	"base.Base": {"type": "object", "description": "The base schema.", "required": ["name"], "properties": {
+		"name": {"type": "string", "description": "The name."}
+	}}
+}}`
+
+const k8sSpec = `{"swagger": "2.0", "definitions": {
+	"api.apps.v1.Deployment": {"type": "object", "description": "Deployment enables declarative updates for Pods.", "properties": {
+		"replicas": {"type": "integer", "format": "int32"}
+	}}
+}}`
+
+func testIndex(t *testing.T) *Index {
+	t.Helper()
+	app, err := LoadModule("app", filepath.Join("testdata", "app"), false, []byte(appSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	k8s, err := LoadModule("k8s", filepath.Join("testdata", "deps", "k8s"), true, []byte(k8sSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewIndex(app, k8s)
+}
+
+func TestLoadModule(t *testing.T) {
+	idx := testIndex(t)
+	var ids []string
+	for _, s := range idx.Schemas() {
+		ids = append(ids, s.ID)
+	}
+	if want := []string{"App", "base.Base", "k8s.api.apps.v1.Deployment"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("schemas: got %v, want %v", ids, want)
+	}
+	app := idx.Schema("App")
+	if app.Base != "base.Base" || app.Line != 4 || !strings.HasSuffix(app.File, "main.k") {
+		t.Errorf("unexpected schema source: base %q, %s:%d", app.Base, app.File, app.Line)
+	}
+	if chain := idx.Chain(app); len(chain) != 1 || chain[0].ID != "base.Base" {
+		t.Errorf("unexpected chain: %v", chain)
+	}
+	deployment := app.Attributes[0]
+	if deployment.Type != "apps.Deployment" || !reflect.DeepEqual(deployment.Refs, []string{"k8s.api.apps.v1.Deployment"}) || deployment.Required {
+		t.Errorf("unexpected attribute: %+v", deployment)
+	}
+	if port := app.Attributes[1]; port.Default != "80" || !port.Required || port.Line != 6 {
+		t.Errorf("unexpected attribute: %+v", port)
+	}
+	if len(app.Examples) != 1 || app.Examples[0].Summary != "A web app" {
+		t.Errorf("unexpected examples: %v", app.Examples)
+	}
+	if d := idx.Schema("k8s.api.apps.v1.Deployment"); d == nil || d.Line != 1 {
+		t.Errorf("unexpected dependency schema: %+v", d)
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	idx := testIndex(t)
+	schema := idx.JSONSchema(idx.Schema("App"))
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Schema     string                    `json:"$schema"`
+		Required   []string                  `json:"required"`
+		Properties map[string]map[string]any `json:"properties"`
+		Defs       map[string]map[string]any `json:"$defs"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Schema != JSONSchemaDialect {
+		t.Errorf("unexpected dialect %q", got.Schema)
+	}
+	// The base attributes come first.
+	if want := []string{"name", "port"}; !reflect.DeepEqual(got.Required, want) {
+		t.Errorf("required: got %v, want %v", got.Required, want)
+	}
+	if ref := got.Properties["deployment"]["$ref"]; ref != "#/$defs/k8s.api.apps.v1.Deployment" {
+		t.Errorf("unexpected reference %v", ref)
+	}
+	if _, ok := got.Defs["k8s.api.apps.v1.Deployment"]; !ok {
+		t.Errorf("missing definition in %s", data)
+	}
+	port := got.Properties["port"]
+	if port["exclusiveMinimum"] != 0.0 || port["exclusiveMaximum"] != 65536.0 {
+		t.Errorf("unexpected port constraints: %v", port)
+	}
+	if checks := schema[ChecksExtension]; !reflect.DeepEqual(checks, []string{"port % 2 == 0"}) {
+		t.Errorf("unexpected checks: %v", checks)
+	}
+}
+
+func TestParseConstraints(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []Constraint
+	}{
+		{"x >= 1", []Constraint{{"x", "minimum", int64(1)}}},
+		{"1 < x <= 2.5", []Constraint{{"x", "exclusiveMinimum", int64(1)}, {"x", "maximum", 2.5}}},
+		{"len(name) > 0 and len(name) <= 63", []Constraint{{"name", "minLength", int64(1)}, {"name", "maxLength", int64(63)}}},
+		{`regex.match(name, r"^[a-z]+$")`, []Constraint{{"name", "pattern", "^[a-z]+$"}}},
+		{`kind in ["a", "b"]`, []Constraint{{"kind", "enum", []any{"a", "b"}}}},
+		{"x > y", nil},
+		{"x > 1 if y", nil},
+	}
+	for _, tt := range tests {
+		got, _ := parseConstraints(tt.expr)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestWriteSite(t *testing.T) {
+	idx := testIndex(t)
+	dir := t.TempDir()
+	opts := SiteOptions{Title: "app", SourceURL: "https://example.com/app/{path}#L{line}"}
+	if err := WriteSite(idx, dir, opts); err != nil {
+		t.Fatal(err)
+	}
+	page, err := os.ReadFile(filepath.Join(dir, "schemas", "App.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<a href="https://example.com/app/main.k#L4">source</a>`,
+		`<a href="k8s.api.apps.v1.Deployment.html">k8s.api.apps.v1.Deployment</a>`,
+		`inherits <a href="base.Base.html">base.Base</a>`,
+		`<a href="App.json">JSON Schema</a>`,
+	} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("the page does not contain %s:\n%s", want, page)
+		}
+	}
+	var entries []*SearchEntry
+	data, err := os.ReadFile(filepath.Join(dir, SearchIndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].URL != "schemas/App.html" || entries[0].Summary != "An application." {
+		t.Errorf("unexpected search index: %s", data)
+	}
+	for _, name := range []string{"index.html", "assets/search.js", "schemas/k8s.api.apps.v1.Deployment.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	idx := testIndex(t)
+	tests := []struct {
+		symbol  string
+		schema  string
+		schemas int
+		err     string
+	}{
+		{symbol: ".", schemas: 1},
+		{symbol: "k8s.api.apps.v1.Deployment", schema: "k8s.api.apps.v1.Deployment"},
+		{symbol: "v1.Deployment", schema: "k8s.api.apps.v1.Deployment"},
+		{symbol: "Base", schema: "base.Base"},
+		{symbol: "base", schemas: 1},
+		{symbol: "Missing", err: `symbol "Missing" not found`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			sym, err := idx.Lookup(tt.symbol)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("got error %v, want %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.schema != "" && (sym.Schema == nil || sym.Schema.ID != tt.schema) {
+				t.Errorf("got %+v, want schema %s", sym, tt.schema)
+			}
+			if tt.schema == "" && len(sym.Schemas) != tt.schemas {
+				t.Errorf("got %d schemas, want %d", len(sym.Schemas), tt.schemas)
+			}
+		})
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	idx := testIndex(t)
+	sym, err := idx.Lookup("App")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf strings.Builder
+	if err := WriteText(&buf, sym); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"schema App(base.Base)\n",
+		"\nAn application.\n",
+		"\nInheritance: App <- base.Base\n",
+		"  deployment?: apps.Deployment\n      The deployment.\n  port: int = 80\n",
+		"\nInherited from base.Base:\n  name: str\n      The name.\n",
+		"  Default: A web app\n      app = App {name = \"web\"}\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("the output does not contain %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package doc
+
+import "strings"
+
+const (
+	// JSONSchemaDialect is the JSON Schema dialect of the exported schemas.
+	JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+	// ChecksExtension is the JSON Schema keyword listing the checks of a
+	// schema that are not expressed as constraints.
+	ChecksExtension = "x-kcl-checks"
+)
+
+// JSONSchema returns the JSON Schema of the schema. The attributes of the
+// base schemas are included and the referenced schemas are defined in
+// `$defs`, keyed by schema ID.
+func (idx *Index) JSONSchema(s *Schema) map[string]any {
+	root := idx.jsonObject(s)
+	root["$schema"] = JSONSchemaDialect
+	defs := map[string]any{}
+	pending := idx.objectRefs(s)
+	for len(pending) > 0 {
+		id := pending[0]
+		pending = pending[1:]
+		if _, ok := defs[id]; ok {
+			continue
+		}
+		ref := idx.schemas[id]
+		if ref == nil {
+			// Schemas missing from the index accept any object.
+			defs[id] = map[string]any{"type": "object", "title": id}
+			continue
+		}
+		defs[id] = idx.jsonObject(ref)
+		pending = append(pending, idx.objectRefs(ref)...)
+	}
+	if len(defs) > 0 {
+		root["$defs"] = defs
+	}
+	return root
+}
+
+// jsonObject returns the JSON Schema object of the schema without `$defs`.
+func (idx *Index) jsonObject(s *Schema) map[string]any {
+	obj := map[string]any{"title": s.ID, "type": "object"}
+	if s.Doc != "" {
+		obj["description"] = s.Doc
+	}
+	props := map[string]any{}
+	required := map[string]bool{}
+	var order, checks []string
+	chain := idx.Chain(s)
+	for i := len(chain) - 1; i >= -1; i-- {
+		t := s
+		if i >= 0 {
+			t = chain[i]
+		}
+		for _, a := range t.Attributes {
+			if _, ok := props[a.Name]; !ok {
+				order = append(order, a.Name)
+			}
+			props[a.Name] = a.JSONSchema()
+			required[a.Name] = a.Required
+		}
+		constraints, rest := t.Constraints()
+		for _, c := range constraints {
+			prop, ok := props[c.Attribute].(map[string]any)
+			if !ok {
+				continue
+			}
+			keyword := c.Keyword
+			switch prop["type"] {
+			case "array":
+				keyword = strings.Replace(keyword, "Length", "Items", 1)
+			case "object":
+				keyword = strings.Replace(keyword, "Length", "Properties", 1)
+			}
+			prop[keyword] = c.Value
+		}
+		for _, c := range rest {
+			checks = append(checks, c.Expr)
+		}
+	}
+	if len(checks) > 0 {
+		// The checks that JSON Schema cannot express are kept as an
+		// extension.
+		obj[ChecksExtension] = checks
+	}
+	var req []string
+	for _, name := range order {
+		if required[name] {
+			req = append(req, name)
+		}
+	}
+	if len(props) > 0 {
+		obj["properties"] = props
+	}
+	if len(req) > 0 {
+		obj["required"] = req
+	}
+	return obj
+}
+
+// objectRefs returns the IDs of the schemas referenced by the attributes of
+// the schema and its bases.
+func (idx *Index) objectRefs(s *Schema) []string {
+	var ids []string
+	for _, t := range append([]*Schema{s}, idx.Chain(s)...) {
+		for _, a := range t.Attributes {
+			ids = append(ids, a.Refs...)
+		}
+	}
+	return ids
+}
+
+// JSONSchema returns the JSON Schema of the attribute. References to schemas
+// point to `#/$defs/<ID>`.
+func (a *Attribute) JSONSchema() map[string]any {
+	schema := map[string]any{}
+	for k, v := range a.schema {
+		schema[k] = v
+	}
+	if a.Doc != "" {
+		schema["description"] = a.Doc
+	}
+	if len(a.Enum) > 0 {
+		schema["enum"] = a.Enum
+	}
+	return schema
+}
+
+// jsonType returns the JSON Schema of an OpenAPI type. References point to
+// the `$defs` of the exported schema.
+func (m *Module) jsonType(t *specType) map[string]any {
+	schema := map[string]any{}
+	if t == nil {
+		return schema
+	}
+	if t.Ref != "" {
+		schema["$ref"] = "#/$defs/" + m.id(t.Ref)
+		return schema
+	}
+	if t.Type != "" {
+		schema["type"] = t.Type
+	}
+	if t.Format != "" {
+		schema["format"] = t.Format
+	}
+	if t.Default != nil {
+		schema["default"] = t.Default
+	}
+	if len(t.Enum) > 0 {
+		schema["enum"] = t.Enum
+	}
+	if t.Items != nil {
+		schema["items"] = m.jsonType(t.Items)
+	}
+	if t.AdditionalProperties != nil {
+		schema["additionalProperties"] = m.jsonType(t.AdditionalProperties)
+	}
+	return schema
+}
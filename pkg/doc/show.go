@@ -0,0 +1,187 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package doc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// CurrentPackage is the symbol of the root package of the documented module.
+const CurrentPackage = "."
+
+// Symbol is the documentation of a symbol, either a schema or a package.
+type Symbol struct {
+	// Schema is the documented schema.
+	Schema *Schema `json:"schema,omitempty"`
+	// Chain are the base schemas of the schema, closest first.
+	Chain []*Schema `json:"chain,omitempty"`
+	// Package is the import path of the documented package, empty for the
+	// root package.
+	Package string `json:"package,omitempty"`
+	// Module is the module of the documented package.
+	Module string `json:"module,omitempty"`
+	// Schemas are the schemas of the documented package.
+	Schemas []*Schema `json:"schemas,omitempty"`
+}
+
+// Lookup returns the documentation of a symbol: `.` for the root package of
+// the documented module, a schema ID such as `k8s.api.apps.v1.Deployment`, a
+// package import path, or an unambiguous suffix of a schema ID such as
+// `Deployment` or `v1.Deployment`.
+func (idx *Index) Lookup(symbol string) (*Symbol, error) {
+	if symbol == CurrentPackage || symbol == "" {
+		if len(idx.Modules) == 0 {
+			return nil, fmt.Errorf("no package documented")
+		}
+		return idx.packageSymbol(idx.Modules[0].Name, ""), nil
+	}
+	if s := idx.schemas[symbol]; s != nil {
+		return &Symbol{Schema: s, Chain: idx.Chain(s)}, nil
+	}
+	for _, s := range idx.Schemas() {
+		if s.Package == symbol {
+			return idx.packageSymbol(s.Module, symbol), nil
+		}
+	}
+	var matches []*Schema
+	for _, s := range idx.Schemas() {
+		if strings.HasSuffix(s.ID, "."+symbol) {
+			matches = append(matches, s)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("symbol %q not found", symbol)
+	case 1:
+		return &Symbol{Schema: matches[0], Chain: idx.Chain(matches[0])}, nil
+	}
+	ids := make([]string, len(matches))
+	for i, s := range matches {
+		ids[i] = s.ID
+	}
+	return nil, fmt.Errorf("ambiguous symbol %q, candidates are: %s", symbol, strings.Join(ids, ", "))
+}
+
+func (idx *Index) packageSymbol(module, pkg string) *Symbol {
+	sym := &Symbol{Package: pkg, Module: module, Schemas: []*Schema{}}
+	for _, s := range idx.Schemas() {
+		if s.Module == module && s.Package == pkg {
+			sym.Schemas = append(sym.Schemas, s)
+		}
+	}
+	sort.Slice(sym.Schemas, func(i, j int) bool { return sym.Schemas[i].Name < sym.Schemas[j].Name })
+	return sym
+}
+
+// WriteJSON writes the documentation of the symbol as indented JSON.
+func WriteJSON(w io.Writer, sym *Symbol) error {
+	data, err := json.MarshalIndent(sym, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// WriteText writes the documentation of the symbol for the terminal.
+func WriteText(w io.Writer, sym *Symbol) error {
+	if sym.Schema == nil {
+		return writePackageText(w, sym)
+	}
+	s := sym.Schema
+	header := "schema " + s.Name
+	if s.Base != "" {
+		header += "(" + s.Base + ")"
+	}
+	fmt.Fprintln(w, header)
+	fmt.Fprintf(w, "    %s, module %s", s.ID, s.Module)
+	if s.File != "" {
+		fmt.Fprintf(w, ", %s:%d", s.File, s.Line)
+	}
+	fmt.Fprintln(w)
+	if s.Doc != "" {
+		fmt.Fprintf(w, "\n%s\n", indent(s.Doc, ""))
+	}
+	if len(sym.Chain) > 0 {
+		chain := []string{s.ID}
+		for _, base := range sym.Chain {
+			chain = append(chain, base.ID)
+		}
+		fmt.Fprintf(w, "\nInheritance: %s\n", strings.Join(chain, " <- "))
+	}
+	fmt.Fprintln(w, "\nAttributes:")
+	writeAttributes(w, s.Attributes)
+	for _, base := range sym.Chain {
+		if len(base.Attributes) > 0 {
+			fmt.Fprintf(w, "\nInherited from %s:\n", base.ID)
+			writeAttributes(w, base.Attributes)
+		}
+	}
+	if len(s.Examples) > 0 {
+		fmt.Fprintln(w, "\nExamples:")
+		for _, ex := range s.Examples {
+			title := ex.Name
+			if ex.Summary != "" {
+				title += ": " + ex.Summary
+			}
+			fmt.Fprintf(w, "  %s\n%s\n", title, indent(ex.Value, "      "))
+		}
+	}
+	return nil
+}
+
+// writeAttributes writes the attributes as KCL declarations followed by
+// their docs.
+func writeAttributes(w io.Writer, attrs []*Attribute) {
+	if len(attrs) == 0 {
+		fmt.Fprintln(w, "  (none)")
+		return
+	}
+	for _, a := range attrs {
+		decl := a.Name
+		if !a.Required {
+			decl += "?"
+		}
+		decl += ": " + a.Type
+		if a.Default != "" {
+			decl += " = " + a.Default
+		}
+		fmt.Fprintf(w, "  %s\n", decl)
+		if a.Doc != "" {
+			fmt.Fprintln(w, indent(a.Doc, "      "))
+		}
+	}
+}
+
+func writePackageText(w io.Writer, sym *Symbol) error {
+	name := sym.Package
+	if name == "" {
+		name = "(root)"
+	}
+	fmt.Fprintf(w, "package %s, module %s\n\n", name, sym.Module)
+	if len(sym.Schemas) == 0 {
+		fmt.Fprintln(w, "no schemas")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, s := range sym.Schemas {
+		fmt.Fprintf(tw, "schema %s\t%s\n", s.Name, s.Summary())
+	}
+	return tw.Flush()
+}
+
+// indent prefixes the lines of text.
+func indent(text, prefix string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, l := range lines {
+		if l != "" {
+			lines[i] = prefix + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,378 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package doc
+
+import (
+	"encoding/json"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SearchIndexFile is the file name of the prebuilt search index of the site.
+const SearchIndexFile = "search.json"
+
+// SiteOptions configures the static site.
+type SiteOptions struct {
+	// Title is the site title.
+	Title string
+	// SourceURL is the template of the source links of the documented
+	// module, such as `https://github.com/org/repo/blob/main/{path}#L{line}`,
+	// where {path} is the slash separated file path relative to the module
+	// root. Without a template, the links point to the local files.
+	SourceURL string
+}
+
+// SearchEntry is an entry of the search index.
+type SearchEntry struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Module     string   `json:"module"`
+	Package    string   `json:"package"`
+	Summary    string   `json:"summary"`
+	Attributes []string `json:"attributes"`
+	URL        string   `json:"url"`
+}
+
+// SearchIndex returns the search index of the schemas of the index.
+func (idx *Index) SearchIndex() []*SearchEntry {
+	entries := []*SearchEntry{}
+	for _, s := range idx.Schemas() {
+		e := &SearchEntry{
+			ID:         s.ID,
+			Name:       s.Name,
+			Module:     s.Module,
+			Package:    s.Package,
+			Summary:    s.Summary(),
+			Attributes: []string{},
+			URL:        "schemas/" + s.ID + ".html",
+		}
+		for _, a := range s.Attributes {
+			e.Attributes = append(e.Attributes, a.Name)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// WriteSite writes the static site of the index to dir: the `index.html`
+// page listing the schemas by module and package, a page and the JSON Schema
+// of each schema in the `schemas` directory, and the search index. Pages
+// link to the pages of the referenced and base schemas, including the
+// schemas of dependencies, and to the source of the schemas.
+func WriteSite(idx *Index, dir string, opts SiteOptions) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	schemasDir := filepath.Join(dir, "schemas")
+	// Remove the pages of deleted schemas.
+	if err := os.RemoveAll(schemasDir); err != nil {
+		return err
+	}
+	for _, d := range []string{schemasDir, filepath.Join(dir, "assets")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return err
+		}
+	}
+	assets := map[string]string{"style.css": styleCSS, "search.js": searchJS}
+	for name, content := range assets {
+		if err := os.WriteFile(filepath.Join(dir, "assets", name), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	if err := writeJSON(filepath.Join(dir, SearchIndexFile), idx.SearchIndex()); err != nil {
+		return err
+	}
+	if err := writePage(filepath.Join(dir, "index.html"), "index", idx.indexPage(opts)); err != nil {
+		return err
+	}
+	for _, s := range idx.Schemas() {
+		base := filepath.Join(schemasDir, s.ID)
+		if err := writeJSON(base+".json", idx.JSONSchema(s)); err != nil {
+			return err
+		}
+		if err := writePage(base+".html", "schema", idx.schemaPage(s, schemasDir, opts)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type indexPage struct {
+	Title   string
+	Root    string
+	Modules []*modulePage
+}
+
+type modulePage struct {
+	Name     string
+	Dep      bool
+	Packages []*packagePage
+}
+
+type packagePage struct {
+	Name    string
+	Schemas []*Schema
+}
+
+func (idx *Index) indexPage(opts SiteOptions) *indexPage {
+	page := &indexPage{Title: opts.Title}
+	for _, m := range idx.Modules {
+		mp := &modulePage{Name: m.Name, Dep: m.Dep}
+		byPkg := map[string]*packagePage{}
+		for _, s := range m.Schemas {
+			// Schemas defined by several modules are listed once.
+			if idx.schemas[s.ID] != s {
+				continue
+			}
+			pp, ok := byPkg[s.Package]
+			if !ok {
+				pp = &packagePage{Name: s.Package}
+				byPkg[s.Package] = pp
+				mp.Packages = append(mp.Packages, pp)
+			}
+			pp.Schemas = append(pp.Schemas, s)
+		}
+		sort.Slice(mp.Packages, func(i, j int) bool { return mp.Packages[i].Name < mp.Packages[j].Name })
+		page.Modules = append(page.Modules, mp)
+	}
+	return page
+}
+
+type schemaPage struct {
+	Title      string
+	Root       string
+	Schema     *Schema
+	Chain      []*link
+	Source     string
+	Attributes []*attributePage
+	Inherited  []*inheritedPage
+}
+
+type link struct {
+	Text string
+	// URL is empty when the target is not documented.
+	URL string
+}
+
+type attributePage struct {
+	*Attribute
+	Refs []*link
+}
+
+type inheritedPage struct {
+	From       *link
+	Attributes []*attributePage
+}
+
+func (idx *Index) schemaPage(s *Schema, dir string, opts SiteOptions) *schemaPage {
+	page := &schemaPage{Title: opts.Title, Root: "../", Schema: s, Source: idx.sourceLink(s, dir, opts)}
+	attrs := func(t *Schema) []*attributePage {
+		var pages []*attributePage
+		for _, a := range t.Attributes {
+			ap := &attributePage{Attribute: a}
+			for _, ref := range a.Refs {
+				ap.Refs = append(ap.Refs, idx.link(ref))
+			}
+			pages = append(pages, ap)
+		}
+		return pages
+	}
+	page.Attributes = attrs(s)
+	for _, base := range idx.Chain(s) {
+		page.Chain = append(page.Chain, idx.link(base.ID))
+		page.Inherited = append(page.Inherited, &inheritedPage{From: idx.link(base.ID), Attributes: attrs(base)})
+	}
+	if len(page.Chain) == 0 && s.Base != "" {
+		page.Chain = append(page.Chain, idx.link(s.Base))
+	}
+	return page
+}
+
+// link returns the link to the page of the schema with the ID, relative to
+// the schemas directory.
+func (idx *Index) link(id string) *link {
+	if idx.schemas[id] == nil {
+		return &link{Text: id}
+	}
+	return &link{Text: id, URL: id + ".html"}
+}
+
+// sourceLink returns the link to the source of the schema from the pages
+// directory.
+func (idx *Index) sourceLink(s *Schema, dir string, opts SiteOptions) string {
+	if s.File == "" {
+		return ""
+	}
+	file, err := filepath.Abs(s.File)
+	if err != nil {
+		return ""
+	}
+	if opts.SourceURL != "" {
+		for _, m := range idx.Modules {
+			if m.Dep || m.Name != s.Module {
+				continue
+			}
+			root, err := filepath.Abs(m.Dir)
+			if err != nil {
+				break
+			}
+			if rel, err := filepath.Rel(root, file); err == nil && !strings.HasPrefix(rel, "..") {
+				return strings.NewReplacer("{path}", filepath.ToSlash(rel), "{line}", strconv.Itoa(s.Line)).Replace(opts.SourceURL)
+			}
+		}
+	}
+	rel, err := filepath.Rel(dir, file)
+	if err != nil {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func writePage(path, name string, data any) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := siteTemplates.ExecuteTemplate(f, name, data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var siteTemplates = template.Must(template.New("site").Parse(`
+{{define "header"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.Root}}assets/style.css">
+</head>
+<body data-root="{{.Root}}">
+<header>
+<a class="home" href="{{.Root}}index.html">{{.Title}}</a>
+<input id="search" type="search" placeholder="Search schemas and attributes" autocomplete="off">
+<ul id="search-results"></ul>
+</header>
+<main>
+{{end}}
+
+{{define "footer"}}</main>
+<script src="{{.Root}}assets/search.js"></script>
+</body>
+</html>
+{{end}}
+
+{{define "index"}}{{template "header" .}}<h1>{{.Title}}</h1>
+{{range .Modules}}<section class="module">
+<h2>{{.Name}}{{if .Dep}} <small>dependency</small>{{end}}</h2>
+{{range .Packages}}<h3>{{if .Name}}{{.Name}}{{else}}(root){{end}}</h3>
+<ul>
+{{range .Schemas}}<li><a href="schemas/{{.ID}}.html">{{.Name}}</a>{{with .Summary}} - {{.}}{{end}}</li>
+{{end}}</ul>
+{{end}}</section>
+{{end}}{{template "footer" .}}{{end}}
+
+{{define "attributes"}}<table>
+<tr><th>Name</th><th>Type</th><th>Default</th><th>Required</th><th>Description</th></tr>
+{{range .}}<tr id="{{.Name}}">
+<td><code>{{.Name}}</code></td>
+<td><code>{{.Type}}</code>{{range .Refs}} {{if .URL}}<a href="{{.URL}}">{{.Text}}</a>{{else}}{{.Text}}{{end}}{{end}}</td>
+<td>{{with .Default}}<code>{{.}}</code>{{end}}</td>
+<td>{{if .Required}}yes{{else}}no{{end}}</td>
+<td>{{.Doc}}</td>
+</tr>
+{{end}}</table>
+{{end}}
+
+{{define "schema"}}{{template "header" .}}{{with .Schema}}<h1>{{.Name}}</h1>
+<p class="meta">module <code>{{.Module}}</code>{{with .Package}}, package <code>{{.}}</code>{{end}}</p>
+{{end}}{{if .Chain}}<p class="meta">inherits {{range $i, $l := .Chain}}{{if $i}} &larr; {{end}}{{if $l.URL}}<a href="{{$l.URL}}">{{$l.Text}}</a>{{else}}{{$l.Text}}{{end}}{{end}}</p>
+{{end}}<p class="meta">{{with .Source}}<a href="{{.}}">source</a> | {{end}}<a href="{{.Schema.ID}}.json">JSON Schema</a></p>
+{{with .Schema.Doc}}<pre class="doc">{{.}}</pre>
+{{end}}<h2>Attributes</h2>
+{{template "attributes" .Attributes}}{{range .Inherited}}<h3>Inherited from {{if .From.URL}}<a href="{{.From.URL}}">{{.From.Text}}</a>{{else}}{{.From.Text}}{{end}}</h3>
+{{template "attributes" .Attributes}}{{end}}{{with .Schema.Examples}}<h2>Examples</h2>
+{{range .}}<h3>{{.Name}}</h3>
+{{with .Summary}}<p>{{.}}</p>
+{{end}}<pre><code>{{.Value}}</code></pre>
+{{end}}{{end}}{{template "footer" .}}{{end}}
+`))
+
+const styleCSS = `body { font-family: sans-serif; margin: 0; color: #222; }
+header { position: relative; display: flex; gap: 1em; align-items: center; padding: 0.6em 1.5em; background: #1f2937; }
+header a.home { color: #fff; font-weight: bold; text-decoration: none; }
+#search { flex: 1; max-width: 28em; padding: 0.3em 0.5em; }
+#search-results { position: absolute; top: 2.4em; left: 12em; z-index: 1; margin: 0; padding: 0; list-style: none; background: #fff; box-shadow: 0 2px 6px rgba(0, 0, 0, 0.3); }
+#search-results li { padding: 0.3em 0.8em; }
+#search-results small { color: #666; }
+main { padding: 0 1.5em 2em; }
+.meta { color: #555; }
+small { color: #777; font-weight: normal; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ddd; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+pre { background: #f5f5f5; padding: 0.8em; overflow-x: auto; }
+pre.doc { background: none; padding: 0; white-space: pre-wrap; font-family: inherit; }
+`
+
+const searchJS = `(function () {
+  var root = document.body.getAttribute("data-root") || "";
+  var input = document.getElementById("search");
+  var results = document.getElementById("search-results");
+  var entries = null;
+
+  function score(e, q) {
+    var name = e.name.toLowerCase();
+    if (name === q) return 0;
+    if (name.indexOf(q) === 0) return 1;
+    if (e.id.toLowerCase().indexOf(q) >= 0) return 2;
+    if (e.attributes.some(function (a) { return a.toLowerCase().indexOf(q) >= 0; })) return 3;
+    if (e.summary.toLowerCase().indexOf(q) >= 0) return 4;
+    return -1;
+  }
+
+  function render() {
+    var q = input.value.trim().toLowerCase();
+    results.innerHTML = "";
+    if (!q || entries === null) return;
+    entries
+      .map(function (e) { return { e: e, s: score(e, q) }; })
+      .filter(function (r) { return r.s >= 0; })
+      .sort(function (a, b) { return a.s - b.s || a.e.id.localeCompare(b.e.id); })
+      .slice(0, 20)
+      .forEach(function (r) {
+        var li = document.createElement("li");
+        var a = document.createElement("a");
+        a.href = root + r.e.url;
+        a.textContent = r.e.id;
+        li.appendChild(a);
+        if (r.e.summary) {
+          var small = document.createElement("small");
+          small.textContent = " " + r.e.summary;
+          li.appendChild(small);
+        }
+        results.appendChild(li);
+      });
+  }
+
+  input.addEventListener("input", function () {
+    if (entries !== null) return render();
+    fetch(root + "search.json")
+      .then(function (r) { return r.json(); })
+      .then(function (data) { entries = data; render(); });
+  });
+})();
+`
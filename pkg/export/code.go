@@ -0,0 +1,303 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package export
+
+import (
+	"fmt"
+	"go/format"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"kcl-lang.io/cli/pkg/doc"
+)
+
+var goIdentRe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// goStruct returns a Go file declaring a struct per schema, or a bundle.
+// Base schemas are embedded, optional attributes are pointers or omitted
+// when empty, and docs, defaults, enums and constraints become comments.
+func (g *generator) goStruct() ([]*File, error) {
+	pkg := g.opts.Package
+	if pkg == "" {
+		pkg = strings.ToLower(goIdentRe.ReplaceAllString(g.module, ""))
+	}
+	file := func(name string, schemas []*doc.Schema) (*File, error) {
+		var b strings.Builder
+		fmt.Fprintf(&b, "// %s\n\npackage %s\n", header, pkg)
+		for _, s := range schemas {
+			b.WriteString("\n")
+			g.writeGoStruct(&b, s)
+		}
+		src, err := format.Source([]byte(b.String()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to format the Go code of %s: %w", name, err)
+		}
+		return &File{Name: name, Data: src}, nil
+	}
+	if g.opts.Bundle {
+		f, err := file(g.fileName(nil, ".go"), g.schemas)
+		if err != nil {
+			return nil, err
+		}
+		return []*File{f}, nil
+	}
+	// The referenced schemas of other modules are declared with the first
+	// schema of the module.
+	var deps []*doc.Schema
+	for _, s := range g.schemas {
+		if s.Module != g.module {
+			deps = append(deps, s)
+		}
+	}
+	var files []*File
+	for i, s := range g.roots {
+		schemas := []*doc.Schema{s}
+		if i == 0 {
+			schemas = append(schemas, deps...)
+		}
+		f, err := file(strings.ToLower(strings.ReplaceAll(s.ID, ".", "_"))+".go", schemas)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+func (g *generator) writeGoStruct(b *strings.Builder, s *doc.Schema) {
+	name := g.names[s.ID]
+	constraints, checks := s.Constraints()
+	comment(b, "", name+" is the KCL schema "+s.ID+".", s.Doc, checkLines(checks))
+	fmt.Fprintf(b, "type %s struct {\n", name)
+	if base := g.names[s.Base]; base != "" {
+		fmt.Fprintf(b, "\t%s\n", base)
+	}
+	for _, a := range s.Attributes {
+		comment(b, "\t", "", strings.Join(describe(a, constraints), "\n"), nil)
+		typ := g.goType(a.JSONSchema())
+		tag := a.Name
+		if !a.Required {
+			tag += ",omitempty"
+			if !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map[") && typ != "any" {
+				typ = "*" + typ
+			}
+		}
+		fmt.Fprintf(b, "\t%s %s `json:%s`\n", pascal(a.Name), typ, strconv.Quote(tag))
+	}
+	b.WriteString("}\n")
+}
+
+// goType returns the Go type of a JSON Schema.
+func (g *generator) goType(schema map[string]any) string {
+	if ref, ok := schema["$ref"].(string); ok {
+		if name := g.names[refID(ref)]; name != "" {
+			return name
+		}
+		return "map[string]any"
+	}
+	switch schema["type"] {
+	case "string":
+		return "string"
+	case "integer":
+		return "int64"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		items, _ := schema["items"].(map[string]any)
+		return "[]" + g.goType(items)
+	case "object":
+		if values, ok := schema["additionalProperties"].(map[string]any); ok {
+			return "map[string]" + g.goType(values)
+		}
+		return "map[string]any"
+	}
+	return "any"
+}
+
+// typeScript returns a TypeScript module declaring an interface per schema,
+// or a bundle. Base schemas are extended, enums are unions of literal types
+// and docs, defaults and constraints become JSDoc comments.
+func (g *generator) typeScript() ([]*File, error) {
+	if g.opts.Bundle {
+		var b strings.Builder
+		fmt.Fprintf(&b, "// %s\n", header)
+		for _, s := range g.schemas {
+			b.WriteString("\n")
+			g.writeInterface(&b, s)
+		}
+		return []*File{{Name: g.fileName(nil, ".ts"), Data: []byte(b.String())}}, nil
+	}
+	var files []*File
+	for _, s := range g.schemas {
+		var body strings.Builder
+		deps := g.writeInterface(&body, s)
+		var b strings.Builder
+		fmt.Fprintf(&b, "// %s\n\n", header)
+		for _, id := range deps {
+			fmt.Fprintf(&b, "import { %s } from \"./%s\";\n", g.names[id], id)
+		}
+		if len(deps) > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(body.String())
+		files = append(files, &File{Name: g.fileName(s, ".ts"), Data: []byte(b.String())})
+	}
+	return files, nil
+}
+
+// writeInterface writes the interface of the schema and returns the IDs of
+// the other schemas it uses.
+func (g *generator) writeInterface(b *strings.Builder, s *doc.Schema) []string {
+	var deps []string
+	seen := map[string]bool{s.ID: true}
+	use := func(id string) string {
+		if !seen[id] {
+			seen[id] = true
+			deps = append(deps, id)
+		}
+		return g.names[id]
+	}
+	constraints, checks := s.Constraints()
+	jsdoc(b, "", strings.Split(strings.TrimSpace(s.Doc), "\n"), checkLines(checks))
+	fmt.Fprintf(b, "export interface %s", g.names[s.ID])
+	if g.names[s.Base] != "" {
+		fmt.Fprintf(b, " extends %s", use(s.Base))
+	}
+	b.WriteString(" {\n")
+	for _, a := range s.Attributes {
+		var tags []string
+		if a.Default != "" {
+			tags = append(tags, "@default "+a.Default)
+		}
+		for _, c := range constraints {
+			if c.Attribute == a.Name && c.Keyword != "enum" {
+				tags = append(tags, fmt.Sprintf("@%s %v", c.Keyword, c.Value))
+			}
+		}
+		var docLines []string
+		if a.Doc != "" {
+			docLines = strings.Split(a.Doc, "\n")
+		}
+		jsdoc(b, "  ", docLines, tags)
+		optional := ""
+		if !a.Required {
+			optional = "?"
+		}
+		schema := a.JSONSchema()
+		for _, c := range constraints {
+			if c.Attribute == a.Name && c.Keyword == "enum" {
+				schema["enum"] = c.Value
+			}
+		}
+		fmt.Fprintf(b, "  %s%s: %s;\n", tsKey(a.Name), optional, g.tsType(schema, use))
+	}
+	b.WriteString("}\n")
+	return deps
+}
+
+// tsType returns the TypeScript type of a JSON Schema.
+func (g *generator) tsType(schema map[string]any, use func(id string) string) string {
+	if ref, ok := schema["$ref"].(string); ok {
+		if g.names[refID(ref)] != "" {
+			return use(refID(ref))
+		}
+		return "Record<string, unknown>"
+	}
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		values := make([]string, len(enum))
+		for i, v := range enum {
+			if s, ok := v.(string); ok {
+				values[i] = strconv.Quote(s)
+			} else {
+				values[i] = fmt.Sprint(v)
+			}
+		}
+		return strings.Join(values, " | ")
+	}
+	switch schema["type"] {
+	case "string":
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		items, _ := schema["items"].(map[string]any)
+		t := g.tsType(items, use)
+		if strings.Contains(t, "|") {
+			t = "(" + t + ")"
+		}
+		return t + "[]"
+	case "object":
+		if values, ok := schema["additionalProperties"].(map[string]any); ok {
+			return "Record<string, " + g.tsType(values, use) + ">"
+		}
+		return "Record<string, unknown>"
+	}
+	return "unknown"
+}
+
+var tsIdentRe = regexp.MustCompile(`^[A-Za-z_$][\w$]*$`)
+
+func tsKey(name string) string {
+	if tsIdentRe.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+// checkLines returns the comment lines of the checks that are not expressed
+// by the target.
+func checkLines(checks []*doc.Check) []string {
+	var lines []string
+	for _, c := range checks {
+		line := "Check: " + c.Expr
+		if c.Message != "" {
+			line += " (" + c.Message + ")"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// comment writes a Go comment of the title, text and extra lines.
+func comment(b *strings.Builder, indent, title, text string, extra []string) {
+	var lines []string
+	if title != "" {
+		lines = append(lines, title)
+	}
+	if text = strings.TrimSpace(text); text != "" {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, strings.Split(text, "\n")...)
+	}
+	if len(extra) > 0 {
+		lines = append(append(lines, ""), extra...)
+	}
+	for _, l := range lines {
+		fmt.Fprintf(b, "%s// %s\n", indent, l)
+	}
+}
+
+// jsdoc writes a JSDoc comment of the lines and tags, if any.
+func jsdoc(b *strings.Builder, indent string, lines, tags []string) {
+	var all []string
+	for _, l := range lines {
+		if strings.TrimSpace(l) != "" || len(all) > 0 {
+			all = append(all, l)
+		}
+	}
+	all = append(all, tags...)
+	if len(all) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "%s/**\n", indent)
+	for _, l := range all {
+		fmt.Fprintf(b, "%s * %s\n", indent, strings.ReplaceAll(strings.TrimRight(l, " "), "*/", "*\\/"))
+	}
+	fmt.Fprintf(b, "%s */\n", indent)
+}
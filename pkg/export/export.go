@@ -0,0 +1,202 @@
+// Copyright The KCL Authors. All rights reserved.
+
+// Package export converts the schemas of a KCL module to JSON Schema,
+// OpenAPI, Kubernetes CRDs, Go structs and TypeScript interfaces.
+package export
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"kcl-lang.io/cli/pkg/doc"
+)
+
+// The export modes.
+const (
+	JSONSchema = "jsonschema"
+	OpenAPI    = "openapi"
+	CRD        = "crd"
+	GoStruct   = "gostruct"
+	TypeScript = "typescript"
+)
+
+// Modes are the supported export modes.
+var Modes = []string{JSONSchema, OpenAPI, CRD, GoStruct, TypeScript}
+
+// header is the first line of the generated code files.
+const header = "Code generated by kcl export. DO NOT EDIT."
+
+// Options configures the generated files.
+type Options struct {
+	// Bundle writes all the schemas to a single file named after the module
+	// instead of one file per schema.
+	Bundle bool
+	// Group and Version are the API group and version of the CRDs.
+	Group   string
+	Version string
+	// Package is the Go package name, the module name by default.
+	Package string
+}
+
+// File is a generated file.
+type File struct {
+	// Name is the file name.
+	Name string
+	Data []byte
+}
+
+// Generate returns the files of the schemas of the documented module, the
+// first module of the index, in the export mode. The schemas of other modules
+// referenced by the exported schemas are included where the target requires
+// them.
+func Generate(idx *doc.Index, mode string, opts Options) ([]*File, error) {
+	if len(idx.Modules) == 0 {
+		return nil, fmt.Errorf("no module to export")
+	}
+	g := &generator{idx: idx, opts: opts, module: idx.Modules[0].Name}
+	g.roots, g.schemas = g.collect()
+	if len(g.roots) == 0 {
+		return nil, fmt.Errorf("no schemas found in module %s", g.module)
+	}
+	g.names = typeNames(g.schemas)
+	switch strings.ToLower(mode) {
+	case JSONSchema:
+		return g.jsonSchema()
+	case OpenAPI:
+		return g.openAPI()
+	case CRD:
+		return g.crd()
+	case GoStruct:
+		return g.goStruct()
+	case TypeScript:
+		return g.typeScript()
+	}
+	return nil, fmt.Errorf("invalid mode: %s, must be one of %s", mode, strings.Join(Modes, ", "))
+}
+
+type generator struct {
+	idx    *doc.Index
+	opts   Options
+	module string
+	// roots are the schemas of the module.
+	roots []*doc.Schema
+	// schemas are the roots and the schemas they depend on.
+	schemas []*doc.Schema
+	// names are the type names of the schemas in code.
+	names map[string]string
+}
+
+// collect returns the schemas of the module and all the schemas they
+// reference or inherit, sorted by ID.
+func (g *generator) collect() ([]*doc.Schema, []*doc.Schema) {
+	var roots []*doc.Schema
+	seen := map[string]bool{}
+	var pending []string
+	for _, s := range g.idx.Schemas() {
+		if s.Module == g.module {
+			roots = append(roots, s)
+			pending = append(pending, s.ID)
+		}
+	}
+	var all []*doc.Schema
+	for len(pending) > 0 {
+		id := pending[0]
+		pending = pending[1:]
+		s := g.idx.Schema(id)
+		if seen[id] || s == nil {
+			continue
+		}
+		seen[id] = true
+		all = append(all, s)
+		if s.Base != "" {
+			pending = append(pending, s.Base)
+		}
+		for _, a := range s.Attributes {
+			pending = append(pending, a.Refs...)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	return roots, all
+}
+
+// typeNames returns unique code type names of the schemas: the schema name,
+// or the PascalCase ID when names collide.
+func typeNames(schemas []*doc.Schema) map[string]string {
+	count := map[string]int{}
+	for _, s := range schemas {
+		count[s.Name]++
+	}
+	names := map[string]string{}
+	for _, s := range schemas {
+		if count[s.Name] == 1 {
+			names[s.ID] = pascal(s.Name)
+		} else {
+			names[s.ID] = pascal(strings.ReplaceAll(s.ID, ".", "_"))
+		}
+	}
+	return names
+}
+
+// pascal converts a snake case or dotted name to PascalCase.
+func pascal(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '_' || r == '-' || r == '.' || r == '$' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// fileName returns the file name of a schema or of the bundle.
+func (g *generator) fileName(s *doc.Schema, ext string) string {
+	if s == nil {
+		return g.module + ext
+	}
+	return s.ID + ext
+}
+
+// refID returns the schema ID of a JSON Schema `$ref` of the doc package.
+func refID(ref string) string {
+	return strings.TrimPrefix(ref, "#/$defs/")
+}
+
+// describe returns the comment lines documenting an attribute: its doc,
+// default, allowed values and constraints.
+func describe(a *doc.Attribute, constraints []doc.Constraint) []string {
+	var lines []string
+	if a.Doc != "" {
+		lines = append(lines, strings.Split(a.Doc, "\n")...)
+	}
+	if a.Default != "" {
+		lines = append(lines, "Default: "+a.Default)
+	}
+	enum := a.Enum
+	for _, c := range constraints {
+		if c.Attribute == a.Name && c.Keyword == "enum" {
+			enum, _ = c.Value.([]any)
+		}
+	}
+	if len(enum) > 0 {
+		values := make([]string, len(enum))
+		for i, v := range enum {
+			values[i] = fmt.Sprintf("%q", fmt.Sprint(v))
+		}
+		lines = append(lines, "Enum: "+strings.Join(values, ", "))
+	}
+	for _, c := range constraints {
+		if c.Attribute == a.Name && c.Keyword != "enum" {
+			lines = append(lines, fmt.Sprintf("Constraint: %s=%v", c.Keyword, c.Value))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,113 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package export
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"kcl-lang.io/cli/pkg/doc"
+)
+
+const appSpec = `{"definitions": {
+	"App": {"type": "object", "description": "An application.", "required": ["port"], "properties": {
+		"port": {"type": "integer", "default": "80"},
+		"deployment": {"$ref": "#/definitions/k8s.api.apps.v1.Deployment"}
+	}},
+	"base.Base": {"type": "object", "description": "The base schema.", "required": ["name"], "properties": {
+		"name": {"type": "string", "description": "The name."}
+	}}
+}}`
+
+const k8sSpec = `{"definitions": {
+	"api.apps.v1.Deployment": {"type": "object", "properties": {"replicas": {"type": "integer"}}}
+}}`
+
+func testIndex(t *testing.T) *doc.Index {
+	t.Helper()
+	testdata := filepath.Join("..", "doc", "testdata")
+	app, err := doc.LoadModule("app", filepath.Join(testdata, "app"), false, []byte(appSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	k8s, err := doc.LoadModule("k8s", filepath.Join(testdata, "deps", "k8s"), true, []byte(k8sSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc.NewIndex(app, k8s)
+}
+
+func TestGenerate(t *testing.T) {
+	idx := testIndex(t)
+	tests := []struct {
+		mode   string
+		opts   Options
+		files  []string
+		output []string
+	}{
+		{
+			mode:   JSONSchema,
+			files:  []string{"App.json", "base.Base.json"},
+			output: []string{`"$ref": "#/$defs/k8s.api.apps.v1.Deployment"`, `"exclusiveMaximum": 65536`, `"x-kcl-checks": [`},
+		},
+		{
+			mode:   OpenAPI,
+			opts:   Options{Bundle: true},
+			files:  []string{"app.yaml"},
+			output: []string{"openapi: 3.1.0", `$ref: "#/components/schemas/k8s.api.apps.v1.Deployment"`, "k8s.api.apps.v1.Deployment:"},
+		},
+		{
+			mode:  CRD,
+			opts:  Options{Bundle: true, Group: "example.com", Version: "v1alpha1"},
+			files: []string{"app.yaml"},
+			output: []string{
+				"name: apps.example.com", "kind: App", "name: v1alpha1", "---\n",
+				"exclusiveMaximum: true", "replicas:",
+			},
+		},
+		{
+			mode:  GoStruct,
+			files: []string{"app.go", "base_base.go"},
+			output: []string{
+				"package app", "type App struct {\n\tBase\n",
+				"\t// Default: 80\n\t// Constraint: exclusiveMinimum=0\n", "Port int64 `json:\"port\"`",
+				"Deployment *Deployment `json:\"deployment,omitempty\"`", "// Check: port % 2 == 0",
+				"type Deployment struct",
+			},
+		},
+		{
+			mode:  TypeScript,
+			files: []string{"App.ts", "base.Base.ts", "k8s.api.apps.v1.Deployment.ts"},
+			output: []string{
+				`import { Base } from "./base.Base";`, "export interface App extends Base {",
+				"   * @default 80\n   * @exclusiveMinimum 0\n", "  port: number;", "  deployment?: Deployment;",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			files, err := Generate(idx, tt.mode, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			var all strings.Builder
+			for _, f := range files {
+				names = append(names, f.Name)
+				all.Write(f.Data)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.files, ",") {
+				t.Errorf("files: got %v, want %v", names, tt.files)
+			}
+			for _, want := range tt.output {
+				if !strings.Contains(all.String(), want) {
+					t.Errorf("the output does not contain %q:\n%s", want, all.String())
+				}
+			}
+		})
+	}
+	if _, err := Generate(idx, "xml", Options{}); err == nil {
+		t.Error("expected an invalid mode error")
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"kcl-lang.io/cli/pkg/doc"
+)
+
+// jsonSchema returns the JSON Schema of each schema, or a bundle defining
+// all the schemas in `$defs`.
+func (g *generator) jsonSchema() ([]*File, error) {
+	if g.opts.Bundle {
+		bundle := map[string]any{
+			"$schema": doc.JSONSchemaDialect,
+			"title":   g.module,
+			"$defs":   g.definitions(),
+		}
+		data, err := marshalJSON(bundle)
+		if err != nil {
+			return nil, err
+		}
+		return []*File{{Name: g.fileName(nil, ".json"), Data: data}}, nil
+	}
+	var files []*File
+	for _, s := range g.roots {
+		data, err := marshalJSON(g.idx.JSONSchema(s))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, &File{Name: g.fileName(s, ".json"), Data: data})
+	}
+	return files, nil
+}
+
+// definitions returns the JSON Schema objects of the exported schemas by ID.
+func (g *generator) definitions() map[string]any {
+	defs := map[string]any{}
+	for _, s := range g.schemas {
+		obj := g.idx.JSONSchema(s)
+		delete(obj, "$schema")
+		delete(obj, "$defs")
+		defs[s.ID] = obj
+	}
+	return defs
+}
+
+// openAPI returns an OpenAPI 3.1 document of each schema and the schemas it
+// references, or a bundle of all the schemas.
+func (g *generator) openAPI() ([]*File, error) {
+	doc := func(title string, schemas map[string]any) ([]byte, error) {
+		return yaml.Marshal(yaml.MapSlice{
+			{Key: "openapi", Value: "3.1.0"},
+			{Key: "info", Value: yaml.MapSlice{{Key: "title", Value: title}, {Key: "version", Value: "1.0.0"}}},
+			{Key: "paths", Value: map[string]any{}},
+			{Key: "components", Value: map[string]any{"schemas": rewriteRefs(schemas, "#/components/schemas/")}},
+		})
+	}
+	if g.opts.Bundle {
+		data, err := doc(g.module, g.definitions())
+		if err != nil {
+			return nil, err
+		}
+		return []*File{{Name: g.fileName(nil, ".yaml"), Data: data}}, nil
+	}
+	var files []*File
+	for _, s := range g.roots {
+		obj := g.idx.JSONSchema(s)
+		schemas := map[string]any{}
+		if defs, ok := obj["$defs"].(map[string]any); ok {
+			for id, def := range defs {
+				schemas[id] = def
+			}
+		}
+		delete(obj, "$schema")
+		delete(obj, "$defs")
+		schemas[s.ID] = obj
+		data, err := doc(s.ID, schemas)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, &File{Name: g.fileName(s, ".yaml"), Data: data})
+	}
+	return files, nil
+}
+
+// crd returns a Kubernetes CustomResourceDefinition for each schema, whose
+// attributes form the spec of the resource, or a multi document bundle.
+func (g *generator) crd() ([]*File, error) {
+	group, version := g.opts.Group, g.opts.Version
+	if group == "" || version == "" {
+		return nil, fmt.Errorf("the CRD group and version are required")
+	}
+	var files []*File
+	var bundle []string
+	for _, s := range g.roots {
+		spec := structural(g.idx.JSONSchema(s), nil)
+		plural := plural(strings.ToLower(s.Name))
+		root := yaml.MapSlice{
+			{Key: "type", Value: "object"},
+		}
+		if s.Doc != "" {
+			root = append(root, yaml.MapItem{Key: "description", Value: s.Doc})
+		}
+		root = append(root, yaml.MapItem{Key: "properties", Value: yaml.MapSlice{
+			{Key: "apiVersion", Value: map[string]any{"type": "string"}},
+			{Key: "kind", Value: map[string]any{"type": "string"}},
+			{Key: "metadata", Value: map[string]any{"type": "object"}},
+			{Key: "spec", Value: spec},
+		}})
+		if _, ok := spec["required"]; ok {
+			root = append(root, yaml.MapItem{Key: "required", Value: []string{"spec"}})
+		}
+		crd := yaml.MapSlice{
+			{Key: "apiVersion", Value: "apiextensions.k8s.io/v1"},
+			{Key: "kind", Value: "CustomResourceDefinition"},
+			{Key: "metadata", Value: map[string]any{"name": plural + "." + group}},
+			{Key: "spec", Value: yaml.MapSlice{
+				{Key: "group", Value: group},
+				{Key: "names", Value: yaml.MapSlice{
+					{Key: "kind", Value: s.Name},
+					{Key: "listKind", Value: s.Name + "List"},
+					{Key: "plural", Value: plural},
+					{Key: "singular", Value: strings.ToLower(s.Name)},
+				}},
+				{Key: "scope", Value: "Namespaced"},
+				{Key: "versions", Value: []any{yaml.MapSlice{
+					{Key: "name", Value: version},
+					{Key: "served", Value: true},
+					{Key: "storage", Value: true},
+					{Key: "schema", Value: map[string]any{"openAPIV3Schema": root}},
+				}}},
+			}},
+		}
+		data, err := yaml.Marshal(crd)
+		if err != nil {
+			return nil, err
+		}
+		bundle = append(bundle, string(data))
+		files = append(files, &File{Name: g.fileName(s, ".yaml"), Data: data})
+	}
+	if g.opts.Bundle {
+		return []*File{{Name: g.fileName(nil, ".yaml"), Data: []byte(strings.Join(bundle, "---\n"))}}, nil
+	}
+	return files, nil
+}
+
+// structural converts a JSON Schema to a structural OpenAPI v3 schema as
+// required by CRDs: references are inlined, recursive references accept any
+// object, and the keywords CRDs do not support are converted or dropped.
+func structural(schema map[string]any, defs map[string]any) map[string]any {
+	if d, ok := schema["$defs"].(map[string]any); ok {
+		defs = d
+	}
+	return structuralType(schema, defs, map[string]bool{})
+}
+
+func structuralType(schema map[string]any, defs map[string]any, visiting map[string]bool) map[string]any {
+	if ref, ok := schema["$ref"].(string); ok {
+		id := refID(ref)
+		def, ok := defs[id].(map[string]any)
+		if !ok || visiting[id] {
+			return map[string]any{"type": "object", "x-kubernetes-preserve-unknown-fields": true}
+		}
+		visiting[id] = true
+		defer delete(visiting, id)
+		out := structuralType(def, defs, visiting)
+		if desc, ok := schema["description"]; ok {
+			out["description"] = desc
+		}
+		return out
+	}
+	out := map[string]any{}
+	for k, v := range schema {
+		switch k {
+		case "$schema", "$defs", "title", doc.ChecksExtension:
+		case "const":
+			out["enum"] = []any{v}
+		case "exclusiveMinimum", "exclusiveMaximum":
+			// OpenAPI v3.0 exclusive bounds are booleans.
+			bound := map[string]string{"exclusiveMinimum": "minimum", "exclusiveMaximum": "maximum"}[k]
+			out[bound] = v
+			out[k] = true
+		case "properties":
+			props := map[string]any{}
+			for name, p := range v.(map[string]any) {
+				props[name] = structuralType(p.(map[string]any), defs, visiting)
+			}
+			out[k] = props
+		case "items", "additionalProperties":
+			if sub, ok := v.(map[string]any); ok {
+				out[k] = structuralType(sub, defs, visiting)
+			} else {
+				out[k] = v
+			}
+		default:
+			out[k] = v
+		}
+	}
+	if _, ok := out["type"]; !ok {
+		out["x-kubernetes-preserve-unknown-fields"] = true
+	}
+	return out
+}
+
+// rewriteRefs returns a copy of v with the `$defs` references rewritten to
+// the prefix.
+func rewriteRefs(v any, prefix string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, val := range v {
+			if ref, ok := val.(string); ok && k == "$ref" {
+				out[k] = prefix + refID(ref)
+			} else {
+				out[k] = rewriteRefs(val, prefix)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = rewriteRefs(val, prefix)
+		}
+		return out
+	}
+	return v
+}
+
+// plural returns the English plural of a lower case kind.
+func plural(kind string) string {
+	switch {
+	case strings.HasSuffix(kind, "s"), strings.HasSuffix(kind, "x"), strings.HasSuffix(kind, "ch"), strings.HasSuffix(kind, "sh"):
+		return kind + "es"
+	case strings.HasSuffix(kind, "y") && len(kind) > 1 && !strings.ContainsRune("aeiou", rune(kind[len(kind)-2])):
+		return kind[:len(kind)-1] + "ies"
+	}
+	return kind + "s"
+}
+
+func marshalJSON(v any) ([]byte, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,179 @@
+// Copyright The KCL Authors. All rights reserved.
+
+// Package formatter applies the configurable style of `kcl fmt` on top of the
+// canonical output of the KCL formatter.
+package formatter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+	"github.com/goccy/go-yaml"
+	"kcl-lang.io/cli/pkg/source"
+)
+
+// ConfigFile is the name of the standalone formatter configuration file.
+const ConfigFile = ".kclfmt.yaml"
+
+const (
+	// Preserve keeps the trailing commas or quotes of the formatter output.
+	Preserve = "preserve"
+	// Always adds trailing commas to the elements of multi-line collections.
+	Always = "always"
+	// Never removes the trailing commas of multi-line collections.
+	Never = "never"
+	// Double converts string literals to double quotes.
+	Double = "double"
+	// Single converts string literals to single quotes.
+	Single = "single"
+)
+
+// DefaultIndentWidth is the indent width of the KCL formatter.
+const DefaultIndentWidth = 4
+
+// Config is the formatter style, read from the `[fmt]` section of kcl.mod or
+// from a .kclfmt.yaml file.
+type Config struct {
+	// IndentWidth is the number of spaces of an indentation level.
+	IndentWidth int `toml:"indent_width" yaml:"indent_width" json:"indent_width"`
+	// MaxLineLength is the line length beyond which single-line lists and
+	// dicts are wrapped, one element per line. Zero disables wrapping.
+	MaxLineLength int `toml:"max_line_length" yaml:"max_line_length" json:"max_line_length"`
+	// SortImports sorts the leading import statements by path.
+	SortImports bool `toml:"sort_imports" yaml:"sort_imports" json:"sort_imports"`
+	// GroupImports separates system, external and relative imports with a
+	// blank line. It implies SortImports.
+	GroupImports bool `toml:"group_imports" yaml:"group_imports" json:"group_imports"`
+	// TrailingComma is one of preserve, always or never.
+	TrailingComma string `toml:"trailing_comma" yaml:"trailing_comma" json:"trailing_comma"`
+	// QuoteStyle is one of preserve, double or single.
+	QuoteStyle string `toml:"quote_style" yaml:"quote_style" json:"quote_style"`
+}
+
+// DefaultConfig returns the style of the KCL formatter.
+func DefaultConfig() *Config {
+	return &Config{
+		IndentWidth:   DefaultIndentWidth,
+		TrailingComma: Preserve,
+		QuoteStyle:    Preserve,
+	}
+}
+
+// Validate checks the config values.
+func (c *Config) Validate() error {
+	if c.IndentWidth <= 0 {
+		return fmt.Errorf("invalid indent_width %d, must be positive", c.IndentWidth)
+	}
+	if c.MaxLineLength < 0 {
+		return fmt.Errorf("invalid max_line_length %d, must not be negative", c.MaxLineLength)
+	}
+	switch c.TrailingComma {
+	case Preserve, Always, Never:
+	default:
+		return fmt.Errorf("invalid trailing_comma %q, must be one of preserve, always or never", c.TrailingComma)
+	}
+	switch c.QuoteStyle {
+	case Preserve, Double, Single:
+	default:
+		return fmt.Errorf("invalid quote_style %q, must be one of preserve, double or single", c.QuoteStyle)
+	}
+	return nil
+}
+
+// IsDefault reports whether the config keeps the formatter output as is.
+func (c *Config) IsDefault() bool {
+	return *c == *DefaultConfig()
+}
+
+// LoadConfig reads the formatter config from a kcl.mod file, or from a YAML
+// file with any other name. Unset values keep their defaults. It returns a
+// nil config for a kcl.mod without a `[fmt]` section.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := DefaultConfig()
+	if filepath.Base(path) == source.ModFile {
+		mod := struct {
+			Fmt *toml.Primitive `toml:"fmt"`
+		}{}
+		md, err := toml.Decode(string(data), &mod)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if mod.Fmt == nil {
+			return nil, nil
+		}
+		if err := md.PrimitiveDecode(*mod.Fmt, c); err != nil {
+			return nil, fmt.Errorf("failed to parse the [fmt] section of %s: %w", path, err)
+		}
+	} else if err := yaml.UnmarshalWithOptions(data, c, yaml.Strict()); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Resolver discovers the formatter config of KCL files by walking up from
+// their directory. In each directory, a .kclfmt.yaml file takes precedence
+// over the `[fmt]` section of kcl.mod. The results are cached per directory.
+type Resolver struct {
+	cache map[string]*Config
+}
+
+// NewResolver returns a new config resolver.
+func NewResolver() *Resolver {
+	return &Resolver{cache: map[string]*Config{}}
+}
+
+// Resolve returns the config applying to the file or directory at path, or
+// the default config when none is found.
+func (r *Resolver) Resolve(path string) (*Config, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	return r.resolveDir(dir)
+}
+
+func (r *Resolver) resolveDir(dir string) (*Config, error) {
+	if c, ok := r.cache[dir]; ok {
+		return c, nil
+	}
+	var c *Config
+	for _, name := range []string{ConfigFile, source.ModFile} {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		found, err := LoadConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		if found != nil {
+			c = found
+			break
+		}
+	}
+	if c == nil {
+		if parent := filepath.Dir(dir); parent != dir {
+			parentConfig, err := r.resolveDir(parent)
+			if err != nil {
+				return nil, err
+			}
+			c = parentConfig
+		} else {
+			c = DefaultConfig()
+		}
+	}
+	r.cache[dir] = c
+	return c, nil
+}
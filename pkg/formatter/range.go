@@ -0,0 +1,119 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package formatter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"kcl-lang.io/cli/pkg/diff"
+	"kcl-lang.io/cli/pkg/source"
+)
+
+// LineRange is an inclusive range of 1-based lines.
+type LineRange struct {
+	Start int
+	End   int
+}
+
+// ParseLineRange parses a `start:end` line range. A single line number
+// selects one line.
+func ParseLineRange(s string) (LineRange, error) {
+	start, end, ok := strings.Cut(s, ":")
+	if !ok {
+		end = start
+	}
+	a, err := strconv.Atoi(strings.TrimSpace(start))
+	if err != nil {
+		return LineRange{}, fmt.Errorf("invalid line range %q, expected <start>:<end>", s)
+	}
+	b, err := strconv.Atoi(strings.TrimSpace(end))
+	if err != nil {
+		return LineRange{}, fmt.Errorf("invalid line range %q, expected <start>:<end>", s)
+	}
+	if a < 1 || b < a {
+		return LineRange{}, fmt.Errorf("invalid line range %q, lines start at 1 and the end must not precede the start", s)
+	}
+	return LineRange{Start: a, End: b}, nil
+}
+
+// Position is a 0-based line and character offset, as in the language
+// server protocol.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a range of text between two positions, the end being exclusive.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// TextEdit replaces the text of a range with a new text.
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// FormatRange returns the edits formatting the top-level statements of the
+// source of the file at path that overlap the line range. Each statement is
+// formatted on its own with the format function, and the rest of the source
+// is left untouched. Sources with syntax errors are not formatted.
+func FormatRange(path string, src []byte, r LineRange, format func([]byte) ([]byte, error)) ([]TextEdit, error) {
+	f, err := source.ParseFile(path, src)
+	if err != nil {
+		return nil, err
+	}
+	lines := diff.SplitLines(string(src))
+	var edits []TextEdit
+	for _, st := range f.Statements {
+		if st.End < r.Start || st.Start > r.End || st.End > len(lines) {
+			continue
+		}
+		chunk := strings.Join(lines[st.Start-1:st.End], "")
+		formatted, err := format([]byte(chunk))
+		if err != nil {
+			return nil, fmt.Errorf("failed to format lines %d-%d: %w", st.Start, st.End, err)
+		}
+		text := string(formatted)
+		end := Position{Line: st.End}
+		if !strings.HasSuffix(chunk, "\n") {
+			// The statement ends the file without a line terminator.
+			text = strings.TrimSuffix(text, "\n")
+			end = Position{Line: st.End - 1, Character: len(lines[st.End-1])}
+		}
+		if text == chunk {
+			continue
+		}
+		edits = append(edits, TextEdit{
+			Range:   Range{Start: Position{Line: st.Start - 1}, End: end},
+			NewText: text,
+		})
+	}
+	return edits, nil
+}
+
+// ApplyEdits applies non-overlapping edits to src.
+func ApplyEdits(src []byte, edits []TextEdit) []byte {
+	lines := diff.SplitLines(string(src))
+	offset := func(p Position) int {
+		n := 0
+		for i := 0; i < p.Line && i < len(lines); i++ {
+			n += len(lines[i])
+		}
+		return min(n+p.Character, len(src))
+	}
+	sorted := append([]TextEdit(nil), edits...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].Range.Start, sorted[j].Range.Start
+		return a.Line > b.Line || (a.Line == b.Line && a.Character > b.Character)
+	})
+	out := string(src)
+	for _, e := range sorted {
+		out = out[:offset(e.Range.Start)] + e.NewText + out[offset(e.Range.End):]
+	}
+	return []byte(out)
+}
@@ -0,0 +1,57 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package formatter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// spaceAssign is a stand-in formatter adding spaces around `=`.
+func spaceAssign(code []byte) ([]byte, error) {
+	src := strings.ReplaceAll(string(code), " = ", "=")
+	src = strings.ReplaceAll(src, "=", " = ")
+	if !strings.HasSuffix(src, "\n") {
+		src += "\n"
+	}
+	return []byte(src), nil
+}
+
+func TestParseLineRange(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    LineRange
+		wantErr bool
+	}{
+		{input: "10:40", want: LineRange{Start: 10, End: 40}},
+		{input: "3", want: LineRange{Start: 3, End: 3}},
+		{input: "5:2", wantErr: true},
+		{input: "0:2", wantErr: true},
+		{input: "a:b", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLineRange(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLineRange(%q) = %v, %v", tt.input, got, err)
+		}
+	}
+}
+
+func TestFormatRange(t *testing.T) {
+	src := "a=1\n\nb={\n    c=2\n}\nd=3"
+	edits, err := FormatRange("main.k", []byte(src), LineRange{Start: 4, End: 6}, spaceAssign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TextEdit{
+		{Range: Range{Start: Position{Line: 2}, End: Position{Line: 5}}, NewText: "b = {\n    c = 2\n}\n"},
+		{Range: Range{Start: Position{Line: 5}, End: Position{Line: 5, Character: 3}}, NewText: "d = 3"},
+	}
+	if !reflect.DeepEqual(edits, want) {
+		t.Fatalf("got %+v, want %+v", edits, want)
+	}
+	if got, want := string(ApplyEdits([]byte(src), edits)), "a=1\n\nb = {\n    c = 2\n}\nd = 3"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
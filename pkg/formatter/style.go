@@ -0,0 +1,434 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package formatter
+
+import (
+	"sort"
+	"strings"
+
+	"kcl-lang.io/cli/pkg/source"
+)
+
+// systemModules are the KCL system modules, grouped first by GroupImports.
+var systemModules = map[string]bool{
+	"base64": true, "collection": true, "crypto": true, "datetime": true,
+	"file": true, "json": true, "manifests": true, "math": true, "net": true,
+	"regex": true, "runtime": true, "template": true, "units": true, "yaml": true,
+}
+
+// Apply applies the config to code produced by the KCL formatter. The code
+// is returned unchanged for the default config.
+func Apply(code []byte, c *Config) []byte {
+	if c == nil || c.IsDefault() {
+		return code
+	}
+	src := string(code)
+	if c.SortImports || c.GroupImports {
+		src = SortImports(src, c.GroupImports)
+	}
+	if c.IndentWidth != DefaultIndentWidth {
+		src = reindent(src, c.IndentWidth)
+	}
+	if c.MaxLineLength > 0 {
+		src = wrap(src, c.MaxLineLength, c.IndentWidth)
+	}
+	if c.TrailingComma != Preserve {
+		src = trailingCommas(src, c.TrailingComma == Always)
+	}
+	if c.QuoteStyle != Preserve {
+		src = quotes(src, c.QuoteStyle)
+	}
+	return []byte(src)
+}
+
+type class byte
+
+const (
+	codeClass class = iota
+	stringClass
+	commentClass
+)
+
+// classify returns whether each byte of src belongs to code, a string
+// literal or a comment.
+func classify(src string) []class {
+	cls := make([]class, len(src))
+	for i := 0; i < len(src); {
+		switch src[i] {
+		case '#':
+			for ; i < len(src) && src[i] != '\n'; i++ {
+				cls[i] = commentClass
+			}
+		case '"', '\'':
+			end := source.SkipString(src, i)
+			for ; i < end; i++ {
+				cls[i] = stringClass
+			}
+		default:
+			i++
+		}
+	}
+	return cls
+}
+
+// lineStarts returns the byte offsets of the lines of src that do not begin
+// inside a multi-line string.
+func lineStarts(src string, cls []class) []int {
+	starts := []int{0}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' && cls[i] != stringClass && i+1 < len(src) {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// SortImports sorts the first run of import statements. Without grouping,
+// the blank-line separated sections of the run are sorted independently.
+func SortImports(src string, group bool) string {
+	lines := strings.Split(src, "\n")
+	begin := -1
+	for i, line := range lines {
+		if strings.HasPrefix(line, "import ") {
+			begin = i
+			break
+		}
+	}
+	if begin < 0 {
+		return src
+	}
+	end := begin
+	for end < len(lines) && (strings.HasPrefix(lines[end], "import ") || strings.TrimSpace(lines[end]) == "") {
+		end++
+	}
+	for end > begin && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	var sections [][]string
+	if group {
+		sections = make([][]string, 3)
+		for _, line := range lines[begin:end] {
+			if line == "" {
+				continue
+			}
+			path := importPath(line)
+			switch {
+			case strings.HasPrefix(path, "."):
+				sections[2] = append(sections[2], line)
+			case systemModules[path]:
+				sections[0] = append(sections[0], line)
+			default:
+				sections[1] = append(sections[1], line)
+			}
+		}
+	} else {
+		section := []string{}
+		for _, line := range lines[begin:end] {
+			if strings.TrimSpace(line) == "" {
+				sections = append(sections, section)
+				section = []string{}
+				continue
+			}
+			section = append(section, line)
+		}
+		sections = append(sections, section)
+	}
+	var block []string
+	for _, section := range sections {
+		if len(section) == 0 {
+			continue
+		}
+		sort.SliceStable(section, func(i, j int) bool {
+			return importPath(section[i]) < importPath(section[j])
+		})
+		if len(block) > 0 {
+			block = append(block, "")
+		}
+		block = append(block, section...)
+	}
+	out := append(append(append([]string{}, lines[:begin]...), block...), lines[end:]...)
+	return strings.Join(out, "\n")
+}
+
+func importPath(line string) string {
+	fields := strings.Fields(strings.TrimPrefix(line, "import "))
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// reindent converts the 4-space indentation of the formatter to width.
+func reindent(src string, width int) string {
+	cls := classify(src)
+	var b strings.Builder
+	last := 0
+	for _, start := range lineStarts(src, cls) {
+		n := 0
+		for start+n < len(src) && src[start+n] == ' ' {
+			n++
+		}
+		b.WriteString(src[last:start])
+		b.WriteString(strings.Repeat(" ", n/DefaultIndentWidth*width+n%DefaultIndentWidth))
+		last = start + n
+	}
+	b.WriteString(src[last:])
+	return b.String()
+}
+
+// wrap splits the lines longer than max that contain a single-line list or
+// dict into one element per line.
+func wrap(src string, max, width int) string {
+	src, newline := strings.CutSuffix(src, "\n")
+	cls := classify(src)
+	var out []string
+	starts := lineStarts(src, cls)
+	for i, start := range starts {
+		end := len(src)
+		if i+1 < len(starts) {
+			end = starts[i+1] - 1
+		}
+		out = append(out, wrapLine(src[start:end], max, width)...)
+	}
+	if newline {
+		out = append(out, "")
+	}
+	return strings.Join(out, "\n")
+}
+
+// wrapLine wraps a line and the resulting element lines recursively.
+func wrapLine(line string, max, width int) []string {
+	if len(line) <= max || strings.Contains(line, "\n") {
+		return []string{line}
+	}
+	cls := classify(line)
+	open, close := wrapGroup(line, cls)
+	if open < 0 {
+		return []string{line}
+	}
+	indent := len(line) - len(strings.TrimLeft(line, " "))
+	var elements []string
+	for _, e := range source.SplitTop(line[open+1:close], ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			elements = append(elements, e)
+		}
+	}
+	if len(elements) < 2 {
+		return []string{line}
+	}
+	lines := []string{strings.TrimRight(line[:open+1], " ")}
+	pad := strings.Repeat(" ", indent+width)
+	for _, e := range elements {
+		lines = append(lines, wrapLine(pad+e, max, width)...)
+	}
+	return append(lines, strings.Repeat(" ", indent)+line[close:])
+}
+
+// wrapGroup returns the position of the first outermost list or dict of the
+// line that can be wrapped, or -1 when there is none. Lines with comments
+// and comprehensions are never wrapped.
+func wrapGroup(line string, cls []class) (int, int) {
+	var stack []int
+	open, close := -1, -1
+	for i := 0; i < len(line); i++ {
+		if cls[i] == commentClass {
+			return -1, -1
+		}
+		if cls[i] != codeClass {
+			continue
+		}
+		switch line[i] {
+		case '(', '[', '{':
+			stack = append(stack, i)
+		case ')', ']', '}':
+			if len(stack) == 0 {
+				return -1, -1
+			}
+			start := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if line[start] == '(' || hasWord(line[start+1:i], cls[start+1:i], "for") {
+				continue
+			}
+			if open < 0 || start < open {
+				open, close = start, i
+			}
+		}
+	}
+	return open, close
+}
+
+// hasWord reports whether the code of text contains the keyword.
+func hasWord(text string, cls []class, word string) bool {
+	for i := 0; i+len(word) <= len(text); i++ {
+		if cls[i] != codeClass || !strings.HasPrefix(text[i:], word) {
+			continue
+		}
+		if (i == 0 || !isIdentChar(text[i-1])) && (i+len(word) == len(text) || !isIdentChar(text[i+len(word)])) {
+			return true
+		}
+	}
+	return false
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// group is a bracket group tracked by trailingCommas.
+type group struct {
+	open   int
+	skip   bool
+	ends   []int
+	commas []int
+}
+
+// trailingCommas adds or removes the comma after the last element of each
+// line of multi-line lists and dicts. Lambda bodies, comprehensions and
+// config if blocks are left untouched.
+func trailingCommas(src string, always bool) string {
+	cls := classify(src)
+	var stack []*group
+	var inserts, deletes []int
+	lastCode, firstWord, lineLambda := -1, "", false
+	for i := 0; i < len(src); i++ {
+		if cls[i] != codeClass {
+			continue
+		}
+		c := src[i]
+		switch {
+		case c == '\n':
+			if len(stack) > 0 && lastCode >= 0 {
+				top := stack[len(stack)-1]
+				switch src[lastCode] {
+				case '(', '[', '{':
+					// The line opens a group.
+				case ':', '\\':
+					top.skip = true
+				case ',':
+					top.commas = append(top.commas, lastCode)
+				default:
+					top.ends = append(top.ends, lastCode+1)
+				}
+				if firstWord == "if" || firstWord == "elif" || firstWord == "else" {
+					top.skip = true
+				}
+			}
+			lastCode, firstWord, lineLambda = -1, "", false
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			continue
+		case c == '(' || c == '[' || c == '{':
+			stack = append(stack, &group{open: i, skip: c == '(' || (c == '{' && lineLambda)})
+		case c == ')' || c == ']' || c == '}':
+			if len(stack) > 0 {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if !top.skip {
+					if always {
+						inserts = append(inserts, top.ends...)
+					} else {
+						deletes = append(deletes, top.commas...)
+					}
+				}
+			}
+		case isIdentChar(c) && (i == 0 || !isIdentChar(src[i-1])):
+			j := i
+			for j < len(src) && isIdentChar(src[j]) {
+				j++
+			}
+			word := src[i:j]
+			if lastCode < 0 {
+				firstWord = word
+			}
+			if word == "lambda" {
+				lineLambda = true
+			}
+			if word == "for" && len(stack) > 0 {
+				stack[len(stack)-1].skip = true
+			}
+			i = j - 1
+		}
+		lastCode = i
+	}
+	edits := map[int]bool{}
+	for _, p := range inserts {
+		edits[p] = true
+	}
+	for _, p := range deletes {
+		edits[p] = false
+	}
+	var b strings.Builder
+	for i := 0; i <= len(src); i++ {
+		if insert, ok := edits[i]; ok {
+			if insert {
+				b.WriteByte(',')
+			} else {
+				continue
+			}
+		}
+		if i < len(src) {
+			b.WriteByte(src[i])
+		}
+	}
+	return b.String()
+}
+
+// quotes converts the single-line string literals to the quote style when
+// the conversion does not require escaping.
+func quotes(src, style string) string {
+	to := byte('"')
+	if style == Single {
+		to = '\''
+	}
+	var b strings.Builder
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '#':
+			j := strings.IndexByte(src[i:], '\n')
+			if j < 0 {
+				j = len(src) - i
+			}
+			b.WriteString(src[i : i+j])
+			i += j
+		case c == '"' || c == '\'':
+			end := source.SkipString(src, i)
+			raw := i > 0 && (src[i-1] == 'r' || src[i-1] == 'R')
+			b.WriteString(convertQuotes(src[i:end], to, raw))
+			i = end
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+func convertQuotes(lit string, to byte, raw bool) string {
+	from := lit[0]
+	if from == to || len(lit) < 2 || lit[len(lit)-1] != from || strings.HasPrefix(lit, strings.Repeat(string(from), 3)) {
+		return lit
+	}
+	body := lit[1 : len(lit)-1]
+	if raw && strings.ContainsRune(body, '\\') {
+		return lit
+	}
+	var b strings.Builder
+	b.WriteByte(to)
+	for i := 0; i < len(body); i++ {
+		switch {
+		case body[i] == to:
+			return lit
+		case body[i] == '\\' && i+1 < len(body):
+			if body[i+1] != from {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(body[i+1])
+			i++
+		default:
+			b.WriteByte(body[i])
+		}
+	}
+	b.WriteByte(to)
+	return b.String()
+}
@@ -0,0 +1,132 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package formatter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		input  string
+		want   string
+	}{
+		{
+			name:   "default",
+			config: *DefaultConfig(),
+			input:  "a = 'x'\n",
+			want:   "a = 'x'\n",
+		},
+		{
+			name:   "indent width",
+			config: Config{IndentWidth: 2},
+			input:  "a = {\n    b = {\n        c = \"\"\"\n    kept\n\"\"\"\n    }\n}\n",
+			want:   "a = {\n  b = {\n    c = \"\"\"\n    kept\n\"\"\"\n  }\n}\n",
+		},
+		{
+			name:   "sort imports",
+			config: Config{IndentWidth: 4, SortImports: true},
+			input:  "import yaml\nimport app\n\nimport .b\nimport .a\n\na = 1\n",
+			want:   "import app\nimport yaml\n\nimport .a\nimport .b\n\na = 1\n",
+		},
+		{
+			name:   "group imports",
+			config: Config{IndentWidth: 4, GroupImports: true},
+			input:  "import .local\nimport k8s.api.apps.v1 as apps\nimport yaml\nimport json\n\na = 1\n",
+			want:   "import json\nimport yaml\n\nimport k8s.api.apps.v1 as apps\n\nimport .local\n\na = 1\n",
+		},
+		{
+			name:   "wrap",
+			config: Config{IndentWidth: 4, MaxLineLength: 30},
+			input:  "config = {name = \"app\", labels = {app = \"nginx\"}, ports = [80, 443]}\n",
+			want:   "config = {\n    name = \"app\"\n    labels = {app = \"nginx\"}\n    ports = [80, 443]\n}\n",
+		},
+		{
+			name:   "wrap skips comprehensions",
+			config: Config{IndentWidth: 4, MaxLineLength: 10},
+			input:  "a = [x for x, y in items]\n",
+			want:   "a = [x for x, y in items]\n",
+		},
+		{
+			name:   "trailing commas always",
+			config: Config{IndentWidth: 4, TrailingComma: Always},
+			input:  "a = [\n    1\n    {\n        b = 2  # two\n    }\n]\nf = lambda {\n    1\n}\nc = [\n    x\n    for x in a\n]\n",
+			want:   "a = [\n    1,\n    {\n        b = 2,  # two\n    },\n]\nf = lambda {\n    1\n}\nc = [\n    x\n    for x in a\n]\n",
+		},
+		{
+			name:   "trailing commas never",
+			config: Config{IndentWidth: 4, TrailingComma: Never},
+			input:  "a = [\n    1, 2,\n    3,\n]\nb = f(1,\n    2)\n",
+			want:   "a = [\n    1, 2\n    3\n]\nb = f(1,\n    2)\n",
+		},
+		{
+			name:   "single quotes",
+			config: Config{IndentWidth: 4, QuoteStyle: Single},
+			input:  "a = \"x\"  # \"c\"\nb = \"it's\"\nc = \"\"\"doc\"\"\"\n",
+			want:   "a = 'x'  # \"c\"\nb = \"it's\"\nc = \"\"\"doc\"\"\"\n",
+		},
+		{
+			name:   "double quotes",
+			config: Config{IndentWidth: 4, QuoteStyle: Double},
+			input:  "a = 'it\\'s'\nb = '\\n'\n",
+			want:   "a = \"it's\"\nb = \"\\n\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.config
+			if c.TrailingComma == "" {
+				c.TrailingComma = Preserve
+			}
+			if c.QuoteStyle == "" {
+				c.QuoteStyle = Preserve
+			}
+			if got := string(Apply([]byte(tt.input), &c)); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolver(t *testing.T) {
+	root := t.TempDir()
+	write := func(path, content string) {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("kcl.mod", "[package]\nname = \"app\"\n\n[fmt]\nindent_width = 2\nquote_style = \"single\"\n")
+	write("sub/kcl.mod", "[package]\nname = \"sub\"\n")
+	write("other/.kclfmt.yaml", "max_line_length: 80\n")
+	write("bad/.kclfmt.yaml", "trailing_comma: sometimes\n")
+
+	r := NewResolver()
+	c, err := r.Resolve(filepath.Join(root, "sub", "main.k"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.IndentWidth != 2 || c.QuoteStyle != Single {
+		t.Errorf("unexpected config from kcl.mod: %+v", c)
+	}
+	c, err = r.Resolve(filepath.Join(root, "other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MaxLineLength != 80 || c.IndentWidth != DefaultIndentWidth {
+		t.Errorf("unexpected config from .kclfmt.yaml: %+v", c)
+	}
+	if _, err := r.Resolve(filepath.Join(root, "bad", "main.k")); err == nil {
+		t.Error("expected an invalid config error")
+	}
+	if c, err := NewResolver().Resolve(t.TempDir()); err != nil || !c.IsDefault() {
+		t.Errorf("expected the default config, got %+v, %v", c, err)
+	}
+}
@@ -0,0 +1,322 @@
+package fs
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Fetcher fetches URL inputs, with credentials, custom TLS settings and a
+// local cache of the fetched documents by URL, credentials and ETag.
+type Fetcher struct {
+	// Headers are the request headers, such as `X-Api-Key: secret`.
+	Headers http.Header
+	// Token is the bearer token of the requests, if any.
+	Token string
+	// InsecureSkipTLSVerify skips the verification of server certificates.
+	InsecureSkipTLSVerify bool
+	// CAFile is the PEM file of the certificate authorities trusted in
+	// addition to the system ones, if any.
+	CAFile string
+	// CacheDir is the directory caching the fetched documents, only readable
+	// by the user. No documents are cached when empty.
+	CacheDir string
+	// Offline fetches the documents from the cache only.
+	Offline bool
+
+	client *http.Client
+	// trusted are the hosts the headers and token are sent to: the hosts of
+	// the inputs, and not those of the documents they reference.
+	trusted map[string]bool
+}
+
+// Trust sends the headers and token of the fetcher to the host of the URL.
+func (f *Fetcher) Trust(rawURL string) {
+	if u, err := url.Parse(rawURL); err == nil {
+		if f.trusted == nil {
+			f.trusted = map[string]bool{}
+		}
+		f.trusted[u.Host] = true
+	}
+}
+
+func (f *Fetcher) httpClient() (*http.Client, error) {
+	if f.client != nil {
+		return f.client, nil
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: f.InsecureSkipTLSVerify}
+	if f.CAFile != "" {
+		pem, err := os.ReadFile(f.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in the CA file %s", f.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	f.client = &http.Client{Transport: transport}
+	return f.client, nil
+}
+
+// cachePath returns the path of the cached document of a URL, whose ETag is
+// cached in the same path with an `.etag` extension. The documents fetched
+// with credentials are cached by URL and credentials, so that they are only
+// returned to the same identity.
+func (f *Fetcher) cachePath(rawURL string) string {
+	key := rawURL
+	if id := f.identity(rawURL); id != "" {
+		key += "\x00" + id
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(f.CacheDir, hex.EncodeToString(sum[:]))
+}
+
+// identity returns the hash of the headers and token sent to the host of a
+// URL, or an empty string when none are sent.
+func (f *Fetcher) identity(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || !f.trusted[u.Host] || (f.Token == "" && len(f.Headers) == 0) {
+		return ""
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "token: %s\n", f.Token)
+	names := make([]string, 0, len(f.Headers))
+	for name := range f.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range f.Headers[name] {
+			fmt.Fprintf(h, "%s: %s\n", http.CanonicalHeaderKey(name), v)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Fetch returns the document of a URL. A cached document is revalidated with
+// its ETag, and is returned as is in offline mode.
+func (f *Fetcher) Fetch(rawURL string) ([]byte, error) {
+	var cached []byte
+	etag := ""
+	if f.CacheDir != "" {
+		if data, err := os.ReadFile(f.cachePath(rawURL)); err == nil {
+			cached = data
+			if tag, err := os.ReadFile(f.cachePath(rawURL) + ".etag"); err == nil {
+				etag = string(tag)
+			}
+		}
+	}
+	if f.Offline {
+		if cached == nil {
+			return nil, fmt.Errorf("failed to fetch URL %q: not cached in offline mode", rawURL)
+		}
+		return cached, nil
+	}
+	client, err := f.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	if f.trusted[req.URL.Host] {
+		for name, values := range f.Headers {
+			for _, v := range values {
+				req.Header.Add(name, v)
+			}
+		}
+		if f.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+f.Token)
+		}
+	}
+	if cached != nil && etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch URL %q: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return cached, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch URL %q: HTTP %d %s", rawURL, resp.StatusCode, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save content from URL %q: %w", rawURL, err)
+	}
+	if f.CacheDir != "" {
+		if err := f.store(rawURL, data, resp.Header.Get("ETag")); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func (f *Fetcher) store(rawURL string, data []byte, etag string) error {
+	if err := os.MkdirAll(f.CacheDir, 0700); err != nil {
+		return fmt.Errorf("failed to create the cache directory: %w", err)
+	}
+	// Restrict the cache directories created by earlier versions.
+	if err := os.Chmod(f.CacheDir, 0700); err != nil {
+		return fmt.Errorf("failed to restrict the cache directory: %w", err)
+	}
+	p := f.cachePath(rawURL)
+	if err := writePrivate(p, data); err != nil {
+		return err
+	}
+	if etag == "" {
+		os.Remove(p + ".etag")
+		return nil
+	}
+	return writePrivate(p+".etag", []byte(etag))
+}
+
+// writePrivate writes a file only readable by the user, restricting it when
+// it already exists.
+func writePrivate(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0600)
+}
+
+// TempFile fetches the document of a URL to a temp file named with the
+// extension of the URL path. The caller is responsible for removing the temp
+// file after use.
+func (f *Fetcher) TempFile(rawURL string) (string, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	ext := path.Ext(parsedURL.Path)
+	if ext == "" {
+		ext = ".tmp"
+	}
+	data, err := f.Fetch(rawURL)
+	if err != nil {
+		return "", err
+	}
+	tempFile, err := os.CreateTemp("", "kcl-import-*"+ext)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer tempFile.Close()
+	if _, err := tempFile.Write(data); err != nil {
+		os.Remove(tempFile.Name())
+		return "", fmt.Errorf("failed to save content from URL %q: %w", rawURL, err)
+	}
+	return tempFile.Name(), nil
+}
+
+// Bundle fetches the JSON or YAML document of a URL and the documents its
+// `$ref`s reference, transitively, to the directory. The references to other
+// documents are rewritten to their local files, so that the bundle can be
+// read offline. It returns the path of the root document.
+func (f *Fetcher) Bundle(rawURL, dir string) (string, error) {
+	root, _, _ := strings.Cut(rawURL, "#")
+	files := map[string]string{}
+	var queue []string
+	local := func(docURL string) string {
+		if name, ok := files[docURL]; ok {
+			return name
+		}
+		u, _ := url.Parse(docURL)
+		ext := path.Ext(u.Path)
+		if ext == "" {
+			ext = ".json"
+		}
+		name := fmt.Sprintf("%d-%s", len(files), strings.TrimSuffix(path.Base(u.Path), ext))
+		// Rewritten documents are written as JSON, which is valid YAML.
+		files[docURL] = name + ".json"
+		queue = append(queue, docURL)
+		return files[docURL]
+	}
+	local(root)
+	for len(queue) > 0 {
+		docURL := queue[0]
+		queue = queue[1:]
+		data, err := f.Fetch(docURL)
+		if err != nil {
+			return "", err
+		}
+		base, err := url.Parse(docURL)
+		if err != nil {
+			return "", err
+		}
+		var doc any
+		if err := yaml.UnmarshalWithOptions(data, &doc, yaml.UseOrderedMap()); err != nil {
+			return "", fmt.Errorf("failed to parse the document of URL %q: %w", docURL, err)
+		}
+		var refErr error
+		doc = rewriteRefs(doc, func(ref string) string {
+			target, err := base.Parse(ref)
+			if err != nil {
+				refErr = fmt.Errorf("invalid $ref %q in %s: %w", ref, docURL, err)
+				return ref
+			}
+			fragment := target.EscapedFragment()
+			target.Fragment, target.RawFragment = "", ""
+			if (target.Scheme != "http" && target.Scheme != "https") || target.String() == docURL {
+				return ref
+			}
+			name := local(target.String())
+			if fragment != "" {
+				return name + "#" + fragment
+			}
+			return name
+		})
+		if refErr != nil {
+			return "", refErr
+		}
+		out, err := yaml.MarshalWithOptions(doc, yaml.JSON())
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(filepath.Join(dir, files[docURL]), out, 0644); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(dir, files[root]), nil
+}
+
+// rewriteRefs returns the value with the `$ref` strings rewritten.
+func rewriteRefs(v any, rewrite func(string) string) any {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		for i, item := range v {
+			if s, ok := item.Value.(string); ok && fmt.Sprint(item.Key) == "$ref" {
+				v[i].Value = rewrite(s)
+			} else {
+				v[i].Value = rewriteRefs(item.Value, rewrite)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = rewriteRefs(item, rewrite)
+		}
+	}
+	return v
+}
@@ -0,0 +1,145 @@
+package fs
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFetcherCache(t *testing.T) {
+	requests, notModified := 0, 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("X-Team") != "platform" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"type": "object"}`))
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	f := &Fetcher{
+		Headers:               http.Header{"X-Team": {"platform"}},
+		Token:                 "secret",
+		InsecureSkipTLSVerify: true,
+		CacheDir:              cacheDir,
+	}
+	if _, err := f.Fetch(server.URL + "/spec.json"); err == nil {
+		t.Fatal("Fetch() sent the credentials to an untrusted host")
+	}
+	f.Trust(server.URL)
+	for i := 0; i < 2; i++ {
+		data, err := f.Fetch(server.URL + "/spec.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != `{"type": "object"}` {
+			t.Errorf("Fetch() = %s", data)
+		}
+	}
+	if requests != 3 || notModified != 1 {
+		t.Errorf("Fetch() sent %d requests with %d not modified, want 3 with 1", requests, notModified)
+	}
+
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if info, err := e.Info(); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("cached file %s mode = %v, %v, want 0600", e.Name(), info.Mode(), err)
+		}
+	}
+	if info, err := os.Stat(cacheDir); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("cache directory mode = %v, %v, want 0700", info.Mode(), err)
+	}
+
+	// The documents fetched with credentials are only returned with them.
+	offline := &Fetcher{Headers: f.Headers, Token: f.Token, CacheDir: cacheDir, Offline: true}
+	offline.Trust(server.URL)
+	if data, err := offline.Fetch(server.URL + "/spec.json"); err != nil || string(data) != `{"type": "object"}` {
+		t.Errorf("Fetch() offline = %s, %v", data, err)
+	}
+	for _, other := range []*Fetcher{
+		{CacheDir: cacheDir, Offline: true},
+		{Headers: f.Headers, Token: "other", CacheDir: cacheDir, Offline: true},
+	} {
+		other.Trust(server.URL)
+		if _, err := other.Fetch(server.URL + "/spec.json"); err == nil {
+			t.Errorf("Fetch() offline returned the document cached for another identity")
+		}
+	}
+	if _, err := offline.Fetch(server.URL + "/other.json"); err == nil || !strings.Contains(err.Error(), "offline") {
+		t.Errorf("Fetch() offline of an uncached URL error = %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("Fetch() offline sent requests")
+	}
+}
+
+func TestFetcherCAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+	if _, err := (&Fetcher{}).Fetch(server.URL); err == nil {
+		t.Fatal("Fetch() trusted an unknown certificate authority")
+	}
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&Fetcher{CAFile: caFile}).Fetch(server.URL); err != nil {
+		t.Errorf("Fetch() with the CA file: %v", err)
+	}
+}
+
+func TestFetcherBundle(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	mux.HandleFunc("/root.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"properties": {"a": {"$ref": "defs/common.yaml#/definitions/A"}, "b": {"$ref": "#/definitions/B"}, "c": {"$ref": "` + server.URL + `/c.json"}}, "definitions": {"B": {"type": "string"}}}`))
+	})
+	mux.HandleFunc("/defs/common.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("definitions:\n  A:\n    $ref: ../c.json\n"))
+	})
+	mux.HandleFunc("/c.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type": "integer"}`))
+	})
+	dir := t.TempDir()
+	root, err := (&Fetcher{}).Bundle(server.URL+"/root.json", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root != filepath.Join(dir, "0-root.json") {
+		t.Errorf("Bundle() = %s", root)
+	}
+	for name, want := range map[string][]string{
+		"0-root.json":   {`"$ref": "1-common.json#/definitions/A"`, `"$ref": "#/definitions/B"`, `"$ref": "2-c.json"`},
+		"1-common.json": {`"$ref": "2-c.json"`},
+		"2-c.json":      {`"type": "integer"`},
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, w := range want {
+			if !strings.Contains(string(data), w) {
+				t.Errorf("%s = %s, want %s", name, data, w)
+			}
+		}
+	}
+}
@@ -3,7 +3,9 @@ package fs
 import (
 	"fmt"
 	"os"
+	pathpkg "path"
 	"path/filepath"
+	"strings"
 )
 
 // ExpandInputFiles returns all the filenames that match the input filename,
@@ -39,3 +41,37 @@ func ExpandIfFilePattern(pattern string, recursive bool) ([]string, error) {
 	}
 	return []string{pattern}, nil
 }
+
+// MatchAnyPattern reports whether the path matches one of the glob
+// patterns. A pattern matches when it matches the whole slash-separated
+// path, its base name, or one of its parent directories, so that patterns
+// such as `vendor` or `gen/*` exclude whole trees.
+func MatchAnyPattern(path string, patterns []string) (bool, error) {
+	path = filepath.ToSlash(filepath.Clean(path))
+	for _, pattern := range patterns {
+		pattern = filepath.ToSlash(filepath.Clean(pattern))
+		for p := path; ; {
+			ok, err := matchPath(pattern, p)
+			if err != nil {
+				return false, fmt.Errorf("pattern %q is not valid: %v", pattern, err)
+			}
+			if ok {
+				return true, nil
+			}
+			i := strings.LastIndex(p, "/")
+			if i < 0 {
+				break
+			}
+			p = p[:i]
+		}
+	}
+	return false, nil
+}
+
+// matchPath matches the pattern against the path and its base name.
+func matchPath(pattern, path string) (bool, error) {
+	if ok, err := filepath.Match(pattern, path); ok || err != nil {
+		return ok, err
+	}
+	return filepath.Match(pattern, pathpkg.Base(path))
+}
@@ -0,0 +1,30 @@
+package fs
+
+import "testing"
+
+func TestMatchAnyPattern(t *testing.T) {
+	tests := []struct {
+		path     string
+		patterns []string
+		want     bool
+	}{
+		{path: "main.k", patterns: []string{"*.k"}, want: true},
+		{path: "a/b/main_test.k", patterns: []string{"*_test.k"}, want: true},
+		{path: "vendor/k8s/api.k", patterns: []string{"vendor"}, want: true},
+		{path: "./gen/models/app.k", patterns: []string{"gen/*"}, want: true},
+		{path: "src/gen.k", patterns: []string{"gen"}, want: false},
+		{path: "main.k", patterns: nil, want: false},
+	}
+	for _, tt := range tests {
+		got, err := MatchAnyPattern(tt.path, tt.patterns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("MatchAnyPattern(%q, %v) = %v, want %v", tt.path, tt.patterns, got, tt.want)
+		}
+	}
+	if _, err := MatchAnyPattern("main.k", []string{"["}); err == nil {
+		t.Error("expected an invalid pattern error")
+	}
+}
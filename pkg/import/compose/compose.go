@@ -0,0 +1,385 @@
+// Copyright The KCL Authors. All rights reserved.
+
+// Package compose converts Docker Compose files to KCL instances of typed
+// compose schemas, normalizing the short syntaxes of the environment, ports
+// and healthchecks.
+package compose
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"kcl-lang.io/cli/pkg/import/schema"
+)
+
+// Schemas returns the compose schemas the generated files instantiate. The
+// schemas declare the normalized attributes and accept the others as they
+// are.
+func Schemas() []*schema.Schema {
+	return []*schema.Schema{
+		{
+			Name:           "Compose",
+			Doc:            "Compose is a Docker Compose application.",
+			IndexSignature: "[...str]: any",
+			Attributes: []*schema.Attribute{
+				{Name: "name", Type: "str", Optional: true},
+				{Name: "services", Type: "{str:Service}", Doc: "The services of the application, by name."},
+				{Name: "networks", Type: "{str:Network}", Optional: true},
+				{Name: "volumes", Type: "{str:Volume}", Optional: true},
+				{Name: "secrets", Type: "{str:Secret}", Optional: true},
+			},
+		},
+		{
+			Name:           "Service",
+			Doc:            "Service is a container of the application.",
+			IndexSignature: "[...str]: any",
+			Attributes: []*schema.Attribute{
+				{Name: "image", Type: "str", Optional: true},
+				{Name: "build", Type: "str | {str:any}", Optional: true},
+				{Name: "container_name", Type: "str", Optional: true},
+				{Name: "command", Type: "str | [str]", Optional: true},
+				{Name: "entrypoint", Type: "str | [str]", Optional: true},
+				{Name: "environment", Type: "{str:str}", Optional: true, Doc: "The environment variables by name. A None value is taken from the shell."},
+				{Name: "env_file", Type: "str | [str]", Optional: true},
+				{Name: "ports", Type: "[Port]", Optional: true},
+				{Name: "expose", Type: "[int | str]", Optional: true},
+				{Name: "volumes", Type: "[str | {str:any}]", Optional: true},
+				{Name: "networks", Type: "[str] | {str:any}", Optional: true},
+				{Name: "depends_on", Type: "{str:Dependency}", Optional: true, Doc: "The services the service depends on, by name."},
+				{Name: "healthcheck", Type: "Healthcheck", Optional: true},
+				{Name: "restart", Type: "str", Optional: true},
+				{Name: "labels", Type: "{str:str}", Optional: true},
+				{Name: "secrets", Type: "[str | {str:any}]", Optional: true},
+				{Name: "working_dir", Type: "str", Optional: true},
+				{Name: "user", Type: "str", Optional: true},
+				{Name: "profiles", Type: "[str]", Optional: true},
+				{Name: "deploy", Type: "{str:any}", Optional: true},
+			},
+		},
+		{
+			Name: "Port",
+			Doc:  "Port is a port of a service published on the host.",
+			Attributes: []*schema.Attribute{
+				{Name: "target", Type: "int | str", Doc: "The container port or port range."},
+				{Name: "published", Type: "int | str", Optional: true, Doc: "The host port or port range."},
+				{Name: "host_ip", Type: "str", Optional: true},
+				{Name: "protocol", Type: `"tcp" | "udp"`, Optional: true},
+				{Name: "mode", Type: `"host" | "ingress"`, Optional: true},
+				{Name: "name", Type: "str", Optional: true},
+				{Name: "app_protocol", Type: "str", Optional: true},
+			},
+		},
+		{
+			Name: "Healthcheck",
+			Doc:  "Healthcheck is the check of the health of a service.",
+			Attributes: []*schema.Attribute{
+				{Name: "test", Type: "[str]", Optional: true, Doc: "The command, starting with NONE, CMD or CMD-SHELL."},
+				{Name: "interval", Type: "str", Optional: true},
+				{Name: "timeout", Type: "str", Optional: true},
+				{Name: "retries", Type: "int", Optional: true},
+				{Name: "start_period", Type: "str", Optional: true},
+				{Name: "start_interval", Type: "str", Optional: true},
+				{Name: "disable", Type: "bool", Optional: true},
+			},
+		},
+		{
+			Name: "Dependency",
+			Doc:  "Dependency is the dependency of a service on another.",
+			Attributes: []*schema.Attribute{
+				{Name: "condition", Type: `"service_started" | "service_healthy" | "service_completed_successfully"`, Default: `"service_started"`},
+				{Name: "restart", Type: "bool", Optional: true},
+				{Name: "required", Type: "bool", Optional: true},
+			},
+		},
+		{
+			Name:           "Network",
+			Doc:            "Network is a network of the services.",
+			IndexSignature: "[...str]: any",
+			Attributes: []*schema.Attribute{
+				{Name: "name", Type: "str", Optional: true},
+				{Name: "driver", Type: "str", Optional: true},
+				{Name: "driver_opts", Type: "{str:str}", Optional: true},
+				{Name: "external", Type: "bool", Optional: true},
+				{Name: "internal", Type: "bool", Optional: true},
+				{Name: "attachable", Type: "bool", Optional: true},
+				{Name: "labels", Type: "{str:str}", Optional: true},
+			},
+		},
+		{
+			Name:           "Volume",
+			Doc:            "Volume is a named volume of the services.",
+			IndexSignature: "[...str]: any",
+			Attributes: []*schema.Attribute{
+				{Name: "name", Type: "str", Optional: true},
+				{Name: "driver", Type: "str", Optional: true},
+				{Name: "driver_opts", Type: "{str:str}", Optional: true},
+				{Name: "external", Type: "bool", Optional: true},
+				{Name: "labels", Type: "{str:str}", Optional: true},
+			},
+		},
+		{
+			Name:           "Secret",
+			Doc:            "Secret is a secret granted to the services.",
+			IndexSignature: "[...str]: any",
+			Attributes: []*schema.Attribute{
+				{Name: "name", Type: "str", Optional: true},
+				{Name: "file", Type: "str", Optional: true},
+				{Name: "environment", Type: "str", Optional: true},
+				{Name: "external", Type: "bool", Optional: true},
+			},
+		},
+	}
+}
+
+// Generate returns the KCL file instantiating the compose schemas from a
+// Docker Compose file, which outputs the normalized compose file. When
+// package is not empty, the schemas are those of this compose schema package
+// instead of generated ones.
+func Generate(data []byte, pkg string) (*schema.File, error) {
+	var doc yaml.MapSlice
+	if err := yaml.UnmarshalWithOptions(data, &doc, yaml.UseOrderedMap()); err != nil {
+		return nil, err
+	}
+	if _, ok := schema.Get(doc, "services").(yaml.MapSlice); !ok {
+		return nil, fmt.Errorf("invalid compose file without services")
+	}
+	c := &converter{}
+	f := &schema.File{Imports: []string{"manifests"}}
+	if pkg == "" {
+		f.Schemas = Schemas()
+	} else {
+		parts := strings.Split(pkg, ".")
+		c.prefix = parts[len(parts)-1] + "."
+		f.Imports = append(f.Imports, pkg)
+	}
+	value, err := c.compose(doc)
+	if err != nil {
+		return nil, err
+	}
+	f.Body = fmt.Sprintf("_compose = %s\n\nmanifests.yaml_stream([_compose])\n", schema.Config(value, ""))
+	return f, nil
+}
+
+type converter struct {
+	// prefix qualifies the names of the schemas of a compose package.
+	prefix string
+}
+
+func (c *converter) instance(name string, value yaml.MapSlice) schema.Instance {
+	return schema.Instance{Schema: c.prefix + name, Value: value}
+}
+
+func (c *converter) compose(doc yaml.MapSlice) (schema.Instance, error) {
+	var out yaml.MapSlice
+	for _, item := range doc {
+		key := fmt.Sprint(item.Key)
+		switch key {
+		case "services":
+			services, err := c.named(item.Value, key, c.service)
+			if err != nil {
+				return schema.Instance{}, err
+			}
+			item.Value = services
+		case "networks", "volumes", "secrets":
+			name := map[string]string{"networks": "Network", "volumes": "Volume", "secrets": "Secret"}[key]
+			values, err := c.named(item.Value, key, func(_ string, v yaml.MapSlice) (any, error) {
+				return c.instance(name, v), nil
+			})
+			if err != nil {
+				return schema.Instance{}, err
+			}
+			item.Value = values
+		}
+		out = append(out, item)
+	}
+	return c.instance("Compose", out), nil
+}
+
+// named converts the values of a top-level section by name. Null values are
+// empty objects.
+func (c *converter) named(v any, section string, convert func(string, yaml.MapSlice) (any, error)) (yaml.MapSlice, error) {
+	m, ok := v.(yaml.MapSlice)
+	if !ok && v != nil {
+		return nil, fmt.Errorf("invalid %s: expected an object", section)
+	}
+	var out yaml.MapSlice
+	for _, item := range m {
+		name := fmt.Sprint(item.Key)
+		value, ok := item.Value.(yaml.MapSlice)
+		if !ok && item.Value != nil {
+			return nil, fmt.Errorf("invalid %s %s: expected an object", section, name)
+		}
+		converted, err := convert(name, value)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, yaml.MapItem{Key: name, Value: converted})
+	}
+	return out, nil
+}
+
+func (c *converter) service(name string, service yaml.MapSlice) (any, error) {
+	var out yaml.MapSlice
+	for _, item := range service {
+		var err error
+		switch fmt.Sprint(item.Key) {
+		case "environment", "labels":
+			item.Value, err = keyValues(item.Value)
+		case "ports":
+			item.Value, err = c.ports(item.Value)
+		case "healthcheck":
+			item.Value, err = c.healthcheck(item.Value)
+		case "depends_on":
+			item.Value, err = c.dependencies(item.Value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid service %s %v: %w", name, item.Key, err)
+		}
+		out = append(out, item)
+	}
+	return c.instance("Service", out), nil
+}
+
+// keyValues returns the dict of a list of `KEY=VALUE` strings or of a map.
+// The values of keys without value are None.
+func keyValues(v any) (yaml.MapSlice, error) {
+	var out yaml.MapSlice
+	switch v := v.(type) {
+	case []any:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected KEY=VALUE strings")
+			}
+			if key, value, ok := strings.Cut(s, "="); ok {
+				out = append(out, yaml.MapItem{Key: key, Value: value})
+			} else {
+				out = append(out, yaml.MapItem{Key: s, Value: nil})
+			}
+		}
+	case yaml.MapSlice:
+		for _, item := range v {
+			value := item.Value
+			switch value.(type) {
+			case nil, string:
+			default:
+				value = fmt.Sprint(value)
+			}
+			out = append(out, yaml.MapItem{Key: fmt.Sprint(item.Key), Value: value})
+		}
+	default:
+		return nil, fmt.Errorf("expected a list or an object")
+	}
+	return out, nil
+}
+
+func (c *converter) ports(v any) ([]any, error) {
+	list, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a list")
+	}
+	out := make([]any, len(list))
+	for i, item := range list {
+		switch item := item.(type) {
+		case yaml.MapSlice:
+			out[i] = c.instance("Port", item)
+		case string, int, uint64, int64:
+			p, err := ParsePort(fmt.Sprint(item))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = c.instance("Port", p)
+		default:
+			return nil, fmt.Errorf("invalid port %v", item)
+		}
+	}
+	return out, nil
+}
+
+// ParsePort returns the long syntax of a port in the short syntax
+// `[[host_ip:]published:]target[/protocol]`.
+func ParsePort(s string) (yaml.MapSlice, error) {
+	spec, protocol, _ := strings.Cut(s, "/")
+	hostIP := ""
+	// IPv6 host IPs are enclosed in brackets.
+	if strings.HasPrefix(spec, "[") {
+		end := strings.Index(spec, "]:")
+		if end < 0 {
+			return nil, fmt.Errorf("invalid port %q", s)
+		}
+		hostIP, spec = spec[1:end], spec[end+2:]
+	}
+	parts := strings.Split(spec, ":")
+	var published, target string
+	switch {
+	case len(parts) == 1:
+		target = parts[0]
+	case len(parts) == 2:
+		published, target = parts[0], parts[1]
+	case hostIP == "":
+		hostIP = strings.Join(parts[:len(parts)-2], ":")
+		published, target = parts[len(parts)-2], parts[len(parts)-1]
+	default:
+		return nil, fmt.Errorf("invalid port %q", s)
+	}
+	if target == "" {
+		return nil, fmt.Errorf("invalid port %q without target", s)
+	}
+	p := yaml.MapSlice{{Key: "target", Value: portNumber(target)}}
+	if published != "" {
+		p = append(p, yaml.MapItem{Key: "published", Value: portNumber(published)})
+	}
+	if hostIP != "" {
+		p = append(p, yaml.MapItem{Key: "host_ip", Value: hostIP})
+	}
+	if protocol != "" {
+		p = append(p, yaml.MapItem{Key: "protocol", Value: protocol})
+	}
+	return p, nil
+}
+
+// portNumber returns the number of a port, or the port range as a string.
+func portNumber(s string) any {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	return s
+}
+
+func (c *converter) healthcheck(v any) (any, error) {
+	m, ok := v.(yaml.MapSlice)
+	if !ok {
+		return nil, fmt.Errorf("expected an object")
+	}
+	var out yaml.MapSlice
+	for _, item := range m {
+		if fmt.Sprint(item.Key) == "test" {
+			// A string test is run by the shell.
+			if s, ok := item.Value.(string); ok {
+				item.Value = []any{"CMD-SHELL", s}
+			}
+		}
+		out = append(out, item)
+	}
+	return c.instance("Healthcheck", out), nil
+}
+
+func (c *converter) dependencies(v any) (yaml.MapSlice, error) {
+	var out yaml.MapSlice
+	switch v := v.(type) {
+	case []any:
+		for _, name := range v {
+			out = append(out, yaml.MapItem{Key: fmt.Sprint(name), Value: c.instance("Dependency", nil)})
+		}
+	case yaml.MapSlice:
+		for _, item := range v {
+			m, _ := item.Value.(yaml.MapSlice)
+			out = append(out, yaml.MapItem{Key: fmt.Sprint(item.Key), Value: c.instance("Dependency", m)})
+		}
+	default:
+		return nil, fmt.Errorf("expected a list or an object")
+	}
+	return out, nil
+}
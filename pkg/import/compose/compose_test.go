@@ -0,0 +1,71 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+)
+
+func TestParsePort(t *testing.T) {
+	tests := []struct {
+		port string
+		want yaml.MapSlice
+	}{
+		{"80", yaml.MapSlice{{Key: "target", Value: 80}}},
+		{"8080:80", yaml.MapSlice{{Key: "target", Value: 80}, {Key: "published", Value: 8080}}},
+		{"127.0.0.1:8443:443/tcp", yaml.MapSlice{{Key: "target", Value: 443}, {Key: "published", Value: 8443}, {Key: "host_ip", Value: "127.0.0.1"}, {Key: "protocol", Value: "tcp"}}},
+		{"[::1]:53:53/udp", yaml.MapSlice{{Key: "target", Value: 53}, {Key: "published", Value: 53}, {Key: "host_ip", Value: "::1"}, {Key: "protocol", Value: "udp"}}},
+		{"127.0.0.1::80", yaml.MapSlice{{Key: "target", Value: 80}, {Key: "host_ip", Value: "127.0.0.1"}}},
+		{"3000-3005", yaml.MapSlice{{Key: "target", Value: "3000-3005"}}},
+	}
+	for _, tt := range tests {
+		got, err := ParsePort(tt.port)
+		if err != nil {
+			t.Errorf("ParsePort(%q) error: %v", tt.port, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParsePort(%q) = %v, want %v", tt.port, got, tt.want)
+		}
+	}
+	if _, err := ParsePort("8080:"); err == nil {
+		t.Errorf("ParsePort() of a port without target returned no error")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "docker-compose.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := Generate(data, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join("testdata", "main.k.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(f.Bytes()); got != string(want) {
+		t.Errorf("Generate() =\n%s\nwant:\n%s", got, want)
+	}
+
+	f, err = Generate(data, "compose.v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(f.Bytes())
+	if len(f.Schemas) != 0 || !strings.Contains(got, "import compose.v2\n") || !strings.Contains(got, "_compose = v2.Compose {\n") {
+		t.Errorf("Generate() with a compose package =\n%s", got)
+	}
+
+	if _, err := Generate([]byte("version: '3'\n"), ""); err == nil {
+		t.Errorf("Generate() of a file without services returned no error")
+	}
+}
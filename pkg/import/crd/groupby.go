@@ -2,38 +2,125 @@
 package crd
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
+	"github.com/BurntSushi/toml"
+	"github.com/goccy/go-yaml"
+	"k8s.io/apimachinery/pkg/version"
 	"kcl-lang.io/cli/pkg/fs"
-	"kcl-lang.io/kpm/pkg/opt"
-	pkg "kcl-lang.io/kpm/pkg/package"
 )
 
-// knownAPIVersions is a slice of strings that contains the known API versions.
-var knownAPIVersions = []string{
-	"v1", "v2", "v3", "v4", "v5", "v6", "v7", "v8", "v9", "v10",
-	"v1alpha1", "v1alpha2", "v1alpha3", "v1alpha4", "v1alpha5",
-	"v2alpha1", "v2alpha2", "v2alpha3", "v2alpha4", "v2alpha5",
-	"v3alpha1", "v3alpha2", "v3alpha3", "v3alpha4", "v3alpha5",
-	"v1beta1", "v1beta2", "v1beta3", "v1beta4", "v1beta5",
-	"v2beta1", "v2beta2", "v2beta3", "v2beta4", "v2beta5",
-	"v3beta1", "v3beta2", "v3beta3", "v3beta4", "v3beta5",
+const unknownVersion = "unknown"
+
+// CRD is a custom resource definition read from a source file.
+type CRD struct {
+	// Name is the name of the definition, such as
+	// `certificates.cert-manager.io`.
+	Name  string
+	Group string
+	Kind  string
+	// Versions are the names of the versions, the latest first.
+	Versions []string
+	// Digest is the sha256 digest of the definition, encoded as JSON with
+	// sorted keys so that it does not depend on the formatting of the source.
+	Digest string
 }
 
-const unknownVersion = "unknown"
+type definition struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Group string `yaml:"group"`
+		Names struct {
+			Kind string `yaml:"kind"`
+		} `yaml:"names"`
+		// Version is the version of apiextensions.k8s.io/v1beta1 definitions.
+		Version  string `yaml:"version"`
+		Versions []struct {
+			Name string `yaml:"name"`
+		} `yaml:"versions"`
+	} `yaml:"spec"`
+}
 
-// moveKclFiles moves files with the ".k" extension to a directory based on their API version.
-// It walks through the specified base directory and for each file with the ".k" extension,
-// it determines the API version based on the file name and moves the file to a subdirectory
-// named after the API version. If the API version cannot be determined, the file is moved
-// to a subdirectory named "unknown".
-//
-// Parameters:
-// - baseDir: The base directory to search for files.
-func GroupByKclFiles(target string, packageName string) error {
+// Parse returns the custom resource definitions of YAML or JSON data, which
+// may contain several documents. Other kinds are skipped.
+func Parse(data []byte) ([]*CRD, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var crds []*CRD
+	for {
+		var doc any
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			return crds, nil
+		} else if err != nil {
+			return nil, err
+		}
+		canonical, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		var def definition
+		if err := yaml.Unmarshal(canonical, &def); err != nil {
+			return nil, err
+		}
+		if def.Kind != "CustomResourceDefinition" {
+			continue
+		}
+		if def.Spec.Group == "" || def.Spec.Names.Kind == "" {
+			return nil, fmt.Errorf("invalid custom resource definition %s without group or kind", def.Metadata.Name)
+		}
+		sum := sha256.Sum256(canonical)
+		crd := &CRD{
+			Name:   def.Metadata.Name,
+			Group:  def.Spec.Group,
+			Kind:   def.Spec.Names.Kind,
+			Digest: "sha256:" + hex.EncodeToString(sum[:]),
+		}
+		for _, v := range def.Spec.Versions {
+			crd.Versions = append(crd.Versions, v.Name)
+		}
+		if len(crd.Versions) == 0 && def.Spec.Version != "" {
+			crd.Versions = []string{def.Spec.Version}
+		}
+		sort.SliceStable(crd.Versions, func(i, j int) bool {
+			return version.CompareKubeAwareVersionStrings(crd.Versions[i], crd.Versions[j]) > 0
+		})
+		crds = append(crds, crd)
+	}
+}
+
+// Package returns the package directory name of an API group, such as
+// `cert_manager_io` for `cert-manager.io`.
+func Package(group string) string {
+	return strings.Trim(nonAlnumRe.ReplaceAllString(strings.ToLower(group), "_"), "_")
+}
+
+var nonAlnumRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// isKubeVersion reports whether v is a Kubernetes API version such as `v1`,
+// `v11` or `v1beta6`, which sort after any other string.
+func isKubeVersion(v string) bool {
+	return v != "" && version.CompareKubeAwareVersionStrings(v, "") > 0
+}
+
+// GroupByKclFiles moves the KCL files generated from the custom resource
+// definitions in the package directory to `<group>/<version>/<Kind>.k`, so
+// that the definitions of a group version share a package. Files generated
+// from other definitions are grouped by the API version in their name, or
+// else moved to the `unknown` directory.
+func GroupByKclFiles(target string, packageName string, crds []*CRD) error {
 	baseDir := filepath.Join(target, packageName)
 	entries, err := os.ReadDir(baseDir)
 	if err != nil {
@@ -43,32 +130,108 @@ func GroupByKclFiles(target string, packageName string) error {
 		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".k") {
 			continue
 		}
+		newPath := filepath.Join(baseDir, destination(entry.Name(), crds))
+		if err := os.MkdirAll(filepath.Dir(newPath), os.ModePerm); err != nil {
+			return err
+		}
+		if err := os.Rename(filepath.Join(baseDir, entry.Name()), newPath); err != nil {
+			return err
+		}
+	}
+	return removeEmptyDirs(baseDir)
+}
 
-		apiVersion, err := extractAPIVersionFromName(entry.Name(), knownAPIVersions)
-		if err != nil {
-			apiVersion = unknownVersion
+// destination returns the path relative to the package directory of a file
+// generated from the definitions, such as `cert_manager_io/v1/Certificate.k`
+// for `cert_manager_io_v1_certificate.k`.
+func destination(name string, crds []*CRD) string {
+	normalized := normalize(strings.TrimSuffix(name, ".k"))
+	var match *CRD
+	matchVersion, matchPrefix := "", ""
+	for _, crd := range crds {
+		for _, v := range crd.Versions {
+			prefix := normalize(crd.Group + v + crd.Kind)
+			// The longest prefix tells CertificateRequest from Certificate.
+			if strings.HasPrefix(normalized, prefix) && len(prefix) > len(matchPrefix) {
+				match, matchVersion, matchPrefix = crd, v, prefix
+			}
 		}
+	}
+	if match != nil {
+		// Keep the suffix of the files of nested schemas.
+		suffix := strings.TrimSuffix(name, ".k")[prefixLen(name, matchPrefix):]
+		return filepath.Join(Package(match.Group), matchVersion, match.Kind+pascal(suffix)+".k")
+	}
+	for _, part := range strings.Split(strings.TrimSuffix(name, ".k"), "_") {
+		if isKubeVersion(part) {
+			return filepath.Join(part, name)
+		}
+	}
+	return filepath.Join(unknownVersion, name)
+}
 
-		newDir := filepath.Join(baseDir, apiVersion)
-		os.MkdirAll(newDir, os.ModePerm)
+// prefixLen returns the length of the prefix of a file name whose
+// normalized form is the normalized prefix.
+func prefixLen(name, normalizedPrefix string) int {
+	n := 0
+	for i := 0; i < len(name); i++ {
+		if n == len(normalizedPrefix) {
+			return i
+		}
+		if c := name[i]; c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			n++
+		}
+	}
+	return len(name)
+}
 
-		oldPath := filepath.Join(baseDir, entry.Name())
-		newPath := filepath.Join(newDir, entry.Name())
+func normalize(s string) string {
+	return nonAlnumRe.ReplaceAllString(strings.ToLower(s), "")
+}
 
-		err = os.Rename(oldPath, newPath)
-		if err != nil {
-			return err
+func pascal(s string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(s, "_") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
 		}
 	}
-	kclPkg := pkg.NewKclPkg(&opt.InitOptions{
-		Name:     filepath.Base(baseDir),
-		InitPath: baseDir,
-	})
-	err = kclPkg.ModFile.StoreModFile()
-	if err != nil {
+	return b.String()
+}
+
+// LockFile is the file of the package of the models recording the digests
+// of the definitions they are generated from.
+const LockFile = ".crd.lock"
+
+type lockFile struct {
+	// CRDs are the digests of the definitions by `<group>/<kind>/<version>`.
+	CRDs map[string]string `toml:"crds"`
+}
+
+// RecordDigests records the digests of the definitions in the lock file of
+// the package directory, by group, kind and version, keeping the digests of
+// the other definitions.
+func RecordDigests(dir string, crds []*CRD) error {
+	path := filepath.Join(dir, LockFile)
+	var lock lockFile
+	if _, err := toml.DecodeFile(path, &lock); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if lock.CRDs == nil {
+		lock.CRDs = map[string]string{}
+	}
+	for _, crd := range crds {
+		for _, v := range crd.Versions {
+			lock.CRDs[crd.Group+"/"+crd.Kind+"/"+v] = crd.Digest
+		}
+	}
+	var buf bytes.Buffer
+	enc := toml.NewEncoder(&buf)
+	enc.Indent = ""
+	if err := enc.Encode(lock); err != nil {
 		return err
 	}
-	return removeEmptyDirs(baseDir)
+	return os.WriteFile(path, buf.Bytes(), 0644)
 }
 
 // removeEmptyDirs removes all empty directories within the specified directory.
@@ -115,32 +278,3 @@ func removeEmptyDirs(dir string) error {
 	}
 	return nil
 }
-
-// extractAPIVersionFromName extracts the API version from the given name string.
-// It searches for a pattern in the name string and returns the extracted API version.
-// If the API version is found and is known (present in the knownAPIVersions slice),
-// it returns the API version string. Otherwise, it returns "unknown".
-//
-// Parameters:
-// - name: The name string to extract the API version from.
-// - knownAPIVersions: A slice of known API versions.
-//
-// Returns:
-// - string: The extracted API version or "unknown" if not found.
-// - error: An error if the extraction fails.
-//
-// Example usage:
-// version, err := extractAPIVersionFromName("example_v1alpha1", knownAPIVersions)
-func extractAPIVersionFromName(name string, knownAPIVersions []string) (string, error) {
-	re := regexp.MustCompile(`_v([0-9a-zA-Z]+)`)
-	matches := re.FindStringSubmatch(name)
-	if len(matches) > 1 {
-		apiVersion := "v" + matches[1]
-		for _, v := range knownAPIVersions {
-			if apiVersion == v {
-				return apiVersion, nil
-			}
-		}
-	}
-	return unknownVersion, nil
-}
@@ -0,0 +1,135 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package crd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const crds = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: certificates.cert-manager.io
+spec:
+  group: cert-manager.io
+  names:
+    kind: Certificate
+  versions:
+    - name: v1beta6
+    - name: v11
+    - name: v1alpha2
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: certificaterequests.cert-manager.io
+spec:
+  group: cert-manager.io
+  names:
+    kind: CertificateRequest
+  versions:
+    - name: v1
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: skipped
+`
+
+func TestParse(t *testing.T) {
+	got, err := Parse([]byte(crds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Parse() returned %d definitions, want 2", len(got))
+	}
+	if want := []string{"v11", "v1beta6", "v1alpha2"}; !reflect.DeepEqual(got[0].Versions, want) {
+		t.Errorf("Versions = %v, want %v", got[0].Versions, want)
+	}
+	if got[0].Group != "cert-manager.io" || got[0].Kind != "Certificate" || !strings.HasPrefix(got[0].Digest, "sha256:") {
+		t.Errorf("Parse() = %+v", got[0])
+	}
+}
+
+func TestGroupByKclFiles(t *testing.T) {
+	defs, err := Parse([]byte(crds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := t.TempDir()
+	baseDir := filepath.Join(target, "models")
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{
+		"cert_manager_io_v1beta6_certificate.k",
+		"cert_manager_io_v11_certificate.k",
+		"cert_manager_io_v11_certificate_spec.k",
+		"cert_manager_io_v1_certificate_request.k",
+		"example_com_v2beta7_widget.k",
+		"other.k",
+	} {
+		if err := os.WriteFile(filepath.Join(baseDir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := GroupByKclFiles(target, "models", defs); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"cert_manager_io/v1beta6/Certificate.k",
+		"cert_manager_io/v11/Certificate.k",
+		"cert_manager_io/v11/CertificateSpec.k",
+		"cert_manager_io/v1/CertificateRequest.k",
+		"v2beta7/example_com_v2beta7_widget.k",
+		"unknown/other.k",
+	} {
+		if _, err := os.Stat(filepath.Join(baseDir, want)); err != nil {
+			t.Errorf("GroupByKclFiles() did not create %s", want)
+		}
+	}
+}
+
+func TestParseDigests(t *testing.T) {
+	got, err := Parse([]byte(crds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].Digest == got[1].Digest {
+		t.Errorf("Parse() returned the same digest %s for two definitions", got[0].Digest)
+	}
+	// The digest does not depend on the formatting of the definition.
+	reformatted, err := Parse([]byte(`{"kind": "CustomResourceDefinition", "apiVersion": "apiextensions.k8s.io/v1",
+"spec": {"versions": [{"name": "v1"}], "names": {"kind": "CertificateRequest"}, "group": "cert-manager.io"},
+"metadata": {"name": "certificaterequests.cert-manager.io"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reformatted[0].Digest != got[1].Digest {
+		t.Errorf("Parse() digest = %s, want %s", reformatted[0].Digest, got[1].Digest)
+	}
+}
+
+func TestRecordDigests(t *testing.T) {
+	dir := t.TempDir()
+	lock := "[crds]\n\"example.com/A/v1\" = \"sha256:old\"\n\"example.com/B/v1\" = \"sha256:b\"\n"
+	if err := os.WriteFile(filepath.Join(dir, LockFile), []byte(lock), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordDigests(dir, []*CRD{{Group: "example.com", Kind: "A", Versions: []string{"v2", "v1"}, Digest: "sha256:new"}}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, LockFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[crds]\n\"example.com/A/v1\" = \"sha256:new\"\n\"example.com/A/v2\" = \"sha256:new\"\n\"example.com/B/v1\" = \"sha256:b\"\n"
+	if string(got) != want {
+		t.Errorf("RecordDigests() wrote:\n%s\nwant:\n%s", got, want)
+	}
+}
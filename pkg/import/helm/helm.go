@@ -0,0 +1,149 @@
+// Copyright The KCL Authors. All rights reserved.
+
+// Package helm converts the values of Helm charts to KCL schemas.
+package helm
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"kcl-lang.io/cli/pkg/import/schema"
+)
+
+// The files of a chart read by the import.
+const (
+	ChartFile        = "Chart.yaml"
+	ValuesFile       = "values.yaml"
+	ValuesSchemaFile = "values.schema.json"
+)
+
+// ValuesSchema is the name of the schema of the chart values.
+const ValuesSchema = "Values"
+
+// Chart is a Helm chart.
+type Chart struct {
+	Name        string `yaml:"name"`
+	Version     string `yaml:"version"`
+	AppVersion  string `yaml:"appVersion"`
+	Description string `yaml:"description"`
+	// Values is the content of values.yaml, if any.
+	Values []byte `yaml:"-"`
+	// Schema is the content of values.schema.json, if any.
+	Schema []byte `yaml:"-"`
+}
+
+// Load reads a chart from a chart directory or a packaged `.tgz` chart.
+func Load(chartPath string) (*Chart, error) {
+	files := map[string][]byte{}
+	info, err := os.Stat(chartPath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		for _, name := range []string{ChartFile, ValuesFile, ValuesSchemaFile} {
+			data, err := os.ReadFile(filepath.Join(chartPath, name))
+			if err == nil {
+				files[name] = data
+			} else if !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+	} else if files, err = readArchive(chartPath); err != nil {
+		return nil, fmt.Errorf("failed to read the chart archive %s: %w", chartPath, err)
+	}
+	data, ok := files[ChartFile]
+	if !ok {
+		return nil, fmt.Errorf("%s not found in the chart %s", ChartFile, chartPath)
+	}
+	c := &Chart{}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ChartFile, err)
+	}
+	if c.Name == "" {
+		return nil, fmt.Errorf("the chart name is missing in %s", ChartFile)
+	}
+	c.Values = files[ValuesFile]
+	c.Schema = files[ValuesSchemaFile]
+	return c, nil
+}
+
+// readArchive returns the files of the chart at the root of a gzipped tar
+// archive, by name. The files of the sub charts are ignored.
+func readArchive(file string) (map[string][]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		// Chart archives contain a single directory named after the chart.
+		parts := strings.SplitN(path.Clean(h.Name), "/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		switch parts[1] {
+		case ChartFile, ValuesFile, ValuesSchemaFile:
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			files[parts[1]] = data
+		}
+	}
+}
+
+// Schemas returns the KCL file declaring the Values schema of the chart,
+// typed from values.schema.json when present and inferred from values.yaml
+// otherwise, with the defaults and comments of values.yaml.
+func (c *Chart) Schemas() (*schema.File, error) {
+	var values yaml.MapSlice
+	if len(c.Values) > 0 {
+		if err := yaml.UnmarshalWithOptions(c.Values, &values, yaml.UseOrderedMap()); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", ValuesFile, err)
+		}
+	}
+	comments, err := schema.Comments(c.Values)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ValuesFile, err)
+	}
+	b := schema.NewBuilder(comments)
+	if len(c.Schema) > 0 {
+		var root yaml.MapSlice
+		if err := yaml.UnmarshalWithOptions(c.Schema, &root, yaml.UseOrderedMap()); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", ValuesSchemaFile, err)
+		}
+		b.FromJSONSchema(ValuesSchema, root, values)
+	} else {
+		b.Infer(ValuesSchema, values)
+	}
+	if root := b.Schemas[0]; root.Doc == "" {
+		root.Doc = fmt.Sprintf("Values of the %s chart.", c.Name)
+		if c.Description != "" {
+			root.Doc += "\n\n" + c.Description
+		}
+	}
+	return b.File(fmt.Sprintf("This file was generated by kcl import from the Helm chart %s %s. DO NOT EDIT.", c.Name, c.Version)), nil
+}
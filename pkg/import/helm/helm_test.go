@@ -0,0 +1,131 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package helm
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// packageChart writes the chart directory to a `.tgz` archive like
+// `helm package` does.
+func packageChart(t *testing.T, dir string) string {
+	t.Helper()
+	archive := filepath.Join(t.TempDir(), filepath.Base(dir)+"-0.1.0.tgz")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		h := &tar.Header{Name: filepath.Base(dir) + "/" + e.Name(), Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archive
+}
+
+func TestLoad(t *testing.T) {
+	dir := filepath.Join("testdata", "nginx")
+	for name, path := range map[string]string{
+		"dir": dir,
+		"tgz": packageChart(t, dir),
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Name != "nginx" || c.Version != "0.1.0" || c.AppVersion != "1.16.0" {
+				t.Errorf("Load() chart = %s %s %s", c.Name, c.Version, c.AppVersion)
+			}
+			if len(c.Values) == 0 || len(c.Schema) == 0 {
+				t.Errorf("Load() values or schema missing")
+			}
+		})
+	}
+	if _, err := Load(t.TempDir()); err == nil || !strings.Contains(err.Error(), "Chart.yaml not found") {
+		t.Errorf("Load() of an empty directory error = %v", err)
+	}
+}
+
+func TestSchemas(t *testing.T) {
+	c, err := Load(filepath.Join("testdata", "nginx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		schema bool
+		want   []string
+	}{
+		{
+			name:   "typed",
+			schema: true,
+			want: []string{
+				"Helm chart nginx 0.1.0",
+				"schema Values:\n    r\"\"\"\n    Values of the nginx chart.\n\n    A Helm chart for Kubernetes\n",
+				"\n    replicaCount?: int = 1\n",
+				"\n    image: Image = Image {}\n",
+				"\n    pullPolicy?: \"Always\" | \"IfNotPresent\" | \"Never\" = \"IfNotPresent\"\n",
+				"    tag : str, default is \"\", optional\n        Overrides the image tag whose default is the chart appVersion.\n",
+				"\n    $type?: \"ClusterIP\" | \"NodePort\" | \"LoadBalancer\" = \"ClusterIP\"\n",
+				"\n        port >= 1 if port != None\n",
+				"\n    ingress?: Ingress = Ingress {}\n",
+			},
+		},
+		{
+			name: "inferred",
+			want: []string{
+				"\n    replicaCount: int = 1\n",
+				"    replicaCount : int, default is 1, required\n        Number of replicas\n",
+				"\n    pullPolicy: str = \"IfNotPresent\"\n",
+				"\n    hosts: [HostsItem] = [{\"host\": \"chart-example.local\", \"paths\": [{\"path\": \"/\", \"pathType\": \"ImplementationSpecific\"}]}]\n",
+				"schema PathsItem:\n",
+				"\n    tolerations: [any] = []\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chart := *c
+			if !tt.schema {
+				chart.Schema = nil
+			}
+			f, err := chart.Schemas()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := string(f.Bytes())
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("Schemas() missing %q in:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright The KCL Authors. All rights reserved.
+
+// Package k8s converts Kubernetes manifests to KCL instances of the schemas
+// of the k8s module.
+package k8s
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"kcl-lang.io/cli/pkg/import/schema"
+)
+
+// Dependency is the name of the KCL module of the Kubernetes schemas.
+const Dependency = "k8s"
+
+// packages are the packages of the API groups outside of `k8s.api`.
+var packages = map[string]string{
+	"apiextensions.k8s.io":   "k8s.apiextensions_apiserver.pkg.apis.apiextensions",
+	"apiregistration.k8s.io": "k8s.kube_aggregator.pkg.apis.apiregistration",
+}
+
+// Index is the schemas of the k8s module by package, such as the
+// `Deployment` schema of the `k8s.api.apps.v1` package.
+type Index map[string]map[string]bool
+
+var schemaRe = regexp.MustCompile(`(?m)^schema\s+([A-Za-z_][A-Za-z0-9_]*)`)
+
+// LoadIndex returns the index of the schemas of the k8s module in dir, such
+// as the module downloaded for the package.
+func LoadIndex(dir string) (Index, error) {
+	index := Index{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".k" || strings.HasSuffix(path, "_test.k") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		pkg := Dependency
+		if rel != "." {
+			pkg += "." + strings.ReplaceAll(filepath.ToSlash(rel), "/", ".")
+		}
+		for _, m := range schemaRe.FindAllSubmatch(data, -1) {
+			if index[pkg] == nil {
+				index[pkg] = map[string]bool{}
+			}
+			index[pkg][string(m[1])] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the k8s module %s: %w", dir, err)
+	}
+	return index, nil
+}
+
+// Package returns the package of the k8s module declaring the schema of a
+// kind, such as `k8s.api.apps.v1` for `apps/v1` deployments, and false if
+// the module does not declare the kind.
+func (idx Index) Package(apiVersion, kind string) (string, bool) {
+	group, version, ok := strings.Cut(apiVersion, "/")
+	if !ok {
+		group, version = "", apiVersion
+	}
+	pkg, ok := packages[group]
+	if !ok {
+		if group == "" {
+			group = "core"
+		}
+		group, _, _ = strings.Cut(group, ".")
+		pkg = "k8s.api." + group
+	}
+	pkg += "." + version
+	if !idx[pkg][kind] {
+		return "", false
+	}
+	return pkg, true
+}
+
+// Manifest is a Kubernetes resource.
+type Manifest struct {
+	APIVersion string
+	Kind       string
+	Name       string
+	// Value is the resource decoded with ordered maps.
+	Value yaml.MapSlice
+}
+
+// Parse returns the resources of YAML or JSON manifests. Empty documents are
+// skipped, and the items of `List` kinds are returned as resources.
+func Parse(data []byte) ([]*Manifest, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data), yaml.UseOrderedMap())
+	var manifests []*Manifest
+	for {
+		var doc yaml.MapSlice
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			return manifests, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if len(doc) == 0 {
+			continue
+		}
+		kind, _ := schema.Get(doc, "kind").(string)
+		if items, ok := schema.Get(doc, "items").([]any); ok && strings.HasSuffix(kind, "List") {
+			for _, item := range items {
+				if m, ok := item.(yaml.MapSlice); ok {
+					manifests = append(manifests, manifest(m))
+				}
+			}
+			continue
+		}
+		m := manifest(doc)
+		if m.APIVersion == "" || m.Kind == "" {
+			return nil, fmt.Errorf("invalid manifest without apiVersion or kind")
+		}
+		manifests = append(manifests, m)
+	}
+}
+
+func manifest(doc yaml.MapSlice) *Manifest {
+	m := &Manifest{Value: doc}
+	m.APIVersion, _ = schema.Get(doc, "apiVersion").(string)
+	m.Kind, _ = schema.Get(doc, "kind").(string)
+	if meta, ok := schema.Get(doc, "metadata").(yaml.MapSlice); ok {
+		m.Name, _ = schema.Get(meta, "name").(string)
+	}
+	return m
+}
+
+// Generate returns the KCL file instantiating the schema of the k8s module
+// of each resource, or a dict for the kinds missing from the index, grouped
+// by API version and kind. The file outputs the resources as a YAML stream in
+// their original order. It also reports whether the file depends on the k8s
+// module.
+func Generate(manifests []*Manifest, index Index) (*schema.File, bool) {
+	sorted := make([]*Manifest, len(manifests))
+	copy(sorted, manifests)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].APIVersion != sorted[j].APIVersion {
+			return sorted[i].APIVersion < sorted[j].APIVersion
+		}
+		return sorted[i].Kind < sorted[j].Kind
+	})
+	aliases := map[string]string{}
+	used := map[string]bool{}
+	alias := func(pkg string) string {
+		if a, ok := aliases[pkg]; ok {
+			return a
+		}
+		parts := strings.Split(pkg, ".")
+		a := parts[len(parts)-2]
+		if used[a] {
+			a += parts[len(parts)-1]
+		}
+		aliases[pkg], used[a] = a, true
+		return a
+	}
+	names := map[string]bool{}
+	vars := map[*Manifest]string{}
+	var body strings.Builder
+	group := ""
+	for _, m := range sorted {
+		if g := m.APIVersion + " " + m.Kind; g != group {
+			if group != "" {
+				body.WriteString("\n")
+			}
+			fmt.Fprintf(&body, "# %s %s\n", m.APIVersion, m.Kind)
+			group = g
+		}
+		name := variable(m, names)
+		vars[m] = name
+		if pkg, ok := index.Package(m.APIVersion, m.Kind); ok {
+			// The schemas set the API version and kind.
+			var value yaml.MapSlice
+			for _, item := range m.Value {
+				if k := fmt.Sprint(item.Key); k != "apiVersion" && k != "kind" {
+					value = append(value, item)
+				}
+			}
+			fmt.Fprintf(&body, "%s = %s.%s %s\n", name, alias(pkg), m.Kind, schema.Config(value, ""))
+		} else {
+			fmt.Fprintf(&body, "%s = %s\n", name, schema.Config(m.Value, ""))
+		}
+	}
+	// The stream keeps the order of the manifests.
+	stream := make([]string, len(manifests))
+	for i, m := range manifests {
+		stream[i] = vars[m]
+	}
+	fmt.Fprintf(&body, "\nmanifests.yaml_stream([%s])\n", strings.Join(stream, ", "))
+	f := &schema.File{Imports: []string{"manifests"}, Body: body.String()}
+	pkgs := make([]string, 0, len(aliases))
+	for pkg := range aliases {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	for _, pkg := range pkgs {
+		f.Imports = append(f.Imports, pkg+" as "+aliases[pkg])
+	}
+	return f, len(aliases) > 0
+}
+
+var nonIdentRe = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// variable returns the unique variable name of a resource, such as
+// `_nginx_deployment`. The variables are private so that only the YAML stream
+// is output.
+func variable(m *Manifest, names map[string]bool) string {
+	base := strings.ToLower(m.Kind)
+	if m.Name != "" {
+		base = m.Name + "_" + base
+	}
+	base = "_" + strings.Trim(nonIdentRe.ReplaceAllString(strings.ToLower(base), "_"), "_")
+	name := base
+	for i := 2; names[name]; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	names[name] = true
+	return name
+}
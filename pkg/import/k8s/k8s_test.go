@@ -0,0 +1,64 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPackage(t *testing.T) {
+	index, err := LoadIndex(filepath.Join("testdata", "k8s"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		apiVersion, kind string
+		want             string
+		ok               bool
+	}{
+		{"apps/v1", "Deployment", "k8s.api.apps.v1", true},
+		{"v1", "Service", "k8s.api.core.v1", true},
+		{"flowcontrol.apiserver.k8s.io/v1", "FlowSchema", "k8s.api.flowcontrol.v1", true},
+		{"networking.k8s.io/v1", "Ingress", "", false},
+		{"apiextensions.k8s.io/v1", "CustomResourceDefinition", "k8s.apiextensions_apiserver.pkg.apis.apiextensions.v1", true},
+		{"cert-manager.io/v1", "Certificate", "", false},
+		{"apps/v1", "Unknown", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := index.Package(tt.apiVersion, tt.kind)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Package(%q, %q) = %q, %v, want %q, %v", tt.apiVersion, tt.kind, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "manifests.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifests, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifests) != 6 {
+		t.Fatalf("Parse() returned %d manifests, want 6", len(manifests))
+	}
+	index, err := LoadIndex(filepath.Join("testdata", "k8s"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, usesK8s := Generate(manifests, index)
+	if !usesK8s {
+		t.Errorf("Generate() does not depend on the k8s module")
+	}
+	want, err := os.ReadFile(filepath.Join("testdata", "main.k.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(f.Bytes()); got != string(want) {
+		t.Errorf("Generate() =\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,442 @@
+// Copyright The KCL Authors. All rights reserved.
+
+// Package openapi prepares OpenAPI specs for the generation of KCL schemas:
+// it bundles multi-file specs, converts OpenAPI 3.x schemas to the Swagger
+// 2.0 definitions the generator reads, and selects the schemas to generate.
+package openapi
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"kcl-lang.io/cli/pkg/import/schema"
+)
+
+const (
+	definitionsRef = "#/definitions/"
+	componentsRef  = "#/components/schemas/"
+)
+
+// Prepare returns the Swagger 2.0 spec, in JSON, of the OpenAPI 2.0 or 3.x
+// spec file. The schemas the spec references in other files are bundled in
+// its definitions. When include patterns are set, only the definitions
+// matching one of them are kept, and the definitions matching an exclude
+// pattern are removed, along with the definitions only they reference.
+func Prepare(path string, include, exclude []string) ([]byte, error) {
+	doc, err := Bundle(path)
+	if err != nil {
+		return nil, err
+	}
+	if v, _ := schema.Get(doc, "openapi").(string); strings.HasPrefix(v, "3.") {
+		doc = Convert(doc)
+	}
+	if len(include) > 0 || len(exclude) > 0 {
+		if doc, err = Select(doc, include, exclude); err != nil {
+			return nil, err
+		}
+	}
+	return yaml.MarshalWithOptions(doc, yaml.JSON())
+}
+
+// Bundle returns the spec of the file with the schemas its `$ref`s reference
+// in other files added to its schemas, and the references rewritten to them.
+func Bundle(path string) (yaml.MapSlice, error) {
+	b := &bundler{docs: map[string]any{}, names: map[string]string{}, taken: map[string]bool{}}
+	root, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	b.root = root
+	v, err := b.load(root)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := v.(yaml.MapSlice)
+	if !ok {
+		return nil, fmt.Errorf("invalid OpenAPI spec %s: expected an object", path)
+	}
+	b.prefix, b.section = definitionsRef, []string{"definitions"}
+	if version, _ := schema.Get(doc, "openapi").(string); strings.HasPrefix(version, "3.") {
+		b.prefix, b.section = componentsRef, []string{"components", "schemas"}
+	}
+	schemas, _ := get(doc, b.section...).(yaml.MapSlice)
+	for _, item := range schemas {
+		b.taken[fmt.Sprint(item.Key)] = true
+	}
+	if err := b.rewrite(doc, root); err != nil {
+		return nil, err
+	}
+	for len(b.queue) > 0 {
+		def := b.queue[0]
+		b.queue = b.queue[1:]
+		if err := b.rewrite(def.value, def.file); err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, yaml.MapItem{Key: def.name, Value: def.value})
+	}
+	if len(b.names) > 0 {
+		doc = set(doc, schemas, b.section...)
+	}
+	return doc, nil
+}
+
+type bundler struct {
+	root    string
+	prefix  string
+	section []string
+	// docs are the documents by absolute path.
+	docs map[string]any
+	// names are the names of the bundled schemas by file and pointer.
+	names map[string]string
+	taken map[string]bool
+	queue []*bundled
+}
+
+type bundled struct {
+	name  string
+	file  string
+	value any
+}
+
+func (b *bundler) load(path string) (any, error) {
+	if doc, ok := b.docs[path]; ok {
+		return doc, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	if err := yaml.UnmarshalWithOptions(data, &doc, yaml.UseOrderedMap()); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	b.docs[path] = doc
+	return doc, nil
+}
+
+// rewrite rewrites the references of a value of the file to the schemas of
+// the root spec, bundling the schemas of other files.
+func (b *bundler) rewrite(v any, file string) error {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		for i, item := range v {
+			ref, ok := item.Value.(string)
+			if !ok || fmt.Sprint(item.Key) != "$ref" {
+				if err := b.rewrite(item.Value, file); err != nil {
+					return err
+				}
+				continue
+			}
+			rewritten, err := b.ref(ref, file)
+			if err != nil {
+				return err
+			}
+			v[i].Value = rewritten
+		}
+	case []any:
+		for _, item := range v {
+			if err := b.rewrite(item, file); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *bundler) ref(ref, file string) (string, error) {
+	location, fragment, _ := strings.Cut(ref, "#")
+	if u, err := url.Parse(location); err == nil && u.Scheme != "" {
+		// URL references are fetched by the URL inputs.
+		return ref, nil
+	}
+	target := file
+	if location != "" {
+		target = filepath.Join(filepath.Dir(file), filepath.FromSlash(location))
+	}
+	if target == b.root {
+		return "#" + fragment, nil
+	}
+	key := target + "#" + fragment
+	if name, ok := b.names[key]; ok {
+		return b.prefix + name, nil
+	}
+	doc, err := b.load(target)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve $ref %q of %s: %w", ref, file, err)
+	}
+	value, err := resolve(doc, fragment)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve $ref %q of %s: %w", ref, file, err)
+	}
+	name := schema.Name(strings.TrimSuffix(filepath.Base(target), filepath.Ext(target)))
+	if parts := strings.Split(fragment, "/"); len(parts) > 1 {
+		name = unescape(parts[len(parts)-1])
+	}
+	base := name
+	for i := 2; b.taken[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	b.taken[name] = true
+	b.names[key] = name
+	b.queue = append(b.queue, &bundled{name: name, file: target, value: value})
+	return b.prefix + name, nil
+}
+
+// resolve returns the value of the JSON pointer of a document.
+func resolve(doc any, pointer string) (any, error) {
+	v := doc
+	if pointer == "" || pointer == "/" {
+		return v, nil
+	}
+	for _, part := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		m, ok := v.(yaml.MapSlice)
+		if !ok {
+			return nil, fmt.Errorf("invalid pointer %q", pointer)
+		}
+		found := false
+		for _, item := range m {
+			if fmt.Sprint(item.Key) == unescape(part) {
+				v, found = item.Value, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("pointer %q not found", pointer)
+		}
+	}
+	return v, nil
+}
+
+func unescape(part string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+}
+
+func get(m yaml.MapSlice, keys ...string) any {
+	var v any = m
+	for _, key := range keys {
+		m, ok := v.(yaml.MapSlice)
+		if !ok {
+			return nil
+		}
+		v = schema.Get(m, key)
+	}
+	return v
+}
+
+// set returns the map with the value set at the path of keys.
+func set(m yaml.MapSlice, value any, keys ...string) yaml.MapSlice {
+	for i, item := range m {
+		if fmt.Sprint(item.Key) != keys[0] {
+			continue
+		}
+		if len(keys) == 1 {
+			m[i].Value = value
+		} else {
+			inner, _ := item.Value.(yaml.MapSlice)
+			m[i].Value = set(inner, value, keys[1:]...)
+		}
+		return m
+	}
+	if len(keys) == 1 {
+		return append(m, yaml.MapItem{Key: keys[0], Value: value})
+	}
+	return append(m, yaml.MapItem{Key: keys[0], Value: set(nil, value, keys[1:]...)})
+}
+
+// Convert returns the Swagger 2.0 spec of the schemas of an OpenAPI 3.x spec.
+// The 3.1 JSON Schema keywords are converted to their 2.0 equivalents: type
+// lists to `x-nullable` types, `const` to single value enums, and numeric
+// exclusive bounds to boolean ones.
+func Convert(doc yaml.MapSlice) yaml.MapSlice {
+	info, _ := schema.Get(doc, "info").(yaml.MapSlice)
+	if info == nil {
+		info = yaml.MapSlice{{Key: "title", Value: "API"}, {Key: "version", Value: "1.0.0"}}
+	}
+	out := yaml.MapSlice{
+		{Key: "swagger", Value: "2.0"},
+		{Key: "info", Value: info},
+		{Key: "paths", Value: yaml.MapSlice{}},
+	}
+	var definitions yaml.MapSlice
+	schemas, _ := get(doc, "components", "schemas").(yaml.MapSlice)
+	for _, item := range schemas {
+		definitions = append(definitions, yaml.MapItem{Key: item.Key, Value: convertSchema(item.Value)})
+	}
+	return append(out, yaml.MapItem{Key: "definitions", Value: definitions})
+}
+
+func convertSchema(v any) any {
+	s, ok := v.(yaml.MapSlice)
+	if !ok {
+		return v
+	}
+	var out yaml.MapSlice
+	for _, item := range s {
+		key := fmt.Sprint(item.Key)
+		switch key {
+		case "$ref":
+			if ref, ok := item.Value.(string); ok {
+				item.Value = strings.Replace(ref, componentsRef, definitionsRef, 1)
+			}
+		case "type":
+			types, ok := item.Value.([]any)
+			if !ok {
+				break
+			}
+			var nonNull []any
+			for _, t := range types {
+				if t == "null" {
+					out = append(out, yaml.MapItem{Key: "x-nullable", Value: true})
+				} else {
+					nonNull = append(nonNull, t)
+				}
+			}
+			if len(nonNull) != 1 {
+				// Several types are any type in Swagger 2.0.
+				continue
+			}
+			item.Value = nonNull[0]
+		case "nullable":
+			item.Key = "x-nullable"
+		case "const":
+			item = yaml.MapItem{Key: "enum", Value: []any{item.Value}}
+		case "examples":
+			if examples, ok := item.Value.([]any); ok && len(examples) > 0 {
+				item = yaml.MapItem{Key: "example", Value: examples[0]}
+			} else {
+				continue
+			}
+		case "exclusiveMinimum", "exclusiveMaximum":
+			if _, ok := item.Value.(bool); !ok {
+				bound := "minimum"
+				if key == "exclusiveMaximum" {
+					bound = "maximum"
+				}
+				out = append(out, yaml.MapItem{Key: bound, Value: item.Value})
+				item.Value = true
+			}
+		case "properties", "patternProperties", "definitions", "$defs":
+			if m, ok := item.Value.(yaml.MapSlice); ok {
+				var props yaml.MapSlice
+				for _, p := range m {
+					props = append(props, yaml.MapItem{Key: p.Key, Value: convertSchema(p.Value)})
+				}
+				item.Value = props
+			}
+		case "items", "additionalProperties", "not":
+			if list, ok := item.Value.([]any); ok {
+				item.Value = convertSchemas(list)
+			} else {
+				item.Value = convertSchema(item.Value)
+			}
+		case "allOf", "oneOf", "anyOf":
+			if list, ok := item.Value.([]any); ok {
+				item.Value = convertSchemas(list)
+			}
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+func convertSchemas(list []any) []any {
+	out := make([]any, len(list))
+	for i, s := range list {
+		out[i] = convertSchema(s)
+	}
+	return out
+}
+
+// Select returns the Swagger 2.0 spec with the definitions matching one of
+// the include patterns, or all when none, and none of the exclude patterns,
+// along with the definitions they depend on.
+func Select(doc yaml.MapSlice, include, exclude []string) (yaml.MapSlice, error) {
+	includeRes, err := compile(include)
+	if err != nil {
+		return nil, err
+	}
+	excludeRes, err := compile(exclude)
+	if err != nil {
+		return nil, err
+	}
+	definitions, _ := schema.Get(doc, "definitions").(yaml.MapSlice)
+	byName := map[string]any{}
+	var queue []string
+	for _, item := range definitions {
+		name := fmt.Sprint(item.Key)
+		byName[name] = item.Value
+		if (len(includeRes) == 0 || matchAny(includeRes, name)) && !matchAny(excludeRes, name) {
+			queue = append(queue, name)
+		}
+	}
+	if len(queue) == 0 {
+		return nil, fmt.Errorf("no schemas selected by the include and exclude patterns")
+	}
+	selected := map[string]bool{}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if selected[name] {
+			continue
+		}
+		selected[name] = true
+		// The dependencies are kept even when excluded, so that the
+		// generated schemas are complete.
+		queue = append(queue, refs(byName[name])...)
+	}
+	var kept yaml.MapSlice
+	for _, item := range definitions {
+		if selected[fmt.Sprint(item.Key)] {
+			kept = append(kept, item)
+		}
+	}
+	return set(doc, kept, "definitions"), nil
+}
+
+// refs returns the names of the definitions a schema references.
+func refs(v any) []string {
+	var names []string
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		for _, item := range v {
+			if ref, ok := item.Value.(string); ok && fmt.Sprint(item.Key) == "$ref" && strings.HasPrefix(ref, definitionsRef) {
+				names = append(names, unescape(strings.TrimPrefix(ref, definitionsRef)))
+			} else {
+				names = append(names, refs(item.Value)...)
+			}
+		}
+	case []any:
+		for _, item := range v {
+			names = append(names, refs(item)...)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func compile(patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema pattern %q: %w", p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+func matchAny(res []*regexp.Regexp, name string) bool {
+	for _, re := range res {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package openapi
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type spec struct {
+	Swagger     string                    `json:"swagger"`
+	Definitions map[string]map[string]any `json:"definitions"`
+}
+
+func prepare(t *testing.T, include, exclude []string) *spec {
+	t.Helper()
+	data, err := Prepare(filepath.Join("testdata", "openapi.yaml"), include, exclude)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &spec{}
+	if err := json.Unmarshal(data, s); err != nil {
+		t.Fatalf("Prepare() returned invalid JSON: %v\n%s", err, data)
+	}
+	return s
+}
+
+func names(s *spec) []string {
+	var out []string
+	for name := range s.Definitions {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestPrepare(t *testing.T) {
+	s := prepare(t, nil, nil)
+	if s.Swagger != "2.0" {
+		t.Errorf("swagger = %q, want 2.0", s.Swagger)
+	}
+	if got, want := names(s), []string{"Address", "Common", "Error", "Owner", "Pet"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("definitions = %v, want %v", got, want)
+	}
+	props := s.Definitions["Pet"]["properties"].(map[string]any)
+	for name, want := range map[string]map[string]any{
+		"tag":   {"type": "string", "x-nullable": true},
+		"kind":  {"enum": []any{"pet"}},
+		"age":   {"type": "integer", "minimum": float64(0), "exclusiveMinimum": true},
+		"owner": {"$ref": "#/definitions/Owner"},
+	} {
+		if got := props[name]; !reflect.DeepEqual(got, want) {
+			t.Errorf("Pet.%s = %v, want %v", name, got, want)
+		}
+	}
+	address := s.Definitions["Address"]["properties"].(map[string]any)["city"]
+	if want := map[string]any{"$ref": "#/definitions/Common"}; !reflect.DeepEqual(address, want) {
+		t.Errorf("Address.city = %v, want %v", address, want)
+	}
+	if want := map[string]any{"type": "string", "x-nullable": true}; !reflect.DeepEqual(s.Definitions["Common"], want) {
+		t.Errorf("Common = %v, want %v", s.Definitions["Common"], want)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		include, exclude []string
+		want             []string
+	}{
+		{[]string{"^Pet$"}, nil, []string{"Address", "Common", "Owner", "Pet"}},
+		{nil, []string{"^Pet$"}, []string{"Address", "Common", "Error", "Owner"}},
+		{[]string{"^Owner$"}, []string{"Address"}, []string{"Address", "Common", "Owner"}},
+	}
+	for _, tt := range tests {
+		if got := names(prepare(t, tt.include, tt.exclude)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Prepare(%v, %v) definitions = %v, want %v", tt.include, tt.exclude, got, tt.want)
+		}
+	}
+	if _, err := Prepare(filepath.Join("testdata", "openapi.yaml"), []string{"^Missing$"}, nil); err == nil {
+		t.Errorf("Prepare() selecting no schemas returned no error")
+	}
+	if _, err := Prepare(filepath.Join("testdata", "openapi.yaml"), []string{"("}, nil); err == nil {
+		t.Errorf("Prepare() with an invalid pattern returned no error")
+	}
+}
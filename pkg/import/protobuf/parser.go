@@ -0,0 +1,565 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package protobuf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// File is a parsed `.proto` file.
+type File struct {
+	// Path is the path of the file.
+	Path string
+	// Name is the import path of the file relative to its proto path.
+	Name    string
+	Syntax  string
+	Package string
+	Imports []string
+	// Messages and Enums are the top level declarations.
+	Messages []*Message
+	Enums    []*Enum
+}
+
+// Message is a message declaration.
+type Message struct {
+	Name string
+	// FullName is the name qualified with the package and the parent
+	// messages, such as `acme.v1.Config.Server`.
+	FullName string
+	Doc      string
+	Fields   []*Field
+	Oneofs   []*Oneof
+	Messages []*Message
+	Enums    []*Enum
+}
+
+// Field is a message field.
+type Field struct {
+	Name string
+	// Label is `repeated`, `optional`, `required` or empty.
+	Label string
+	// Type is the field type as written, such as `string` or `Server`. The
+	// type of map fields is empty.
+	Type string
+	// MapKey and MapValue are the key and value types of map fields.
+	MapKey   string
+	MapValue string
+	Number   int
+	Doc      string
+	// Default is the proto2 default value, if any.
+	Default string
+	// Oneof is the name of the oneof of the field, if any.
+	Oneof string
+}
+
+// Oneof is a oneof declaration.
+type Oneof struct {
+	Name   string
+	Doc    string
+	Fields []string
+}
+
+// Enum is an enum declaration.
+type Enum struct {
+	Name     string
+	FullName string
+	Doc      string
+	Values   []*EnumValue
+}
+
+// EnumValue is an enum value.
+type EnumValue struct {
+	Name   string
+	Number int
+	Doc    string
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenSymbol
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	line  int
+	value string
+}
+
+type comment struct {
+	text string
+	// line and end are the first and last lines of the comment.
+	line, end int
+	// trailing reports whether the comment follows code on its line.
+	trailing bool
+}
+
+// Parse parses the source of a `.proto` file.
+func Parse(path string, src []byte) (*File, error) {
+	tokens, comments, err := lex(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	p := &parser{tokens: tokens, comments: comments}
+	f := &File{Path: path, Syntax: "proto2"}
+	if err := p.file(f); err != nil {
+		return nil, fmt.Errorf("%s:%d: %w", path, p.peek().line, err)
+	}
+	return f, nil
+}
+
+func lex(src string) ([]token, []comment, error) {
+	var tokens []token
+	var comments []comment
+	line := 1
+	lastCodeLine := 0
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			text := strings.TrimPrefix(src[i:i+end], "//")
+			comments = append(comments, comment{text: text, line: line, end: line, trailing: lastCodeLine == line})
+			i += end
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, nil, fmt.Errorf("%d: unterminated comment", line)
+			}
+			text := src[i+2 : i+2+end]
+			start := line
+			line += strings.Count(text, "\n")
+			comments = append(comments, comment{text: blockComment(text), line: start, end: line, trailing: lastCodeLine == start})
+			i += end + 4
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c {
+				if src[j] == '\\' {
+					j++
+				}
+				if j < len(src) && src[j] == '\n' {
+					return nil, nil, fmt.Errorf("%d: unterminated string", line)
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, nil, fmt.Errorf("%d: unterminated string", line)
+			}
+			raw := src[i : j+1]
+			value, err := strconv.Unquote(`"` + strings.ReplaceAll(raw[1:len(raw)-1], `"`, `\"`) + `"`)
+			if err != nil {
+				value = raw[1 : len(raw)-1]
+			}
+			tokens = append(tokens, token{kind: tokenString, text: raw, value: value, line: line})
+			lastCodeLine = line
+			i = j + 1
+		case isIdentStart(c) || c == '.' && i+1 < len(src) && isIdentStart(src[i+1]):
+			j := i + 1
+			for j < len(src) && (isIdentPart(src[j]) || src[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[i:j], line: line})
+			lastCodeLine = line
+			i = j
+		case unicode.IsDigit(rune(c)) || (c == '-' || c == '+' || c == '.') && i+1 < len(src) && unicode.IsDigit(rune(src[i+1])):
+			j := i + 1
+			for j < len(src) && (isIdentPart(src[j]) || src[j] == '.' || (src[j] == '-' || src[j] == '+') && (src[j-1] == 'e' || src[j-1] == 'E')) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: src[i:j], line: line})
+			lastCodeLine = line
+			i = j
+		default:
+			tokens = append(tokens, token{kind: tokenSymbol, text: string(c), line: line})
+			lastCodeLine = line
+			i++
+		}
+	}
+	tokens = append(tokens, token{kind: tokenEOF, line: line})
+	return tokens, comments, nil
+}
+
+// blockComment returns the text of a block comment without the leading `*`
+// of its lines.
+func blockComment(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		l = strings.TrimSpace(l)
+		l = strings.TrimPrefix(strings.TrimPrefix(l, "*"), " ")
+		lines[i] = l
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9'
+}
+
+type parser struct {
+	tokens   []token
+	pos      int
+	comments []comment
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(text string) bool {
+	if t := p.peek(); t.kind != tokenString && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return fmt.Errorf("expected %q, found %q", text, p.peek().text)
+	}
+	return nil
+}
+
+func (p *parser) ident() (string, error) {
+	t := p.next()
+	if t.kind != tokenIdent {
+		return "", fmt.Errorf("expected an identifier, found %q", t.text)
+	}
+	return t.text, nil
+}
+
+// doc returns the leading comment of the declaration starting at the line:
+// the comments directly above it, or else its trailing comment.
+func (p *parser) doc(line int) string {
+	var lines []string
+	next := line
+	for i := len(p.comments) - 1; i >= 0; i-- {
+		c := p.comments[i]
+		if c.end >= line || c.trailing {
+			continue
+		}
+		if c.end != next-1 {
+			if c.end < next-1 {
+				break
+			}
+			continue
+		}
+		lines = append([]string{c.text}, lines...)
+		next = c.line
+	}
+	if len(lines) == 0 {
+		for _, c := range p.comments {
+			if c.trailing && c.line == line {
+				lines = append(lines, c.text)
+			}
+		}
+	}
+	for i, l := range lines {
+		lines[i] = strings.TrimPrefix(strings.TrimRight(l, " \t"), " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func (p *parser) file(f *File) error {
+	for p.peek().kind != tokenEOF {
+		t := p.peek()
+		switch {
+		case p.accept(";"):
+		case p.accept("syntax"), p.accept("edition"):
+			if err := p.expect("="); err != nil {
+				return err
+			}
+			f.Syntax = p.next().value
+			if err := p.expect(";"); err != nil {
+				return err
+			}
+		case p.accept("package"):
+			name, err := p.ident()
+			if err != nil {
+				return err
+			}
+			f.Package = name
+			if err := p.expect(";"); err != nil {
+				return err
+			}
+		case p.accept("import"):
+			if !p.accept("public") {
+				p.accept("weak")
+			}
+			path := p.next()
+			if path.kind != tokenString {
+				return fmt.Errorf("expected an import path, found %q", path.text)
+			}
+			f.Imports = append(f.Imports, path.value)
+			if err := p.expect(";"); err != nil {
+				return err
+			}
+		case p.accept("option"):
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		case p.accept("message"):
+			m, err := p.message(f.Package, t.line)
+			if err != nil {
+				return err
+			}
+			f.Messages = append(f.Messages, m)
+		case p.accept("enum"):
+			e, err := p.enum(f.Package, t.line)
+			if err != nil {
+				return err
+			}
+			f.Enums = append(f.Enums, e)
+		case p.accept("service"), p.accept("extend"):
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unexpected %q", t.text)
+		}
+	}
+	return nil
+}
+
+func qualify(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+func (p *parser) message(scope string, line int) (*Message, error) {
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	m := &Message{Name: name, FullName: qualify(scope, name), Doc: p.doc(line)}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	if err := p.messageBody(m, nil); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// messageBody parses the declarations of a message until the closing brace,
+// adding the fields to the oneof if not nil.
+func (p *parser) messageBody(m *Message, oneof *Oneof) error {
+	for !p.accept("}") {
+		t := p.peek()
+		switch {
+		case t.kind == tokenEOF:
+			return fmt.Errorf("unexpected end of file in message %s", m.Name)
+		case p.accept(";"):
+		case oneof == nil && p.accept("message"):
+			nested, err := p.message(m.FullName, t.line)
+			if err != nil {
+				return err
+			}
+			m.Messages = append(m.Messages, nested)
+		case oneof == nil && p.accept("enum"):
+			e, err := p.enum(m.FullName, t.line)
+			if err != nil {
+				return err
+			}
+			m.Enums = append(m.Enums, e)
+		case oneof == nil && p.accept("oneof"):
+			name, err := p.ident()
+			if err != nil {
+				return err
+			}
+			o := &Oneof{Name: name, Doc: p.doc(t.line)}
+			if err := p.expect("{"); err != nil {
+				return err
+			}
+			if err := p.messageBody(m, o); err != nil {
+				return err
+			}
+			m.Oneofs = append(m.Oneofs, o)
+		case p.accept("option"), p.accept("reserved"), p.accept("extensions"):
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		case oneof == nil && p.accept("extend"):
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		default:
+			f, err := p.field(t.line)
+			if err != nil {
+				return err
+			}
+			if oneof != nil {
+				f.Oneof = oneof.Name
+				oneof.Fields = append(oneof.Fields, f.Name)
+			}
+			m.Fields = append(m.Fields, f)
+		}
+	}
+	return nil
+}
+
+func (p *parser) field(line int) (*Field, error) {
+	f := &Field{}
+	switch p.peek().text {
+	case "repeated", "optional", "required":
+		if next := p.tokens[p.pos+1]; next.kind == tokenIdent {
+			f.Label = p.next().text
+		}
+	}
+	if p.accept("map") {
+		if err := p.expect("<"); err != nil {
+			return nil, err
+		}
+		key, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		value, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(">"); err != nil {
+			return nil, err
+		}
+		f.MapKey, f.MapValue = key, value
+	} else {
+		typ, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		f.Type = typ
+	}
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	f.Name = name
+	if err := p.expect("="); err != nil {
+		return nil, err
+	}
+	number := p.next()
+	if f.Number, err = strconv.Atoi(number.text); err != nil {
+		return nil, fmt.Errorf("invalid field number %q", number.text)
+	}
+	if p.accept("[") {
+		for !p.accept("]") {
+			t := p.next()
+			switch {
+			case t.kind == tokenEOF:
+				return nil, fmt.Errorf("unexpected end of file in the options of field %s", f.Name)
+			case t.text == "default" && p.accept("="):
+				v := p.next()
+				f.Default = v.text
+				if v.kind == tokenString {
+					f.Default = strconv.Quote(v.value)
+				}
+			}
+		}
+	}
+	f.Doc = p.doc(line)
+	return f, p.expect(";")
+}
+
+func (p *parser) enum(scope string, line int) (*Enum, error) {
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	e := &Enum{Name: name, FullName: qualify(scope, name), Doc: p.doc(line)}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for !p.accept("}") {
+		t := p.peek()
+		switch {
+		case t.kind == tokenEOF:
+			return nil, fmt.Errorf("unexpected end of file in enum %s", e.Name)
+		case p.accept(";"):
+		case p.accept("option"), p.accept("reserved"):
+			if err := p.skipStatement(); err != nil {
+				return nil, err
+			}
+		default:
+			name, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("="); err != nil {
+				return nil, err
+			}
+			number, err := strconv.Atoi(p.next().text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of enum %s", name)
+			}
+			if p.accept("[") {
+				for !p.accept("]") {
+					if p.next().kind == tokenEOF {
+						return nil, fmt.Errorf("unexpected end of file in enum %s", e.Name)
+					}
+				}
+			}
+			e.Values = append(e.Values, &EnumValue{Name: name, Number: number, Doc: p.doc(t.line)})
+			if err := p.expect(";"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return e, nil
+}
+
+// skipStatement skips a statement until its semicolon, or a block with its
+// nested blocks.
+func (p *parser) skipStatement() error {
+	depth := 0
+	for {
+		t := p.next()
+		switch {
+		case t.kind == tokenEOF:
+			return fmt.Errorf("unexpected end of file")
+		case t.kind == tokenString:
+		case t.text == "{":
+			depth++
+		case t.text == "}":
+			depth--
+			if depth == 0 {
+				p.accept(";")
+				return nil
+			}
+		case t.text == ";" && depth == 0:
+			return nil
+		}
+	}
+}
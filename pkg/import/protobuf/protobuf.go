@@ -0,0 +1,327 @@
+// Copyright The KCL Authors. All rights reserved.
+
+// Package protobuf converts the messages and enums of `.proto` files to KCL
+// schemas and type aliases, without protoc.
+package protobuf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kcl-lang.io/cli/pkg/import/schema"
+)
+
+// wellKnownPrefix is the import path prefix of the well-known types, which
+// are not read but mapped to KCL types.
+const wellKnownPrefix = "google/protobuf/"
+
+// wellKnownTypes are the KCL types of the well-known types.
+var wellKnownTypes = map[string]string{
+	"google.protobuf.Any":         "{str:any}",
+	"google.protobuf.Struct":      "{str:any}",
+	"google.protobuf.Empty":       "{str:any}",
+	"google.protobuf.Value":       "any",
+	"google.protobuf.ListValue":   "[any]",
+	"google.protobuf.Timestamp":   "str",
+	"google.protobuf.Duration":    "str",
+	"google.protobuf.FieldMask":   "str",
+	"google.protobuf.DoubleValue": "float",
+	"google.protobuf.FloatValue":  "float",
+	"google.protobuf.Int64Value":  "int",
+	"google.protobuf.UInt64Value": "int",
+	"google.protobuf.Int32Value":  "int",
+	"google.protobuf.UInt32Value": "int",
+	"google.protobuf.BoolValue":   "bool",
+	"google.protobuf.StringValue": "str",
+	"google.protobuf.BytesValue":  "str",
+}
+
+// scalarTypes are the KCL types of the scalar value types.
+var scalarTypes = map[string]string{
+	"double": "float", "float": "float",
+	"int32": "int", "int64": "int", "uint32": "int", "uint64": "int",
+	"sint32": "int", "sint64": "int", "fixed32": "int", "fixed64": "int",
+	"sfixed32": "int", "sfixed64": "int",
+	"bool": "bool", "string": "str", "bytes": "str",
+}
+
+// Load parses the `.proto` files and the files they import. Imports are
+// resolved within the proto paths, then the directory of the importing file.
+// The well-known types are not read.
+func Load(files []string, protoPath []string) ([]*File, error) {
+	var out []*File
+	loaded := map[string]bool{}
+	var load func(path, name string) error
+	load = func(path, name string) error {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		if loaded[abs] {
+			return nil
+		}
+		loaded[abs] = true
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		f, err := Parse(path, src)
+		if err != nil {
+			return err
+		}
+		f.Name = name
+		out = append(out, f)
+		for _, imp := range f.Imports {
+			if strings.HasPrefix(imp, wellKnownPrefix) {
+				continue
+			}
+			resolved := ""
+			for _, dir := range append(append([]string{}, protoPath...), filepath.Dir(path)) {
+				candidate := filepath.Join(dir, filepath.FromSlash(imp))
+				if _, err := os.Stat(candidate); err == nil {
+					resolved = candidate
+					break
+				}
+			}
+			if resolved == "" {
+				return fmt.Errorf("%s: import %q not found in the proto path %s", path, imp, strings.Join(protoPath, string(os.PathListSeparator)))
+			}
+			if err := load(resolved, imp); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, f := range files {
+		if err := load(f, importName(f, protoPath)); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// importName returns the import path of a file relative to the proto path
+// containing it, or its base name.
+func importName(file string, protoPath []string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.Base(file)
+	}
+	for _, dir := range protoPath {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(absDir, abs); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.Base(file)
+}
+
+// Output is a generated KCL file.
+type Output struct {
+	// Name is the file name, such as `config.k` for `acme/v1/config.proto`.
+	Name string
+	File *schema.File
+}
+
+// Generate returns a KCL file per `.proto` file, which all belong to the
+// same KCL package. Messages become schemas, nested messages being named
+// after their parents, and enums become union types of their value names.
+// Repeated fields are lists, maps are dicts, and the fields of a oneof are
+// optional attributes checked to be exclusive. Only the proto2 required
+// fields are required.
+func Generate(files []*File) ([]*Output, error) {
+	g := &generator{types: map[string]string{}, enums: map[string]bool{}}
+	used := map[string]bool{}
+	for _, f := range files {
+		for _, m := range f.Messages {
+			g.declareMessage(f.Package, "", m, used)
+		}
+		for _, e := range f.Enums {
+			g.declareEnum(f.Package, "", e, used)
+		}
+	}
+	var out []*Output
+	names := map[string]bool{}
+	for _, f := range files {
+		kf := &schema.File{Doc: fmt.Sprintf("This file was generated by kcl import from %s. DO NOT EDIT.", f.Name)}
+		for _, e := range f.Enums {
+			kf.Types = append(kf.Types, g.enum(e))
+		}
+		for _, m := range f.Messages {
+			if err := g.message(kf, m); err != nil {
+				return nil, err
+			}
+		}
+		name := strings.TrimSuffix(filepath.Base(f.Name), ".proto") + ".k"
+		if names[name] {
+			name = strings.ReplaceAll(strings.TrimSuffix(f.Name, ".proto"), "/", "_") + ".k"
+		}
+		names[name] = true
+		out = append(out, &Output{Name: name, File: kf})
+	}
+	return out, nil
+}
+
+type generator struct {
+	// types are the KCL names of the messages and enums by full name.
+	types map[string]string
+	enums map[string]bool
+}
+
+// declare returns the KCL name of a declaration: its name prefixed with its
+// parents, or else also with its package.
+func declare(pkg, parent, name string, used map[string]bool) string {
+	kclName := parent + schema.Name(name)
+	if used[kclName] {
+		kclName = schema.Name(strings.ReplaceAll(pkg, ".", "_")) + kclName
+	}
+	for i := 2; used[kclName]; i++ {
+		kclName = fmt.Sprintf("%s%s%d", parent, schema.Name(name), i)
+	}
+	used[kclName] = true
+	return kclName
+}
+
+func (g *generator) declareMessage(pkg, parent string, m *Message, used map[string]bool) {
+	name := declare(pkg, parent, m.Name, used)
+	g.types[m.FullName] = name
+	for _, nested := range m.Messages {
+		g.declareMessage(pkg, name, nested, used)
+	}
+	for _, e := range m.Enums {
+		g.declareEnum(pkg, name, e, used)
+	}
+}
+
+func (g *generator) declareEnum(pkg, parent string, e *Enum, used map[string]bool) {
+	g.types[e.FullName] = declare(pkg, parent, e.Name, used)
+	g.enums[e.FullName] = true
+}
+
+func (g *generator) enum(e *Enum) *schema.Type {
+	values := make([]string, len(e.Values))
+	var docs []string
+	for i, v := range e.Values {
+		values[i] = schema.Literal(v.Name)
+		if v.Doc != "" {
+			docs = append(docs, v.Name+": "+strings.ReplaceAll(v.Doc, "\n", " "))
+		}
+	}
+	doc := e.Doc
+	if len(docs) > 0 {
+		if doc != "" {
+			doc += "\n\n"
+		}
+		doc += strings.Join(docs, "\n")
+	}
+	return &schema.Type{Name: g.types[e.FullName], Type: schema.Union(values...), Doc: doc}
+}
+
+func (g *generator) message(f *schema.File, m *Message) error {
+	s := &schema.Schema{Name: g.types[m.FullName], Doc: m.Doc}
+	for _, field := range m.Fields {
+		a := &schema.Attribute{Name: field.Name, Doc: field.Doc, Optional: field.Label != "required"}
+		if field.MapKey != "" {
+			a.Type = "{str:" + g.typeOf(m.FullName, field.MapValue) + "}"
+		} else {
+			a.Type = g.typeOf(m.FullName, field.Type)
+			if field.Label == "repeated" {
+				a.Type = "[" + a.Type + "]"
+			}
+		}
+		if field.Default != "" {
+			a.Default = defaultValue(field.Default, g.enums[g.resolve(m.FullName, field.Type)])
+		}
+		if field.Oneof != "" {
+			line := fmt.Sprintf("Member of the oneof %s.", field.Oneof)
+			if a.Doc != "" {
+				a.Doc += "\n"
+			}
+			a.Doc += line
+		}
+		s.Attributes = append(s.Attributes, a)
+	}
+	for _, o := range m.Oneofs {
+		if len(o.Fields) < 2 {
+			continue
+		}
+		refs := make([]string, len(o.Fields))
+		for i, name := range o.Fields {
+			refs[i] = schema.SelfRef(name)
+		}
+		s.Checks = append(s.Checks, &schema.Check{
+			Expr:    fmt.Sprintf("len([_v for _v in [%s] if _v != None]) <= 1", strings.Join(refs, ", ")),
+			Message: fmt.Sprintf("at most one of %s can be set", strings.Join(o.Fields, ", ")),
+		})
+	}
+	f.Schemas = append(f.Schemas, s)
+	for _, e := range m.Enums {
+		f.Types = append(f.Types, g.enum(e))
+	}
+	for _, nested := range m.Messages {
+		if err := g.message(f, nested); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// typeOf returns the KCL type of a field type referenced in a scope.
+func (g *generator) typeOf(scope, typ string) string {
+	if t, ok := scalarTypes[typ]; ok {
+		return t
+	}
+	full := g.resolve(scope, typ)
+	if t, ok := wellKnownTypes[full]; ok {
+		return t
+	}
+	if name, ok := g.types[full]; ok {
+		return name
+	}
+	return "any"
+}
+
+// resolve returns the full name of a type referenced in a scope, searching
+// the scope then its parents like protoc does.
+func (g *generator) resolve(scope, typ string) string {
+	if strings.HasPrefix(typ, ".") {
+		return typ[1:]
+	}
+	for {
+		candidate := qualify(scope, typ)
+		if _, ok := g.types[candidate]; ok {
+			return candidate
+		}
+		if _, ok := wellKnownTypes[candidate]; ok {
+			return candidate
+		}
+		if scope == "" {
+			return typ
+		}
+		if i := strings.LastIndex(scope, "."); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+}
+
+// defaultValue returns the KCL literal of a proto2 default value.
+func defaultValue(v string, enum bool) string {
+	switch {
+	case strings.HasPrefix(v, `"`):
+		return v
+	case v == "true":
+		return "True"
+	case v == "false":
+		return "False"
+	case enum:
+		return schema.Literal(v)
+	}
+	return v
+}
@@ -0,0 +1,84 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package protobuf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	src := `syntax = "proto2";
+package demo;
+
+// Item is an item.
+// It has a name.
+message Item {
+  required string name = 1 [default = "x"];
+  optional Kind kind = 2 [default = BIG, deprecated = true];
+  reserved 3 to 5;
+  enum Kind { BIG = 0; SMALL = 1; }
+}
+
+service Items {
+  rpc Get(Item) returns (Item) {}
+}
+`
+	f, err := Parse("demo.proto", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Syntax != "proto2" || f.Package != "demo" || len(f.Messages) != 1 {
+		t.Fatalf("Parse() = %+v", f)
+	}
+	m := f.Messages[0]
+	if m.FullName != "demo.Item" || m.Doc != "Item is an item.\nIt has a name." {
+		t.Errorf("message = %q, doc %q", m.FullName, m.Doc)
+	}
+	if len(m.Fields) != 2 || m.Fields[0].Label != "required" || m.Fields[0].Default != `"x"` || m.Fields[1].Default != "BIG" {
+		t.Errorf("fields = %+v", m.Fields)
+	}
+	if len(m.Enums) != 1 || m.Enums[0].FullName != "demo.Item.Kind" || len(m.Enums[0].Values) != 2 {
+		t.Errorf("enums = %+v", m.Enums)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	protoPath := []string{"testdata"}
+	files, err := Load([]string{filepath.Join("testdata", "acme", "v1", "config.proto")}, protoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Name != "acme/v1/config.proto" || files[1].Name != "acme/v1/common.proto" {
+		t.Fatalf("Load() = %d files", len(files))
+	}
+	outputs, err := Generate(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range outputs {
+		got := string(o.File.Bytes())
+		golden := filepath.Join("testdata", o.Name+".golden")
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != string(want) {
+			t.Errorf("%s:\n%s\nwant:\n%s", o.Name, got, want)
+		}
+	}
+}
+
+func TestLoadMissingImport(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.proto")
+	if err := os.WriteFile(path, []byte(`syntax = "proto3"; import "b.proto";`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Load([]string{path}, nil)
+	if err == nil || !strings.Contains(err.Error(), `import "b.proto" not found`) {
+		t.Errorf("Load() error = %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package schema
+
+import (
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// Comments returns the comments of the keys of a YAML document by dotted key
+// path. The comment of a key is the last block of comment lines above it, or
+// its inline comment. Comments following the helm-docs convention, starting
+// with `# --`, begin at that marker.
+func Comments(data []byte) (map[string]string, error) {
+	f, err := parser.ParseBytes(data, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	comments := map[string]string{}
+	for _, doc := range f.Docs {
+		collectComments(doc.Body, "", comments)
+	}
+	return comments, nil
+}
+
+func collectComments(node ast.Node, path string, comments map[string]string) {
+	switch n := node.(type) {
+	case *ast.MappingNode:
+		for _, v := range n.Values {
+			collectComments(v, path, comments)
+		}
+	case *ast.MappingValueNode:
+		key := join(path, strings.Trim(n.Key.String(), `"'`))
+		text := commentText(n.GetComment())
+		if text == "" {
+			text = commentText(n.Value.GetComment())
+		}
+		if text != "" {
+			comments[key] = text
+		}
+		collectComments(n.Value, key, comments)
+	}
+}
+
+// commentText returns the text of the last block of a comment group.
+func commentText(group *ast.CommentGroupNode) string {
+	if group == nil {
+		return ""
+	}
+	var lines []string
+	last := -1
+	for _, c := range group.Comments {
+		line := c.Token.Position.Line
+		if last >= 0 && line > last+1 {
+			lines = nil
+		}
+		last = line
+		text := strings.TrimPrefix(strings.TrimPrefix(c.Token.Value, "#"), " ")
+		if strings.HasPrefix(text, "-- ") || text == "--" {
+			lines = nil
+			text = strings.TrimSpace(strings.TrimPrefix(text, "--"))
+		}
+		lines = append(lines, strings.TrimRight(text, " \t"))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
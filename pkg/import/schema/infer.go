@@ -0,0 +1,259 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Builder builds the schemas of a generated file.
+type Builder struct {
+	// Schemas are the built schemas, in the order they were created.
+	Schemas []*Schema
+	// Imports are the modules the built checks require.
+	Imports []string
+	// Comments are the docs of the values by dotted key path, such as
+	// `image.tag`.
+	Comments map[string]string
+	// EnumLimit is the maximum number of distinct string values inferred as
+	// a union of literals rather than `str`. Zero disables enum detection.
+	EnumLimit int
+	names     Names
+	refs      map[string]string
+}
+
+// NewBuilder returns a builder of schemas documented by the comments.
+func NewBuilder(comments map[string]string) *Builder {
+	return &Builder{Comments: comments, names: Names{}, refs: map[string]string{}}
+}
+
+// File returns the file of the built schemas.
+func (b *Builder) File(doc string) *File {
+	return &File{Doc: doc, Imports: b.Imports, Schemas: b.Schemas}
+}
+
+func (b *Builder) newSchema(parent, name, doc string) *Schema {
+	s := &Schema{Name: b.names.New(parent, name), Doc: doc}
+	b.Schemas = append(b.Schemas, s)
+	return s
+}
+
+func (b *Builder) require(module string) {
+	for _, m := range b.Imports {
+		if m == module {
+			return
+		}
+	}
+	b.Imports = append(b.Imports, module)
+}
+
+// Infer returns the schema named name typed from the sample values of a
+// YAML or JSON object. Nested objects become schemas, attributes default to
+// the sample values, and attributes with null values are optional.
+func (b *Builder) Infer(name string, value yaml.MapSlice) *Schema {
+	return b.inferObject("", name, "", []yaml.MapSlice{value})
+}
+
+// InferSamples returns the schema named name describing all the samples of
+// an object. Attributes missing or null in some samples are optional, and
+// conflicting types are widened to unions.
+func (b *Builder) InferSamples(name string, samples []yaml.MapSlice) *Schema {
+	return b.inferObject("", name, "", samples)
+}
+
+// Samples returns the objects of the documents of YAML or JSON data, skipping
+// empty documents.
+func Samples(data []byte) ([]yaml.MapSlice, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data), yaml.UseOrderedMap())
+	var samples []yaml.MapSlice
+	for {
+		var doc any
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			return samples, nil
+		} else if err != nil {
+			return nil, err
+		}
+		switch doc := doc.(type) {
+		case nil:
+		case yaml.MapSlice:
+			samples = append(samples, doc)
+		default:
+			return nil, fmt.Errorf("expected an object, got %T", doc)
+		}
+	}
+}
+
+// inferObject returns the schema of the samples of an object. Attributes
+// missing in some samples are optional, and only the attributes of a single
+// sample have defaults.
+func (b *Builder) inferObject(parent, name, path string, samples []yaml.MapSlice) *Schema {
+	s := b.newSchema(parent, name, b.Comments[path])
+	values := map[string][]any{}
+	var keys []string
+	for _, sample := range samples {
+		for _, item := range sample {
+			key := fmt.Sprint(item.Key)
+			if _, ok := values[key]; !ok {
+				keys = append(keys, key)
+			}
+			values[key] = append(values[key], item.Value)
+		}
+	}
+	for _, key := range keys {
+		attrPath := join(path, key)
+		a := &Attribute{
+			Name:     key,
+			Doc:      b.Comments[attrPath],
+			Optional: len(values[key]) < len(samples),
+		}
+		var nonNull []any
+		for _, v := range values[key] {
+			if v == nil {
+				a.Optional = true
+				continue
+			}
+			nonNull = append(nonNull, v)
+		}
+		a.Type = b.inferType(s.Name, key, attrPath, nonNull)
+		if len(samples) == 1 && len(nonNull) == 1 {
+			a.Default = b.defaultOf(a.Type, nonNull[0])
+		}
+		s.Attributes = append(s.Attributes, a)
+	}
+	return s
+}
+
+// inferType returns the type of the samples of a value.
+func (b *Builder) inferType(parent, key, path string, samples []any) string {
+	if len(samples) == 0 {
+		return "any"
+	}
+	var maps []yaml.MapSlice
+	var items []any
+	var strs []string
+	var types []string
+	list := false
+	for _, v := range samples {
+		switch v := v.(type) {
+		case yaml.MapSlice:
+			maps = append(maps, v)
+		case []any:
+			list = true
+			items = append(items, v...)
+		case string:
+			strs = append(strs, v)
+			types = append(types, "str")
+		default:
+			types = append(types, scalarType(v))
+		}
+	}
+	if enum := b.enum(strs); enum != "" {
+		for i, t := range types {
+			if t == "str" {
+				types[i] = enum
+			}
+		}
+	}
+	if len(maps) > 0 {
+		empty := true
+		for _, m := range maps {
+			empty = empty && len(m) == 0
+		}
+		if empty {
+			types = append(types, "{str:any}")
+		} else {
+			types = append(types, b.inferObject(parent, Name(key), path, maps).Name)
+		}
+	}
+	if list {
+		types = append(types, "["+b.inferType(parent, key+"Item", path, nonNil(items))+"]")
+	}
+	// Integers are valid floats.
+	for _, t := range types {
+		if t == "float" {
+			types = remove(types, "int")
+			break
+		}
+	}
+	return Union(types...)
+}
+
+// enum returns the union of the literals of the string samples when they
+// repeat a few distinct values, or an empty string.
+func (b *Builder) enum(strs []string) string {
+	if b.EnumLimit <= 0 {
+		return ""
+	}
+	var literals []string
+	seen := map[string]bool{}
+	for _, v := range strs {
+		if !seen[v] {
+			seen[v] = true
+			literals = append(literals, Literal(v))
+		}
+	}
+	if len(literals) == 0 || len(literals) > b.EnumLimit || len(literals) == len(strs) {
+		return ""
+	}
+	return Union(literals...)
+}
+
+// defaultOf returns the default value expression of an attribute of the type
+// with the sample value: an instance of an inferred schema, which defaults its
+// own attributes, or the literal value.
+func (b *Builder) defaultOf(typ string, v any) string {
+	if m, ok := v.(yaml.MapSlice); ok && len(m) > 0 {
+		for _, s := range b.Schemas {
+			if s.Name == typ {
+				return typ + " {}"
+			}
+		}
+	}
+	return Literal(v)
+}
+
+func scalarType(v any) string {
+	switch v.(type) {
+	case bool:
+		return "bool"
+	case int, int64, uint64:
+		return "int"
+	case float64:
+		return "float"
+	case string:
+		return "str"
+	}
+	return "any"
+}
+
+func nonNil(values []any) []any {
+	var out []any
+	for _, v := range values {
+		if v != nil {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func remove(types []string, t string) []string {
+	var out []string
+	for _, typ := range types {
+		if typ != t {
+			out = append(out, typ)
+		}
+	}
+	return out
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
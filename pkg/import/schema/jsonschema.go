@@ -0,0 +1,306 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package schema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// FromJSONSchema returns the schema named name typed from a JSON Schema
+// object decoded with ordered maps. Defaults are taken from the values, a
+// sample of the object, or else from the JSON Schema, and docs from the
+// descriptions, or else from the comments of the values. Properties not
+// required by the JSON Schema are optional.
+func (b *Builder) FromJSONSchema(name string, root yaml.MapSlice, values yaml.MapSlice) *Schema {
+	c := &converter{b: b, root: root}
+	return c.object("", name, "", root, values)
+}
+
+type converter struct {
+	b    *Builder
+	root yaml.MapSlice
+}
+
+func (c *converter) object(parent, name, path string, s yaml.MapSlice, values yaml.MapSlice) *Schema {
+	out := c.b.newSchema(parent, name, "")
+	c.fill(out, path, s, values)
+	return out
+}
+
+// fill adds the attributes and checks of an object schema to out.
+func (c *converter) fill(out *Schema, path string, s yaml.MapSlice, values yaml.MapSlice) {
+	out.Doc, _ = Get(s, "description").(string)
+	if out.Doc == "" {
+		out.Doc = c.b.Comments[path]
+	}
+	required := map[string]bool{}
+	props := c.properties(s, required)
+	seen := map[string]bool{}
+	for _, prop := range props {
+		key := fmt.Sprint(prop.Key)
+		seen[key] = true
+		ps, _ := prop.Value.(yaml.MapSlice)
+		value, hasValue := lookup(values, key)
+		out.Attributes = append(out.Attributes, c.attribute(out, key, join(path, key), ps, value, hasValue, required[key]))
+	}
+	// Values without properties are typed from their samples.
+	if _, ok := Get(s, "additionalProperties").(yaml.MapSlice); !ok && Get(s, "additionalProperties") != false {
+		for _, item := range values {
+			key := fmt.Sprint(item.Key)
+			if seen[key] || len(props) == 0 {
+				continue
+			}
+			a := &Attribute{Name: key, Doc: c.b.Comments[join(path, key)], Optional: true}
+			if item.Value != nil {
+				a.Type = c.b.inferType(out.Name, key, join(path, key), []any{item.Value})
+				a.Default = c.b.defaultOf(a.Type, item.Value)
+			} else {
+				a.Type = "any"
+			}
+			out.Attributes = append(out.Attributes, a)
+		}
+	}
+}
+
+// properties returns the properties of an object schema and of the schemas
+// it composes with `allOf`, and adds the required property names.
+func (c *converter) properties(s yaml.MapSlice, required map[string]bool) yaml.MapSlice {
+	s = c.resolve(s)
+	if list, ok := Get(s, "required").([]any); ok {
+		for _, r := range list {
+			required[fmt.Sprint(r)] = true
+		}
+	}
+	props, _ := Get(s, "properties").(yaml.MapSlice)
+	if all, ok := Get(s, "allOf").([]any); ok {
+		for _, sub := range all {
+			if m, ok := sub.(yaml.MapSlice); ok {
+				props = append(props, c.properties(m, required)...)
+			}
+		}
+	}
+	return props
+}
+
+func (c *converter) attribute(parent *Schema, key, path string, s yaml.MapSlice, value any, hasValue, required bool) *Attribute {
+	a := &Attribute{Name: key, Optional: !required}
+	a.Doc, _ = Get(s, "description").(string)
+	if a.Doc == "" {
+		a.Doc = c.b.Comments[path]
+	}
+	a.Type = c.typeOf(parent.Name, key, path, s, value)
+	switch {
+	case hasValue && value != nil && Get(s, "$ref") != nil:
+		// Referenced schemas are not converted with the values.
+		a.Default = Literal(value)
+	case hasValue && value != nil:
+		a.Default = c.b.defaultOf(a.Type, value)
+	case !hasValue && Get(s, "default") != nil:
+		a.Default = Literal(Get(s, "default"))
+	}
+	parent.Checks = append(parent.Checks, c.checks(a, s, required)...)
+	return a
+}
+
+// typeOf returns the KCL type of a JSON Schema.
+func (c *converter) typeOf(parent, key, path string, s yaml.MapSlice, value any) string {
+	if ref, ok := Get(s, "$ref").(string); ok {
+		return c.ref(ref)
+	}
+	if v, ok := lookup(s, "const"); ok {
+		return Literal(v)
+	}
+	if enum, ok := Get(s, "enum").([]any); ok && len(enum) > 0 {
+		types := make([]string, len(enum))
+		for i, v := range enum {
+			types[i] = Literal(v)
+		}
+		return Union(types...)
+	}
+	for _, k := range []string{"oneOf", "anyOf"} {
+		if subs, ok := Get(s, k).([]any); ok && len(subs) > 0 {
+			var types []string
+			for i, sub := range subs {
+				m, _ := sub.(yaml.MapSlice)
+				name := key
+				if len(subs) > 1 {
+					name = key + strconv.Itoa(i)
+				}
+				types = append(types, c.typeOf(parent, name, path, m, nil))
+			}
+			return Union(types...)
+		}
+	}
+	var types []string
+	switch t := Get(s, "type").(type) {
+	case string:
+		types = []string{t}
+	case []any:
+		for _, v := range t {
+			types = append(types, fmt.Sprint(v))
+		}
+	}
+	if len(types) == 0 {
+		if Get(s, "properties") != nil || Get(s, "allOf") != nil {
+			types = []string{"object"}
+		} else if Get(s, "items") != nil {
+			types = []string{"array"}
+		}
+	}
+	var out []string
+	for _, t := range types {
+		switch t {
+		case "string":
+			out = append(out, "str")
+		case "integer":
+			out = append(out, "int")
+		case "number":
+			out = append(out, "float")
+		case "boolean":
+			out = append(out, "bool")
+		case "null":
+		case "array":
+			items, _ := Get(s, "items").(yaml.MapSlice)
+			out = append(out, "["+c.typeOf(parent, key+"Item", path, items, nil)+"]")
+		case "object":
+			if len(c.properties(s, map[string]bool{})) > 0 {
+				values, _ := value.(yaml.MapSlice)
+				out = append(out, c.object(parent, Name(key), path, s, values).Name)
+			} else if values, ok := Get(s, "additionalProperties").(yaml.MapSlice); ok {
+				out = append(out, "{str:"+c.typeOf(parent, key+"Value", path, values, nil)+"}")
+			} else {
+				out = append(out, "{str:any}")
+			}
+		default:
+			out = append(out, "any")
+		}
+	}
+	return Union(out...)
+}
+
+// ref returns the schema of a local JSON Schema reference, converting the
+// referenced schema once.
+func (c *converter) ref(ref string) string {
+	if name, ok := c.b.refs[ref]; ok {
+		return name
+	}
+	s, ok := c.pointer(ref)
+	if !ok {
+		return "any"
+	}
+	name := Name(ref[strings.LastIndex(ref, "/")+1:])
+	if len(c.properties(s, map[string]bool{})) == 0 {
+		typ := c.typeOf("", name, "", s, nil)
+		c.b.refs[ref] = typ
+		return typ
+	}
+	// Register the schema before converting it for recursive references.
+	out := c.b.newSchema("", name, "")
+	c.b.refs[ref] = out.Name
+	c.fill(out, "", s, nil)
+	return out.Name
+}
+
+// resolve returns the schema referenced by a schema with a local `$ref`, or
+// the schema itself.
+func (c *converter) resolve(s yaml.MapSlice) yaml.MapSlice {
+	if ref, ok := Get(s, "$ref").(string); ok {
+		if target, ok := c.pointer(ref); ok {
+			return target
+		}
+	}
+	return s
+}
+
+// pointer returns the schema of a local JSON pointer such as
+// `#/definitions/person`.
+func (c *converter) pointer(ref string) (yaml.MapSlice, bool) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, false
+	}
+	var target any = c.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		m, ok := target.(yaml.MapSlice)
+		if !ok {
+			return nil, false
+		}
+		target = Get(m, part)
+	}
+	s, ok := target.(yaml.MapSlice)
+	return s, ok
+}
+
+// checks returns the check expressions of the validation keywords of an
+// attribute.
+func (c *converter) checks(a *Attribute, s yaml.MapSlice, required bool) []*Check {
+	ref := SelfRef(a.Name)
+	var exprs []string
+	bound := func(keyword, op string, length bool) {
+		v := Get(s, keyword)
+		if v == nil {
+			return
+		}
+		if _, ok := v.(bool); ok {
+			return
+		}
+		target := ref
+		if length {
+			target = "len(" + ref + ")"
+		}
+		exprs = append(exprs, fmt.Sprintf("%s %s %s", target, op, Literal(v)))
+	}
+	exclusive := func(keyword string) bool {
+		v, _ := Get(s, keyword).(bool)
+		return v
+	}
+	if exclusive("exclusiveMinimum") {
+		bound("minimum", ">", false)
+	} else {
+		bound("minimum", ">=", false)
+		bound("exclusiveMinimum", ">", false)
+	}
+	if exclusive("exclusiveMaximum") {
+		bound("maximum", "<", false)
+	} else {
+		bound("maximum", "<=", false)
+		bound("exclusiveMaximum", "<", false)
+	}
+	bound("minLength", ">=", true)
+	bound("maxLength", "<=", true)
+	bound("minItems", ">=", true)
+	bound("maxItems", "<=", true)
+	bound("minProperties", ">=", true)
+	bound("maxProperties", "<=", true)
+	if pattern, ok := Get(s, "pattern").(string); ok {
+		c.b.require("regex")
+		exprs = append(exprs, fmt.Sprintf("regex.match(%s, %s)", ref, Literal(pattern)))
+	}
+	checks := make([]*Check, len(exprs))
+	for i, e := range exprs {
+		if !required {
+			e += " if " + ref + " != None"
+		}
+		checks[i] = &Check{Expr: e}
+	}
+	return checks
+}
+
+// Get returns the value of a key of an ordered map, or nil.
+func Get(m yaml.MapSlice, key string) any {
+	v, _ := lookup(m, key)
+	return v
+}
+
+func lookup(m yaml.MapSlice, key string) (any, bool) {
+	for _, item := range m {
+		if fmt.Sprint(item.Key) == key {
+			return item.Value, true
+		}
+	}
+	return nil, false
+}
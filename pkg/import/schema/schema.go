@@ -0,0 +1,355 @@
+// Copyright The KCL Authors. All rights reserved.
+
+// Package schema models the KCL schemas generated by kcl import and writes
+// them as KCL code.
+package schema
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/goccy/go-yaml"
+)
+
+// File is a generated KCL file.
+type File struct {
+	// Doc is the module docstring.
+	Doc string
+	// Imports are the import statements of the file, such as `regex` or
+	// `k8s.api.apps.v1 as apps`.
+	Imports []string
+	// Types are the type aliases declared before the schemas.
+	Types   []*Type
+	Schemas []*Schema
+	// Body is the KCL code following the schemas, if any.
+	Body string
+}
+
+// Type is a generated KCL type alias, such as `type Color = "RED" | "BLUE"`.
+type Type struct {
+	Name string
+	Type string
+	Doc  string
+}
+
+// Schema is a generated KCL schema.
+type Schema struct {
+	Name string
+	// Base is the name of the base schema, if any.
+	Base string
+	Doc  string
+	// IndexSignature is the index signature of the attributes not declared,
+	// such as `[...str]: any`, if any.
+	IndexSignature string
+	Attributes     []*Attribute
+	Checks         []*Check
+}
+
+// Attribute is an attribute of a generated schema.
+type Attribute struct {
+	Name string
+	// Type is the KCL type of the attribute, such as `str`, `[int]` or
+	// `"a" | "b"`.
+	Type string
+	// Default is the KCL expression of the default value, if any.
+	Default  string
+	Doc      string
+	Optional bool
+}
+
+// Check is a check expression of a generated schema.
+type Check struct {
+	Expr    string
+	Message string
+}
+
+// Attribute returns the attribute of the schema with the name, or nil.
+func (s *Schema) Attribute(name string) *Attribute {
+	for _, a := range s.Attributes {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// Bytes returns the KCL code of the file.
+func (f *File) Bytes() []byte {
+	var b strings.Builder
+	if f.Doc != "" {
+		fmt.Fprintf(&b, "\"\"\"\n%s\n\"\"\"\n", escapeDoc(strings.TrimSpace(f.Doc)))
+	}
+	if len(f.Imports) > 0 {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		for _, imp := range f.Imports {
+			fmt.Fprintf(&b, "import %s\n", imp)
+		}
+	}
+	for _, t := range f.Types {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		if doc := strings.TrimSpace(t.Doc); doc != "" {
+			for _, l := range strings.Split(doc, "\n") {
+				b.WriteString(strings.TrimRight("# "+l, " ") + "\n")
+			}
+		}
+		fmt.Fprintf(&b, "type %s = %s\n", t.Name, t.Type)
+	}
+	for _, s := range f.Schemas {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		s.write(&b)
+	}
+	if body := strings.TrimSpace(f.Body); body != "" {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(body)
+		b.WriteString("\n")
+	}
+	return []byte(b.String())
+}
+
+// write writes the schema with a docstring documenting its attributes in
+// the format of the KCL auto-gen tools.
+func (s *Schema) write(b *strings.Builder) {
+	fmt.Fprintf(b, "schema %s", s.Name)
+	if s.Base != "" {
+		fmt.Fprintf(b, "(%s)", s.Base)
+	}
+	b.WriteString(":\n")
+	b.WriteString("    r\"\"\"\n")
+	doc := strings.TrimSpace(s.Doc)
+	if doc == "" {
+		doc = s.Name
+	}
+	for _, l := range strings.Split(doc, "\n") {
+		writeDocLine(b, "    ", l)
+	}
+	if len(s.Attributes) > 0 {
+		b.WriteString("\n    Attributes\n    ----------\n")
+		for _, a := range s.Attributes {
+			requirement := "required"
+			if a.Optional {
+				requirement = "optional"
+			}
+			def := "Undefined"
+			if a.Default != "" {
+				def = a.Default
+			}
+			fmt.Fprintf(b, "    %s : %s, default is %s, %s\n", a.Name, a.Type, escapeDoc(def), requirement)
+			if doc := strings.TrimSpace(a.Doc); doc != "" {
+				for _, l := range strings.Split(doc, "\n") {
+					writeDocLine(b, "        ", l)
+				}
+			}
+		}
+	}
+	b.WriteString("    \"\"\"\n")
+	if s.IndexSignature != "" {
+		b.WriteString("\n    " + s.IndexSignature + "\n")
+	}
+	for _, a := range s.Attributes {
+		b.WriteString("\n    ")
+		b.WriteString(Ident(a.Name))
+		if a.Optional {
+			b.WriteString("?")
+		}
+		b.WriteString(": " + a.Type)
+		if a.Default != "" {
+			b.WriteString(" = " + a.Default)
+		}
+		b.WriteString("\n")
+	}
+	if len(s.Checks) > 0 {
+		b.WriteString("\n    check:\n")
+		for _, c := range s.Checks {
+			b.WriteString("        " + c.Expr)
+			if c.Message != "" {
+				b.WriteString(", " + strconv.Quote(c.Message))
+			}
+			b.WriteString("\n")
+		}
+	}
+}
+
+func writeDocLine(b *strings.Builder, indent, line string) {
+	line = strings.TrimRight(escapeDoc(line), " \t")
+	if line == "" {
+		b.WriteString("\n")
+		return
+	}
+	b.WriteString(indent + line + "\n")
+}
+
+func escapeDoc(s string) string {
+	return strings.ReplaceAll(s, `"""`, `\"\"\"`)
+}
+
+// keywords are the KCL keywords, which must be prefixed with `$` when used as
+// attribute names.
+var keywords = map[string]bool{
+	"True": true, "False": true, "None": true, "Undefined": true, "import": true, "as": true,
+	"rule": true, "schema": true, "mixin": true, "protocol": true, "check": true, "for": true,
+	"assert": true, "if": true, "elif": true, "else": true, "or": true, "and": true, "not": true,
+	"in": true, "is": true, "final": true, "lambda": true, "all": true, "any": true, "filter": true,
+	"map": true, "type": true,
+}
+
+// IsKeyword reports whether name is a KCL keyword.
+func IsKeyword(name string) bool {
+	return keywords[name]
+}
+
+var identRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Ident returns the KCL attribute name of a key: the key itself, prefixed
+// with `$` if it is a keyword, or quoted if it is not an identifier.
+func Ident(name string) string {
+	switch {
+	case keywords[name]:
+		return "$" + name
+	case identRe.MatchString(name):
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+// Ref returns the KCL expression accessing the attribute of a value, such as
+// `config.name` or `config["my-key"]`.
+func Ref(value, name string) string {
+	if identRe.MatchString(name) && !keywords[name] {
+		return value + "." + name
+	}
+	return value + "[" + strconv.Quote(name) + "]"
+}
+
+// SelfRef returns the KCL expression accessing an attribute of the schema
+// in its checks, such as `name` or `self["my-key"]`.
+func SelfRef(name string) string {
+	return strings.TrimPrefix(Ref("self", name), "self.")
+}
+
+// Name returns the PascalCase schema name of a key, prefixed with `_` if the
+// key does not start with a letter.
+func Name(key string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range key {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	name := b.String()
+	if name == "" || !unicode.IsLetter(rune(name[0])) {
+		name = "_" + name
+	}
+	return name
+}
+
+// Literal returns the KCL literal of a value decoded from JSON or YAML.
+func Literal(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "None"
+	case bool:
+		if v {
+			return "True"
+		}
+		return "False"
+	case string:
+		return strconv.Quote(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) && math.Abs(v) < 1e15 {
+			return strconv.FormatFloat(v, 'f', 1, 64)
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = Literal(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case yaml.MapSlice:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = Literal(fmt.Sprint(item.Key)) + ": " + Literal(item.Value)
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := make([]string, len(keys))
+		for i, k := range keys {
+			items[i] = Literal(k) + ": " + Literal(v[k])
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+	return strconv.Quote(fmt.Sprint(v))
+}
+
+// Union returns the union type of the types, without duplicates, or `any`
+// when there is none or one of them is `any`.
+func Union(types ...string) string {
+	var out []string
+	seen := map[string]bool{}
+	for _, t := range types {
+		if t == "any" {
+			return "any"
+		}
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	if len(out) == 0 {
+		return "any"
+	}
+	return strings.Join(out, " | ")
+}
+
+// Names allocates unique schema names.
+type Names map[string]bool
+
+// New returns a unique name based on the name, prefixed with the parent name
+// or suffixed with a number when taken.
+func (n Names) New(parent, name string) string {
+	candidates := []string{name, parent + name}
+	for _, c := range candidates {
+		if c != "" && !n[c] {
+			n[c] = true
+			return c
+		}
+	}
+	for i := 1; ; i++ {
+		c := fmt.Sprintf("%s%s%d", parent, name, i)
+		if !n[c] {
+			n[c] = true
+			return c
+		}
+	}
+}
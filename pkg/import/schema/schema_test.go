@@ -0,0 +1,192 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+)
+
+const values = `# Default values for nginx.
+# This is a YAML-formatted file.
+
+# -- Number of replicas
+replicaCount: 1
+
+image:
+  # The image repository
+  repository: nginx
+  pullPolicy: IfNotPresent # Image pull policy
+  # -- Overrides the image tag.
+  tag: ""
+
+podAnnotations: {}
+nodeSelector:
+resources:
+  limits:
+    cpu: 100m
+ports:
+  - name: http
+    port: 80
+  - name: https
+    port: 443
+    tls: true
+`
+
+func decode(t *testing.T, data string) yaml.MapSlice {
+	t.Helper()
+	var v yaml.MapSlice
+	if err := yaml.UnmarshalWithOptions([]byte(data), &v, yaml.UseOrderedMap()); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestComments(t *testing.T) {
+	comments, err := Comments([]byte(values))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"replicaCount":     "Number of replicas",
+		"image.repository": "The image repository",
+		"image.pullPolicy": "Image pull policy",
+		"image.tag":        "Overrides the image tag.",
+	}
+	if !reflect.DeepEqual(comments, want) {
+		t.Errorf("Comments() = %#v, want %#v", comments, want)
+	}
+}
+
+func TestInfer(t *testing.T) {
+	comments, err := Comments([]byte(values))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBuilder(comments)
+	b.Infer("Values", decode(t, values))
+	got := string(b.File("").Bytes())
+	for _, want := range []string{
+		"schema Values:\n",
+		"    replicaCount : int, default is 1, required\n        Number of replicas\n",
+		"\n    replicaCount: int = 1\n",
+		"\n    image: Image = Image {}\n",
+		"\n    podAnnotations: {str:any} = {}\n",
+		"\n    nodeSelector?: any\n",
+		"\n    resources: Resources = Resources {}\n",
+		"\n    ports: [PortsItem] = [{\"name\": \"http\", \"port\": 80}, {\"name\": \"https\", \"port\": 443, \"tls\": True}]\n",
+		"schema Image:\n",
+		"\n    tag: str = \"\"\n",
+		"schema Limits:\n",
+		"\n    cpu: str = \"100m\"\n",
+		"schema PortsItem:\n",
+		"\n    port: int\n",
+		"\n    tls?: bool\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Infer() missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestInferSamples(t *testing.T) {
+	var samples []yaml.MapSlice
+	for _, data := range []string{
+		"name: a\nlevel: debug\nport: 80\nmeta: {team: x}\n",
+		"name: b\nlevel: info\nport: \"http\"\n---\nname: c\nlevel: debug\nreplicas: 2\nmeta: null\n",
+	} {
+		s, err := Samples([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples = append(samples, s...)
+	}
+	if len(samples) != 3 {
+		t.Fatalf("Samples() returned %d samples, want 3", len(samples))
+	}
+	b := NewBuilder(nil)
+	b.EnumLimit = 5
+	b.InferSamples("Config", samples)
+	got := string(b.File("").Bytes())
+	for _, want := range []string{
+		"schema Config:\n",
+		"\n    name: str\n",
+		"\n    level: \"debug\" | \"info\"\n",
+		"\n    port?: int | str\n",
+		"\n    meta?: Meta\n",
+		"\n    replicas?: int\n",
+		"schema Meta:\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("InferSamples() missing %q in:\n%s", want, got)
+		}
+	}
+	if _, err := Samples([]byte("- a\n")); err == nil {
+		t.Errorf("Samples() of a list returned no error")
+	}
+}
+
+func TestFromJSONSchema(t *testing.T) {
+	jsonSchema := `{
+  "type": "object",
+  "required": ["image"],
+  "properties": {
+    "replicaCount": {"type": "integer", "minimum": 1, "description": "The replicas."},
+    "image": {
+      "type": "object",
+      "required": ["repository"],
+      "properties": {
+        "repository": {"type": "string", "pattern": "^[a-z/]+$"},
+        "pullPolicy": {"type": "string", "enum": ["Always", "IfNotPresent"]}
+      }
+    },
+    "service": {"$ref": "#/definitions/service"},
+    "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+    "tolerations": {"type": "array", "items": {"type": "object"}},
+    "type": {"type": ["string", "null"]}
+  },
+  "definitions": {
+    "service": {"type": "object", "properties": {"port": {"type": "integer", "default": 80}}}
+  }
+}`
+	b := NewBuilder(map[string]string{"image": "The image."})
+	b.FromJSONSchema("Values", decode(t, jsonSchema), decode(t, values))
+	got := string(b.File("").Bytes())
+	for _, want := range []string{
+		"import regex\n",
+		"\n    replicaCount?: int = 1\n",
+		"    replicaCount : int, default is 1, optional\n        The replicas.\n",
+		"\n    image: Image = Image {}\n",
+		"\n    service?: Service\n",
+		"\n    labels?: {str:str}\n",
+		"\n    tolerations?: [{str:any}]\n",
+		"\n    $type?: str\n",
+		"\n    podAnnotations?: {str:any} = {}\n",
+		"\n        replicaCount >= 1 if replicaCount != None\n",
+		"schema Image:\n    r\"\"\"\n    The image.\n",
+		"\n    repository: str = \"nginx\"\n",
+		"\n    pullPolicy?: \"Always\" | \"IfNotPresent\" = \"IfNotPresent\"\n",
+		"\n        regex.match(repository, \"^[a-z/]+$\")\n",
+		"schema Service:\n",
+		"\n    port?: int = 80\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FromJSONSchema() missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestIdent(t *testing.T) {
+	for name, want := range map[string]string{
+		"name":               "name",
+		"type":               "$type",
+		"cancel-in-progress": `"cancel-in-progress"`,
+	} {
+		if got := Ident(name); got != want {
+			t.Errorf("Ident(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Instance is a config value instantiating a schema, written as
+// `Name { ... }`.
+type Instance struct {
+	// Schema is the name of the schema, such as `Service` or
+	// `compose.Service`.
+	Schema string
+	Value  yaml.MapSlice
+}
+
+// Config returns the multi-line KCL config expression of a value decoded
+// from JSON or YAML with ordered maps, such as `{ name = "app" }`, indented
+// by the indentation of its first line.
+func Config(v any, indent string) string {
+	var b strings.Builder
+	writeConfig(&b, v, indent)
+	return b.String()
+}
+
+func writeConfig(b *strings.Builder, v any, indent string) {
+	inner := indent + "    "
+	switch v := v.(type) {
+	case Instance:
+		b.WriteString(v.Schema + " ")
+		writeConfig(b, v.Value, indent)
+	case yaml.MapSlice:
+		if len(v) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteString("{\n")
+		for _, item := range v {
+			key := fmt.Sprint(item.Key)
+			if identRe.MatchString(key) && !keywords[key] {
+				b.WriteString(inner + key + " = ")
+			} else {
+				b.WriteString(inner + Literal(key) + " = ")
+			}
+			writeConfig(b, item.Value, inner)
+			b.WriteString("\n")
+		}
+		b.WriteString(indent + "}")
+	case []any:
+		if len(v) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteString("[\n")
+		for _, item := range v {
+			b.WriteString(inner)
+			writeConfig(b, item, inner)
+			b.WriteString("\n")
+		}
+		b.WriteString(indent + "]")
+	default:
+		b.WriteString(Literal(v))
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright The KCL Authors. All rights reserved.
+
+// Package tree lays out the KCL package of a directory tree of configs,
+// keeping its structure.
+package tree
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"kcl-lang.io/cli/pkg/import/schema"
+)
+
+// Extensions are the extensions of the config files imported from a tree.
+var Extensions = []string{".json", ".yaml", ".yml", ".toml"}
+
+// MainFile is the file of the package importing the modules of the tree.
+const MainFile = "main.k"
+
+// Entry is a config file of the tree and its KCL module.
+type Entry struct {
+	// Source is the path of the config file.
+	Source string
+	// Output is the path of the KCL file relative to the package root, such
+	// as `prod_eu/app.k` for `prod-eu/app.yaml`.
+	Output string
+	// Module is the import path of the KCL file, such as `prod_eu.app`.
+	Module string
+	// Alias is the name of the module in the main file, such as
+	// `prod_eu_app`.
+	Alias string
+}
+
+// Plan returns the entries of the config files found under the roots, which
+// are directories or files. The paths under a directory are kept relative to
+// it, and the files given as roots are at the top of the package. Names are
+// turned into identifiers, and the extension is appended to the names of the
+// files of a directory sharing a base name, or named as the main file or as
+// a KCL keyword.
+func Plan(roots map[string][]string) []*Entry {
+	var entries []*Entry
+	taken := map[string]bool{strings.TrimSuffix(MainFile, ".k"): true}
+	rootNames := make([]string, 0, len(roots))
+	for root := range roots {
+		rootNames = append(rootNames, root)
+	}
+	sort.Strings(rootNames)
+	for _, root := range rootNames {
+		files := append([]string{}, roots[root]...)
+		sort.Strings(files)
+		for _, f := range files {
+			if !isConfig(f) {
+				continue
+			}
+			rel, err := filepath.Rel(root, f)
+			if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+				rel = filepath.Base(f)
+			}
+			var parts []string
+			for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(rel)), "/") {
+				if dir == "." {
+					continue
+				}
+				// Directories named as keywords are prefixed like the
+				// names starting with a digit.
+				if id := ident(dir); schema.IsKeyword(id) {
+					parts = append(parts, "_"+id)
+				} else {
+					parts = append(parts, id)
+				}
+			}
+			ext := filepath.Ext(rel)
+			base := ident(strings.TrimSuffix(filepath.Base(rel), ext))
+			module := strings.Join(append(parts, base), ".")
+			if taken[module] || schema.IsKeyword(base) {
+				base += "_" + strings.TrimPrefix(strings.ToLower(ext), ".")
+				module = strings.Join(append(parts, base), ".")
+			}
+			for i := 2; taken[module]; i++ {
+				module = strings.Join(append(parts, fmt.Sprintf("%s%d", base, i)), ".")
+			}
+			taken[module] = true
+			entries = append(entries, &Entry{
+				Source: f,
+				Output: filepath.FromSlash(strings.ReplaceAll(module, ".", "/")) + ".k",
+				Module: module,
+				Alias:  strings.ReplaceAll(module, ".", "_"),
+			})
+		}
+	}
+	return entries
+}
+
+// Main returns the main file of the package, importing the modules of the
+// entries.
+func Main(entries []*Entry) *schema.File {
+	f := &schema.File{Doc: "This file was generated by kcl import. DO NOT EDIT."}
+	for _, e := range entries {
+		if e.Alias == e.Module {
+			f.Imports = append(f.Imports, e.Module)
+		} else {
+			f.Imports = append(f.Imports, e.Module+" as "+e.Alias)
+		}
+	}
+	return f
+}
+
+func isConfig(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range Extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+var nonIdentRe = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// ident returns the identifier of a file or directory name, such as
+// `prod_eu` for `prod-eu`.
+func ident(name string) string {
+	id := strings.Trim(nonIdentRe.ReplaceAllString(name, "_"), "_")
+	if id == "" || id[0] >= '0' && id[0] <= '9' {
+		id = "_" + id
+	}
+	return id
+}
@@ -0,0 +1,69 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package tree
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	root := filepath.Join("configs")
+	entries := Plan(map[string][]string{
+		root: {
+			filepath.Join(root, "app.yaml"),
+			filepath.Join(root, "app.json"),
+			filepath.Join(root, "prod-eu", "1db.toml"),
+			filepath.Join(root, "README.md"),
+		},
+		"extra.yml": {"extra.yml"},
+	})
+	want := []Entry{
+		{filepath.Join(root, "app.json"), "app.k", "app", "app"},
+		{filepath.Join(root, "app.yaml"), "app_yaml.k", "app_yaml", "app_yaml"},
+		{filepath.Join(root, "prod-eu", "1db.toml"), filepath.Join("prod_eu", "_1db.k"), "prod_eu._1db", "prod_eu__1db"},
+		{"extra.yml", "extra.k", "extra", "extra"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Plan() returned %d entries, want %d", len(entries), len(want))
+	}
+	for i, e := range entries {
+		if *e != want[i] {
+			t.Errorf("Plan()[%d] = %+v, want %+v", i, *e, want[i])
+		}
+	}
+	got := string(Main(entries).Bytes())
+	if want := "\"\"\"\nThis file was generated by kcl import. DO NOT EDIT.\n\"\"\"\n\nimport app\nimport app_yaml\nimport prod_eu._1db as prod_eu__1db\nimport extra\n"; got != want {
+		t.Errorf("Main() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPlanReserved(t *testing.T) {
+	root := filepath.Join("configs")
+	entries := Plan(map[string][]string{
+		root: {
+			filepath.Join(root, "main.yaml"),
+			filepath.Join(root, "schema.yaml"),
+			filepath.Join(root, "import.json"),
+			filepath.Join(root, "import.yaml"),
+			filepath.Join(root, "sub", "main.json"),
+			filepath.Join(root, "lambda", "app.json"),
+		},
+	})
+	want := []Entry{
+		{filepath.Join(root, "import.json"), "import_json.k", "import_json", "import_json"},
+		{filepath.Join(root, "import.yaml"), "import_yaml.k", "import_yaml", "import_yaml"},
+		{filepath.Join(root, "lambda", "app.json"), filepath.Join("_lambda", "app.k"), "_lambda.app", "_lambda_app"},
+		{filepath.Join(root, "main.yaml"), "main_yaml.k", "main_yaml", "main_yaml"},
+		{filepath.Join(root, "schema.yaml"), "schema_yaml.k", "schema_yaml", "schema_yaml"},
+		{filepath.Join(root, "sub", "main.json"), filepath.Join("sub", "main.k"), "sub.main", "sub_main"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Plan() returned %d entries, want %d", len(entries), len(want))
+	}
+	for i, e := range entries {
+		if *e != want[i] {
+			t.Errorf("Plan()[%d] = %+v, want %+v", i, *e, want[i])
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package lint
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"kcl-lang.io/cli/pkg/source"
+)
+
+// Config is the `[lint]` section of kcl.mod:
+//
+//	[lint]
+//	exclude = ["vendor/*", "*_gen.k"]
+//
+//	[lint.rules]
+//	missing-docstring = "off"
+//	unused-import = "error"
+type Config struct {
+	// Rules overrides the default severity of rules by ID.
+	Rules map[string]Severity `toml:"rules"`
+	// Exclude are the glob patterns of the files not reported, relative to
+	// the module root.
+	Exclude []string `toml:"exclude"`
+}
+
+// LoadConfig reads the `[lint]` section of a kcl.mod file. It returns an
+// empty config when the section is missing.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	mod := struct {
+		Lint *Config `toml:"lint"`
+	}{}
+	if _, err := toml.Decode(string(data), &mod); err != nil {
+		return nil, fmt.Errorf("failed to parse the [lint] section of %s: %w", path, err)
+	}
+	if mod.Lint == nil {
+		return &Config{}, nil
+	}
+	return mod.Lint, nil
+}
+
+// Validate checks that the configured rules exist.
+func (c *Config) Validate(rules []*Rule) error {
+	known := map[string]bool{}
+	for _, r := range rules {
+		known[r.ID] = true
+	}
+	for id := range c.Rules {
+		if !known[id] {
+			return fmt.Errorf("unknown lint rule %q", id)
+		}
+	}
+	return nil
+}
+
+// SetSeverity overrides the severity of a rule.
+func (c *Config) SetSeverity(id string, s Severity) {
+	if c.Rules == nil {
+		c.Rules = map[string]Severity{}
+	}
+	c.Rules[id] = s
+}
+
+func (c *Config) severity(r *Rule) Severity {
+	if s, ok := c.Rules[r.ID]; ok {
+		return s
+	}
+	return r.Severity
+}
+
+// directiveRe matches `kcl-lint: disable=RULE[,RULE]` and
+// `kcl-lint: disable-file=RULE[,RULE]` comments. The `all` rule matches any
+// rule.
+var directiveRe = regexp.MustCompile(`^\s*kcl-lint:\s*(disable|disable-file)=([\w-]+(?:\s*,\s*[\w-]+)*)`)
+
+// suppressed reports whether the finding is disabled by a comment. A
+// trailing comment applies to its own line, a comment on its own line to the
+// next statement line and its decorators, and a `disable-file` comment to the
+// whole file.
+func suppressed(pkg *source.Package, f *Finding) bool {
+	var file *source.File
+	for _, sf := range append(append([]*source.File{}, pkg.Files...), pkg.TestFiles...) {
+		if sf.Path == f.File {
+			file = sf
+		}
+	}
+	if file == nil {
+		return false
+	}
+	for _, c := range file.Comments {
+		m := directiveRe.FindStringSubmatch(c.Text)
+		if m == nil || !matchesRule(m[2], f.Rule) {
+			continue
+		}
+		switch {
+		case m[1] == "disable-file":
+			return true
+		case c.Trailing:
+			if c.Line == f.Line {
+				return true
+			}
+		default:
+			for _, l := range file.Lines {
+				if l.Start <= c.Line {
+					continue
+				}
+				if f.Line >= l.Start && f.Line <= l.End {
+					return true
+				}
+				// Decorators belong to the next statement line.
+				if !strings.HasPrefix(l.Text, "@") {
+					break
+				}
+			}
+		}
+	}
+	return false
+}
+
+func matchesRule(list, rule string) bool {
+	for _, id := range strings.Split(list, ",") {
+		if id = strings.TrimSpace(id); id == rule || id == "all" {
+			return true
+		}
+	}
+	return false
+}
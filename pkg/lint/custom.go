@@ -0,0 +1,326 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package lint
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"kcl-lang.io/cli/pkg/source"
+)
+
+// CustomResultKey is the output key holding the findings of custom rules.
+const CustomResultKey = "kcl_lint_findings"
+
+// View is the structured view of a package passed to custom rules.
+type View struct {
+	// Path is the package directory.
+	Path    string        `json:"path"`
+	Schemas []*ViewSchema `json:"schemas"`
+}
+
+// ViewSchema is a schema of the package view.
+type ViewSchema struct {
+	Name string `json:"name"`
+	// Package is the package path of the schema, if any.
+	Package    string           `json:"package"`
+	Doc        string           `json:"doc"`
+	File       string           `json:"file"`
+	Line       int              `json:"line"`
+	Attributes []*ViewAttribute `json:"attributes"`
+}
+
+// ViewAttribute is a schema attribute of the package view.
+type ViewAttribute struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Doc      string `json:"doc"`
+	Default  string `json:"default"`
+	Required bool   `json:"required"`
+	Line     int    `json:"line"`
+}
+
+// Schema returns the schema of the view named name, or nil.
+func (v *View) Schema(name string) *ViewSchema {
+	for _, s := range v.Schemas {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// openAPIType is the subset of the OpenAPI schema exported by
+// `api.GetKclPackage` read by ViewFromOpenAPI.
+type openAPIType struct {
+	Type                 string                  `json:"type"`
+	Ref                  string                  `json:"$ref"`
+	Description          string                  `json:"description"`
+	Default              any                     `json:"default"`
+	Required             []string                `json:"required"`
+	Properties           map[string]*openAPIType `json:"properties"`
+	Items                *openAPIType            `json:"items"`
+	AdditionalProperties *openAPIType            `json:"additionalProperties"`
+}
+
+// ViewFromOpenAPI builds the view of a package from its Swagger v2 spec, as
+// exported by the doc command, and adds the source locations found in pkg.
+func ViewFromOpenAPI(spec []byte, pkg *source.Package) (*View, error) {
+	doc := struct {
+		Definitions map[string]*openAPIType `json:"definitions"`
+	}{}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("invalid package spec: %w", err)
+	}
+	view := &View{Path: pkg.Dir, Schemas: []*ViewSchema{}}
+	names := make([]string, 0, len(doc.Definitions))
+	for name := range doc.Definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, full := range names {
+		def := doc.Definitions[full]
+		s := &ViewSchema{Name: full, Doc: strings.TrimSpace(def.Description), Attributes: []*ViewAttribute{}}
+		if i := strings.LastIndex(full, "."); i >= 0 {
+			s.Package, s.Name = full[:i], full[i+1:]
+		}
+		var src *source.Schema
+		for _, f := range pkg.Files {
+			if src = f.Schema(s.Name); src != nil {
+				s.File, s.Line = f.Path, src.Line
+				break
+			}
+		}
+		required := map[string]bool{}
+		for _, r := range def.Required {
+			required[r] = true
+		}
+		attrs := make([]string, 0, len(def.Properties))
+		for name := range def.Properties {
+			attrs = append(attrs, name)
+		}
+		sort.Strings(attrs)
+		for _, name := range attrs {
+			prop := def.Properties[name]
+			a := &ViewAttribute{
+				Name:     name,
+				Type:     typeString(prop),
+				Doc:      strings.TrimSpace(prop.Description),
+				Required: required[name],
+			}
+			if prop.Default != nil {
+				a.Default = fmt.Sprint(prop.Default)
+			}
+			if src != nil {
+				if sa := src.Attribute(name); sa != nil {
+					a.Line = sa.Line
+					if a.Default == "" {
+						a.Default = sa.Default
+					}
+				}
+			}
+			s.Attributes = append(s.Attributes, a)
+		}
+		view.Schemas = append(view.Schemas, s)
+	}
+	return view, nil
+}
+
+// typeString returns the KCL type of an OpenAPI type.
+func typeString(t *openAPIType) string {
+	if t == nil {
+		return "any"
+	}
+	if t.Ref != "" {
+		return t.Ref[strings.LastIndex(t.Ref, "/")+1:]
+	}
+	switch t.Type {
+	case "string":
+		return "str"
+	case "integer":
+		return "int"
+	case "number":
+		return "float"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[" + typeString(t.Items) + "]"
+	case "object":
+		if t.AdditionalProperties != nil {
+			return "{str:" + typeString(t.AdditionalProperties) + "}"
+		}
+		return "{str:}"
+	case "":
+		return "any"
+	}
+	return t.Type
+}
+
+// CustomFinding is a finding returned by a custom rule. The location is
+// either given by file and line, or by the schema and attribute names.
+type CustomFinding struct {
+	Message   string `json:"message"`
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Schema    string `json:"schema"`
+	Attribute string `json:"attribute"`
+}
+
+// CustomRuleSet is a set of rules written as KCL lambdas in a rules package.
+// Every public top-level lambda of the package is a rule, whose ID is the
+// lambda name. A rule receives the package view as a dict and returns a list
+// of findings, dicts with a `message` and either `file` and `line`, or
+// `schema` and optionally `attribute` keys.
+type CustomRuleSet struct {
+	// Dir is the rules package directory.
+	Dir string
+	// Files are the KCL files of the rules package.
+	Files []string
+	// Rules are the rules of the package.
+	Rules []*Rule
+
+	// Describe returns the view of a linted package.
+	Describe func(pkg *source.Package) (*View, error)
+	// Evaluate runs the KCL program with the rules package files and returns
+	// its JSON output.
+	Evaluate func(files []string, program string) (string, error)
+
+	results map[string]map[string][]CustomFinding
+}
+
+// LoadCustomRules loads the rules of the rules package in dir.
+func LoadCustomRules(dir string) (*CustomRuleSet, error) {
+	pkg, err := source.LoadPackage(dir)
+	if err != nil {
+		return nil, err
+	}
+	set := &CustomRuleSet{Dir: dir, results: map[string]map[string][]CustomFinding{}}
+	for _, f := range pkg.Files {
+		set.Files = append(set.Files, f.Path)
+		for _, lam := range f.Lambdas {
+			if strings.HasPrefix(lam.Name, "_") {
+				continue
+			}
+			name := lam.Name
+			set.Rules = append(set.Rules, &Rule{
+				ID:       name,
+				Doc:      fmt.Sprintf("Custom rule from %s.", filepath.Base(f.Path)),
+				Severity: Warning,
+				Check:    func(p *Pass) { set.check(p, name) },
+			})
+		}
+	}
+	if len(set.Rules) == 0 {
+		return nil, fmt.Errorf("no rule lambdas found in %s", dir)
+	}
+	return set, nil
+}
+
+// Program returns the KCL code evaluating the rules on the view. The view is
+// embedded as base64 encoded JSON so that it never needs escaping.
+func (s *CustomRuleSet) Program(view *View) (string, error) {
+	data, err := json.Marshal(view)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString("import base64\nimport json\n\n")
+	fmt.Fprintf(&b, "_kcl_lint_view = json.decode(base64.decode(%q))\n", base64.StdEncoding.EncodeToString(data))
+	fmt.Fprintf(&b, "%s = {\n", CustomResultKey)
+	for _, r := range s.Rules {
+		fmt.Fprintf(&b, "    %s = %s(_kcl_lint_view)\n", r.ID, r.ID)
+	}
+	b.WriteString("}\n")
+	return b.String(), nil
+}
+
+// ParseCustomResult reads the findings of each rule from the JSON output of
+// the program.
+func ParseCustomResult(output string) (map[string][]CustomFinding, error) {
+	result := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("invalid custom rules output: %w", err)
+	}
+	findings := map[string][]CustomFinding{}
+	raw, ok := result[CustomResultKey]
+	if !ok {
+		return findings, nil
+	}
+	byRule := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &byRule); err != nil {
+		return nil, fmt.Errorf("invalid custom rules output: %w", err)
+	}
+	for rule, data := range byRule {
+		if string(data) == "null" {
+			continue
+		}
+		var fs []CustomFinding
+		if err := json.Unmarshal(data, &fs); err != nil {
+			return nil, fmt.Errorf("rule %s must return a list of findings: %w", rule, err)
+		}
+		findings[rule] = fs
+	}
+	return findings, nil
+}
+
+// check reports the findings of the rule on the package of the pass. All
+// the rules are evaluated at once on the first check of a package.
+func (s *CustomRuleSet) check(p *Pass, rule string) {
+	results, ok := s.results[p.Package.Dir]
+	if !ok {
+		view, err := s.Describe(p.Package)
+		if err != nil {
+			p.Fail(err)
+			return
+		}
+		program, err := s.Program(view)
+		if err != nil {
+			p.Fail(err)
+			return
+		}
+		output, err := s.Evaluate(s.Files, program)
+		if err != nil {
+			p.Fail(fmt.Errorf("failed to evaluate the custom rules of %s: %w", s.Dir, err))
+			return
+		}
+		if results, err = ParseCustomResult(output); err != nil {
+			p.Fail(err)
+			return
+		}
+		results = locate(results, view)
+		s.results[p.Package.Dir] = results
+	}
+	for _, cf := range results[rule] {
+		f := p.file(cf.File)
+		if f == nil {
+			continue
+		}
+		p.Reportf(f, cf.Line, "%s", cf.Message)
+	}
+}
+
+// locate fills the file and line of the findings located by schema and
+// attribute names.
+func locate(results map[string][]CustomFinding, view *View) map[string][]CustomFinding {
+	for _, fs := range results {
+		for i := range fs {
+			f := &fs[i]
+			if f.File != "" || f.Schema == "" {
+				continue
+			}
+			if s := view.Schema(f.Schema); s != nil {
+				f.File, f.Line = s.File, s.Line
+				for _, a := range s.Attributes {
+					if a.Name == f.Attribute && a.Line > 0 {
+						f.Line = a.Line
+					}
+				}
+			}
+		}
+	}
+	return results
+}
@@ -0,0 +1,163 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package lint
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"kcl-lang.io/cli/pkg/diff"
+	"kcl-lang.io/cli/pkg/formatter"
+	"kcl-lang.io/cli/pkg/source"
+)
+
+// maxFixRounds bounds the number of lint and fix rounds. Overlapping fixes
+// are applied in later rounds.
+const maxFixRounds = 10
+
+// Fix is the automatic fix of a finding, a list of text edits of its file.
+type Fix struct {
+	Edits []formatter.TextEdit `json:"edits"`
+}
+
+// ReportFix reports a finding with its automatic fix.
+func (p *Pass) ReportFix(f *source.File, line int, edits []formatter.TextEdit, format string, args ...any) {
+	p.Reportf(f, line, format, args...)
+	p.findings[len(p.findings)-1].Fix = &Fix{Edits: edits}
+}
+
+// DeleteLines returns the edit deleting the 1-based inclusive line range.
+func DeleteLines(start, end int) formatter.TextEdit {
+	return ReplaceLines(start, end, "")
+}
+
+// ReplaceLines returns the edit replacing the 1-based inclusive line range,
+// line terminators included, with text.
+func ReplaceLines(start, end int, text string) formatter.TextEdit {
+	return formatter.TextEdit{
+		Range: formatter.Range{
+			Start: formatter.Position{Line: start - 1},
+			End:   formatter.Position{Line: end},
+		},
+		NewText: text,
+	}
+}
+
+// Position returns the position of the byte at index i of the text of the
+// logical line l of the file.
+func Position(f *source.File, l source.Line, i int) formatter.Position {
+	line := l.Start + strings.Count(l.Text[:i], "\n")
+	if nl := strings.LastIndex(l.Text[:i], "\n"); nl >= 0 {
+		return formatter.Position{Line: line - 1, Character: i - nl - 1}
+	}
+	lineStart := strings.LastIndex(string(f.Src[:l.Offset]), "\n") + 1
+	return formatter.Position{Line: line - 1, Character: l.Offset - lineStart + i}
+}
+
+// FixedFile is a file modified by the fixes.
+type FixedFile struct {
+	Path string
+	Old  []byte
+	New  []byte
+}
+
+// Diff returns the unified diff of the fixes of the file.
+func (f *FixedFile) Diff() string {
+	return diff.Unified(f.Path, f.Path, string(f.Old), string(f.New))
+}
+
+// FixReport is the outcome of the fixes.
+type FixReport struct {
+	// Fixed are the findings whose fixes were applied.
+	Fixed []Finding
+	// Remaining are the findings left after the fixes.
+	Remaining []Finding
+	// Files are the modified files. They are not written by Fix.
+	Files []*FixedFile
+}
+
+// Fix lints the targets and applies the fixes of the findings in memory,
+// repeating until no fix applies, then formats the modified files with the
+// format function. Overlapping fixes are deferred to the next round.
+func (l *Linter) Fix(targets []Target, format func(path string, src []byte) ([]byte, error)) (*FixReport, error) {
+	report := &FixReport{}
+	original := map[string][]byte{}
+	l.overlay = map[string][]byte{}
+	defer func() { l.overlay, l.packages = nil, map[string]*source.Package{} }()
+	for round := 0; ; round++ {
+		l.packages = map[string]*source.Package{}
+		findings, err := l.Lint(targets)
+		if err != nil {
+			return nil, err
+		}
+		byFile := map[string][]Finding{}
+		for _, f := range findings {
+			if f.Fix != nil {
+				byFile[f.File] = append(byFile[f.File], f)
+			}
+		}
+		if len(byFile) == 0 || round == maxFixRounds {
+			report.Remaining = findings
+			break
+		}
+		for path, fs := range byFile {
+			src, ok := l.overlay[path]
+			if !ok {
+				if src, err = os.ReadFile(path); err != nil {
+					return nil, err
+				}
+				original[path] = src
+			}
+			applied, edits := nonOverlapping(fs)
+			l.overlay[path] = formatter.ApplyEdits(src, edits)
+			report.Fixed = append(report.Fixed, applied...)
+		}
+	}
+	paths := make([]string, 0, len(l.overlay))
+	for path := range l.overlay {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		src, err := format(path, l.overlay[path])
+		if err != nil {
+			return nil, fmt.Errorf("failed to format %s after the fixes: %w", path, err)
+		}
+		if string(src) != string(original[path]) {
+			report.Files = append(report.Files, &FixedFile{Path: path, Old: original[path], New: src})
+		}
+	}
+	for i := range report.Fixed {
+		report.Fixed[i].Fixed = true
+		report.Fixed[i].Fix = nil
+	}
+	return report, nil
+}
+
+// nonOverlapping returns the findings whose edits do not overlap the edits
+// of the findings before them, and their edits.
+func nonOverlapping(findings []Finding) ([]Finding, []formatter.TextEdit) {
+	before := func(a, b formatter.Position) bool {
+		return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+	}
+	var applied []Finding
+	var edits []formatter.TextEdit
+	for _, f := range findings {
+		ok := true
+		for _, e := range f.Fix.Edits {
+			for _, prev := range edits {
+				if before(e.Range.Start, prev.Range.End) && before(prev.Range.Start, e.Range.End) ||
+					e.Range.Start == prev.Range.Start {
+					ok = false
+				}
+			}
+		}
+		if ok {
+			applied = append(applied, f)
+			edits = append(edits, f.Fix.Edits...)
+		}
+	}
+	return applied, edits
+}
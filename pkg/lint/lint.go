@@ -0,0 +1,313 @@
+// Copyright The KCL Authors. All rights reserved.
+
+// Package lint implements the rule engine of `kcl lint`. Rules inspect the
+// structural view of KCL packages provided by the source package and report
+// findings, whose severity is configured per rule in the `[lint]` section of
+// kcl.mod. Findings are suppressed with `# kcl-lint: disable=RULE` comments.
+package lint
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"kcl-lang.io/cli/pkg/fs"
+	"kcl-lang.io/cli/pkg/source"
+)
+
+// Severity is the severity of a finding.
+type Severity int
+
+const (
+	// Off disables a rule.
+	Off Severity = iota
+	Info
+	Warning
+	Error
+)
+
+var severityNames = []string{"off", "info", "warning", "error"}
+
+// String returns the name of the severity.
+func (s Severity) String() string {
+	if s < Off || s > Error {
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+	return severityNames[s]
+}
+
+// ParseSeverity parses a severity name.
+func ParseSeverity(name string) (Severity, error) {
+	for i, n := range severityNames {
+		if strings.EqualFold(name, n) {
+			return Severity(i), nil
+		}
+	}
+	return Off, fmt.Errorf("invalid severity %q, must be one of %s", name, strings.Join(severityNames, ", "))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Severity) UnmarshalText(text []byte) error {
+	v, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+
+// Finding is a problem reported by a rule.
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	File     string   `json:"file"`
+	// Line is the 1-based line of the finding.
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+	// Fix is the automatic fix of the finding, if any.
+	Fix *Fix `json:"fix,omitempty"`
+	// Fixed reports whether the fix was applied.
+	Fixed bool `json:"fixed,omitempty"`
+}
+
+// String formats the finding as `file:line: severity: message (rule)`.
+func (f *Finding) String() string {
+	s := fmt.Sprintf("%s:%d: %s: %s (%s)", f.File, f.Line, f.Severity, f.Message, f.Rule)
+	if f.Fixed {
+		s += " [fixed]"
+	}
+	return s
+}
+
+// Rule is a lint rule.
+type Rule struct {
+	// ID is the rule identifier used in configs and suppression comments.
+	ID string
+	// Doc is a one line description of the rule.
+	Doc string
+	// Severity is the default severity of the rule.
+	Severity Severity
+	// Check inspects the package of the pass and reports findings.
+	Check func(p *Pass)
+}
+
+// Target is a package to lint.
+type Target struct {
+	// Dir is the package directory.
+	Dir string
+	// Files restricts the reported findings to these files of the package.
+	// All the files are reported when empty.
+	Files []string
+	// Config is the config of the package, such as the one of its module,
+	// replacing the config of the linter when set.
+	Config *Config
+}
+
+// Linter runs rules on packages.
+type Linter struct {
+	// Rules are the rules to run.
+	Rules []*Rule
+	// Config holds the rule severities and the excluded files.
+	Config *Config
+
+	packages map[string]*source.Package
+	// overlay holds the fixed sources replacing the file contents.
+	overlay map[string][]byte
+}
+
+// NewLinter returns a linter running the rules with the config. A nil
+// config keeps the default rule severities.
+func NewLinter(rules []*Rule, config *Config) *Linter {
+	if config == nil {
+		config = &Config{}
+	}
+	return &Linter{Rules: rules, Config: config, packages: map[string]*source.Package{}}
+}
+
+// Lint runs the enabled rules on the targets and returns the findings that
+// are not suppressed, sorted by file and line.
+func (l *Linter) Lint(targets []Target) ([]Finding, error) {
+	var findings []Finding
+	for _, t := range targets {
+		pkg, err := l.load(t.Dir)
+		if err != nil {
+			return nil, err
+		}
+		root := source.FindModRoot(t.Dir)
+		config := l.Config
+		if t.Config != nil {
+			config = t.Config
+		}
+		selected := map[string]bool{}
+		for _, f := range t.Files {
+			selected[filepath.Clean(f)] = true
+		}
+		for _, rule := range l.Rules {
+			severity := config.severity(rule)
+			if severity == Off {
+				continue
+			}
+			p := &Pass{Rule: rule, Package: pkg, Root: root, linter: l}
+			rule.Check(p)
+			if p.err != nil {
+				return nil, fmt.Errorf("rule %s failed: %w", rule.ID, p.err)
+			}
+			for _, f := range p.findings {
+				if len(selected) > 0 && !selected[filepath.Clean(f.File)] {
+					continue
+				}
+				excluded, err := excluded(config, root, f.File)
+				if err != nil {
+					return nil, err
+				}
+				if excluded || suppressed(pkg, &f) {
+					continue
+				}
+				f.Severity = severity
+				findings = append(findings, f)
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings, nil
+}
+
+func (l *Linter) load(dir string) (*source.Package, error) {
+	dir = filepath.Clean(dir)
+	if pkg, ok := l.packages[dir]; ok {
+		return pkg, nil
+	}
+	pkg, err := source.LoadPackage(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, files := range [][]*source.File{pkg.Files, pkg.TestFiles} {
+		for i, f := range files {
+			if src, ok := l.overlay[f.Path]; ok {
+				if files[i], err = source.ParseFile(f.Path, src); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	l.packages[dir] = pkg
+	return pkg, nil
+}
+
+func excluded(config *Config, root, file string) (bool, error) {
+	if len(config.Exclude) == 0 {
+		return false, nil
+	}
+	if abs, err := filepath.Abs(file); err == nil {
+		if rel, err := filepath.Rel(root, abs); err == nil && !strings.HasPrefix(rel, "..") {
+			file = rel
+		}
+	}
+	return fs.MatchAnyPattern(file, config.Exclude)
+}
+
+// Pass is the execution of a rule on a package.
+type Pass struct {
+	Rule    *Rule
+	Package *source.Package
+	// Root is the module root used to resolve local imports.
+	Root string
+
+	linter   *Linter
+	findings []Finding
+	err      error
+}
+
+// Files returns the source and test files of the package.
+func (p *Pass) Files() []*source.File {
+	return append(append([]*source.File{}, p.Package.Files...), p.Package.TestFiles...)
+}
+
+// Reportf reports a finding at the line of the file.
+func (p *Pass) Reportf(f *source.File, line int, format string, args ...any) {
+	p.findings = append(p.findings, Finding{
+		Rule:    p.Rule.ID,
+		File:    f.Path,
+		Line:    line,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Fail aborts the lint with the error. Only the first error is kept.
+func (p *Pass) Fail(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// file returns the file of the package at path, which may be relative to the
+// package directory. An empty path is the first file of the package.
+func (p *Pass) file(path string) *source.File {
+	files := p.Files()
+	if len(files) == 0 {
+		return nil
+	}
+	if path == "" {
+		return files[0]
+	}
+	for _, f := range files {
+		if filepath.Clean(f.Path) == filepath.Clean(path) || filepath.Join(p.Package.Dir, path) == filepath.Clean(f.Path) {
+			return f
+		}
+	}
+	return nil
+}
+
+// SchemaRef is a schema together with the file and package defining it.
+type SchemaRef struct {
+	Schema *source.Schema
+	File   *source.File
+	Dir    string
+}
+
+// LookupSchema resolves a schema reference such as `App` or `app.App` used
+// in the file, searching the package of the file and its local imports.
+func (p *Pass) LookupSchema(f *source.File, ref string) *SchemaRef {
+	return p.linter.lookupSchema(p.Root, f, ref)
+}
+
+func (l *Linter) lookupSchema(root string, f *source.File, ref string) *SchemaRef {
+	dir := filepath.Dir(f.Path)
+	name := ref
+	if i := strings.LastIndex(ref, "."); i >= 0 {
+		alias := ref[:i]
+		name = ref[i+1:]
+		dir = ""
+		for _, imp := range f.Imports {
+			if imp.Name() == alias {
+				if target, ok := source.ResolveImport(root, filepath.Dir(f.Path), imp.Path); ok && fs.IsDir(target) {
+					dir = target
+				}
+			}
+		}
+		if dir == "" {
+			return nil
+		}
+	}
+	pkg, err := l.load(dir)
+	if err != nil {
+		return nil
+	}
+	for _, file := range append(append([]*source.File{}, pkg.Files...), pkg.TestFiles...) {
+		if s := file.Schema(name); s != nil {
+			return &SchemaRef{Schema: s, File: file, Dir: dir}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package lint
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"kcl-lang.io/cli/pkg/source"
+)
+
+func TestLint(t *testing.T) {
+	dir := filepath.Join("testdata", "app")
+	config, err := LoadConfig(filepath.Join(dir, "kcl.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Validate(Rules()); err != nil {
+		t.Fatal(err)
+	}
+	findings, err := NewLinter(Rules(), config).Lint([]Target{{Dir: dir}, {Dir: filepath.Join(dir, "gen")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range findings {
+		got = append(got, strings.TrimPrefix(f.String(), dir+string(filepath.Separator)))
+	}
+	want := []string{
+		`main.k:2: warning: import "regex" is not used (unused-import)`,
+		`main.k:5: warning: variable "_unused" is assigned but never used (unused-variable)`,
+		`main.k:8: info: schema App has no docstring (missing-docstring)`,
+		`main.k:9: warning: attribute "name" of schema App shadows the attribute of base schema Base (shadowed-attribute)`,
+		`main.k:10: warning: option("port") has no default value (option-without-default)`,
+		`main.k:14: error: schema name "quiet" should be PascalCase (naming-convention)`,
+		`main.k:19: warning: attribute "tag" of schema App is deprecated (version="1.2", reason="use labels") (deprecated-attribute)`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, findings[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"rule": "unused-import"`) || !strings.Contains(buf.String(), `"severity": "warning"`) {
+		t.Errorf("unexpected JSON output:\n%s", buf.String())
+	}
+}
+
+func TestLintTargetConfig(t *testing.T) {
+	dir := filepath.Join("testdata", "app")
+	config := &Config{}
+	config.SetSeverity("missing-docstring", Off)
+	// The config of the target replaces the one of the linter.
+	findings, err := NewLinter(Rules(), nil).Lint([]Target{{Dir: dir, Config: config}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range findings {
+		if f.Rule == "missing-docstring" {
+			t.Errorf("unexpected finding %s", f.String())
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	c := &Config{}
+	c.SetSeverity("no-such-rule", Error)
+	if err := c.Validate(Rules()); err == nil {
+		t.Error("expected an unknown rule error")
+	}
+	if _, err := ParseSeverity("fatal"); err == nil {
+		t.Error("expected an invalid severity error")
+	}
+}
+
+func TestCalls(t *testing.T) {
+	lines, _ := source.Scan([]byte("a = option(\"a\", \"int\", False, 1) + x.option(\"b\")\nb = option(\"b\",\n    help=\"x\")\n"))
+	var calls []*Call
+	for _, l := range lines {
+		calls = append(calls, Calls(l, "option")...)
+	}
+	if len(calls) != 2 || !calls[0].HasDefault() || calls[1].HasDefault() || calls[1].Line != 2 {
+		t.Errorf("unexpected calls: %+v", calls)
+	}
+}
+
+func TestFix(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.k")
+	src := "import yaml\nimport json\nimport math\n\nport = option(\"port\")  # port\nhost = option(\"host\",\n    help=\"the host\")\nx = json.encode({a = math.floor(1.5)})\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	identity := func(_ string, src []byte) ([]byte, error) { return src, nil }
+	report, err := NewLinter(Rules(), nil).Fix([]Target{{Dir: dir}}, identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Files) != 1 {
+		t.Fatalf("expected one fixed file, got %d", len(report.Files))
+	}
+	want := "import json\nimport math\n\nport = option(\"port\", default=None)  # port\nhost = option(\"host\",\n    help=\"the host\", default=None)\nx = json.encode({a = math.floor(1.5)})\n"
+	if got := string(report.Files[0].New); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	var rules []string
+	for _, f := range report.Fixed {
+		rules = append(rules, f.Rule)
+	}
+	sort.Strings(rules)
+	if want := []string{"option-without-default", "option-without-default", "unused-import"}; !reflect.DeepEqual(rules, want) {
+		t.Errorf("fixed rules: got %v, want %v", rules, want)
+	}
+	for _, f := range report.Remaining {
+		if f.Fix != nil {
+			t.Errorf("unexpected fixable finding left: %s", f.String())
+		}
+	}
+	// The fixes are not written.
+	if data, _ := os.ReadFile(path); string(data) != src {
+		t.Error("Fix must not write the files")
+	}
+}
+
+func TestCustomRules(t *testing.T) {
+	set, err := LoadCustomRules(filepath.Join("testdata", "rules"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, r := range set.Rules {
+		ids = append(ids, r.ID)
+	}
+	if want := []string{"schema_doc", "required_port"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("rules: got %v, want %v", ids, want)
+	}
+	spec := `{"definitions": {
+		"App": {"type": "object", "required": ["name", "port"], "properties": {
+			"name": {"type": "string", "description": "The app name."},
+			"port": {"type": "integer"},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}}
+		}},
+		"quiet": {"type": "object", "properties": {"host": {"type": "string"}}}
+	}}`
+	var view *View
+	set.Describe = func(pkg *source.Package) (*View, error) {
+		view, err = ViewFromOpenAPI([]byte(spec), pkg)
+		return view, err
+	}
+	set.Evaluate = func(files []string, program string) (string, error) {
+		if !strings.Contains(program, "required_port = required_port(_kcl_lint_view)") {
+			t.Errorf("unexpected program:\n%s", program)
+		}
+		return `{"kcl_lint_findings": {
+			"schema_doc": [
+				{"schema": "App", "message": "schema App has no docstring"},
+				{"schema": "quiet", "message": "schema quiet has no docstring"}
+			],
+			"required_port": [{"schema": "App", "attribute": "port", "message": "port is required"}]
+		}}`, nil
+	}
+	dir := filepath.Join("testdata", "app")
+	findings, err := NewLinter(set.Rules, nil).Lint([]Target{{Dir: dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range findings {
+		got = append(got, strings.TrimPrefix(f.String(), dir+string(filepath.Separator)))
+	}
+	want := []string{
+		`main.k:8: warning: schema App has no docstring (schema_doc)`,
+		`main.k:10: warning: port is required (required_port)`,
+		`main.k:14: warning: schema quiet has no docstring (schema_doc)`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	app := view.Schema("App")
+	if app == nil || len(app.Attributes) != 3 {
+		t.Fatalf("unexpected view: %+v", view)
+	}
+	if a := app.Attributes[0]; a.Name != "labels" || a.Type != "{str:str}" || a.Required {
+		t.Errorf("unexpected attribute: %+v", a)
+	}
+	if a := app.Attributes[1]; a.Name != "name" || a.Doc != "The app name." || !a.Required || a.Line != 9 {
+		t.Errorf("unexpected attribute: %+v", a)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Count returns the number of findings with at least the severity.
+func Count(findings []Finding, min Severity) int {
+	n := 0
+	for _, f := range findings {
+		if f.Severity >= min {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteText writes one finding per line followed by a summary.
+func WriteText(w io.Writer, findings []Finding) error {
+	for _, f := range findings {
+		if _, err := fmt.Fprintln(w, f.String()); err != nil {
+			return err
+		}
+	}
+	if len(findings) > 0 {
+		_, err := fmt.Fprintf(w, "%d finding(s): %d error(s), %d warning(s), %d info\n",
+			len(findings), Count(findings, Error), Count(findings, Warning)-Count(findings, Error),
+			len(findings)-Count(findings, Warning))
+		return err
+	}
+	return nil
+}
+
+// WriteJSON writes the findings as a JSON list.
+func WriteJSON(w io.Writer, findings []Finding) error {
+	if findings == nil {
+		findings = []Finding{}
+	}
+	data, err := json.MarshalIndent(findings, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// WriteRules writes the ID, default severity and description of the rules.
+func WriteRules(w io.Writer, rules []*Rule) error {
+	width := 0
+	for _, r := range rules {
+		width = max(width, len(r.ID))
+	}
+	for _, r := range rules {
+		if _, err := fmt.Fprintf(w, "%-*s  %-7s  %s\n", width, r.ID, r.Severity, r.Doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package lint
+
+import (
+	"regexp"
+	"strings"
+
+	"kcl-lang.io/cli/pkg/diff"
+	"kcl-lang.io/cli/pkg/formatter"
+	"kcl-lang.io/cli/pkg/source"
+)
+
+// Rules returns the built-in rules.
+func Rules() []*Rule {
+	return []*Rule{
+		UnusedImport,
+		ImportOrder,
+		UnusedVariable,
+		ShadowedAttribute,
+		MissingDocstring,
+		OptionWithoutDefault,
+		DeprecatedAttribute,
+		NamingConvention,
+	}
+}
+
+// UnusedImport reports imports that are not referenced in their file.
+var UnusedImport = &Rule{
+	ID:       "unused-import",
+	Doc:      "Imports must be referenced in the importing file.",
+	Severity: Warning,
+	Check: func(p *Pass) {
+		for _, f := range p.Files() {
+			refs := References(f)
+			for _, imp := range f.Imports {
+				if refs[imp.Name()] == 0 {
+					p.ReportFix(f, imp.Line, []formatter.TextEdit{DeleteLines(imp.Line, imp.Line)},
+						"import %q is not used", imp.Path)
+				}
+			}
+		}
+	},
+}
+
+// ImportOrder reports leading import blocks that are not sorted by path.
+// Blank line separated sections are sorted independently.
+var ImportOrder = &Rule{
+	ID:       "import-order",
+	Doc:      "Imports must be sorted by path.",
+	Severity: Info,
+	Check: func(p *Pass) {
+		for _, f := range p.Files() {
+			start, end := importBlock(f)
+			if start == 0 {
+				continue
+			}
+			block := strings.Join(diff.SplitLines(string(f.Src))[start-1:end], "")
+			if sorted := formatter.SortImports(block, false); sorted != block {
+				p.ReportFix(f, start, []formatter.TextEdit{ReplaceLines(start, end, sorted)},
+					"imports are not sorted")
+			}
+		}
+	},
+}
+
+// importBlock returns the line range of the first run of import statements,
+// or zeros when the file has no imports.
+func importBlock(f *source.File) (int, int) {
+	start, end := 0, 0
+	for _, st := range f.Statements {
+		if st.Kind != source.ImportStmt {
+			if start > 0 {
+				break
+			}
+			continue
+		}
+		if start == 0 {
+			start = st.Start
+		}
+		end = st.End
+	}
+	return start, end
+}
+
+// UnusedVariable reports private top-level variables that are never read.
+// Public variables are part of the package output and are never unused.
+var UnusedVariable = &Rule{
+	ID:       "unused-variable",
+	Doc:      "Private top-level variables must be referenced.",
+	Severity: Warning,
+	Check: func(p *Pass) {
+		refs := map[string]int{}
+		assigns := map[string]int{}
+		for _, f := range p.Files() {
+			for name, n := range References(f) {
+				refs[name] += n
+			}
+			for _, st := range f.Statements {
+				if st.Kind == source.AssignStmt {
+					assigns[st.Name]++
+				}
+			}
+		}
+		for _, f := range p.Files() {
+			for _, st := range f.Statements {
+				if st.Kind != source.AssignStmt || !strings.HasPrefix(st.Name, "_") || strings.Contains(st.Name, ".") {
+					continue
+				}
+				// Each assignment references its own target once.
+				if refs[st.Name] <= assigns[st.Name] {
+					p.Reportf(f, st.Start, "variable %q is assigned but never used", st.Name)
+				}
+			}
+		}
+	},
+}
+
+// ShadowedAttribute reports schema attributes redeclaring an attribute of a
+// base schema.
+var ShadowedAttribute = &Rule{
+	ID:       "shadowed-attribute",
+	Doc:      "Schema attributes must not redeclare the attributes of base schemas.",
+	Severity: Warning,
+	Check: func(p *Pass) {
+		for _, f := range p.Files() {
+			for _, s := range f.Schemas {
+				inherited := map[string]string{}
+				for _, base := range p.baseChain(f, s) {
+					for _, a := range base.Schema.Attributes {
+						if _, ok := inherited[a.Name]; !ok {
+							inherited[a.Name] = base.Schema.Name
+						}
+					}
+				}
+				for _, a := range s.Attributes {
+					if base, ok := inherited[a.Name]; ok {
+						p.Reportf(f, a.Line, "attribute %q of schema %s shadows the attribute of base schema %s", a.Name, s.Name, base)
+					}
+				}
+			}
+		}
+	},
+}
+
+// MissingDocstring reports public schemas without a docstring.
+var MissingDocstring = &Rule{
+	ID:       "missing-docstring",
+	Doc:      "Public schemas, mixins, protocols and rules must have a docstring.",
+	Severity: Info,
+	Check: func(p *Pass) {
+		for _, f := range p.Package.Files {
+			for _, s := range f.Schemas {
+				if s.Doc == "" && !strings.HasPrefix(s.Name, "_") {
+					p.Reportf(f, s.Line, "%s %s has no docstring", s.Keyword, s.Name)
+				}
+			}
+		}
+	},
+}
+
+// OptionWithoutDefault reports `option()` calls that neither set a default
+// nor are required.
+var OptionWithoutDefault = &Rule{
+	ID:       "option-without-default",
+	Doc:      "option() calls must set a default value or be required.",
+	Severity: Warning,
+	Check: func(p *Pass) {
+		for _, f := range p.Files() {
+			for _, l := range f.Lines {
+				for _, c := range Calls(l, "option") {
+					if c.HasDefault() {
+						continue
+					}
+					// option() returns None when unset, so None is a
+					// behavior preserving default.
+					insert := ", default=None"
+					if strings.HasSuffix(strings.TrimSpace(l.Text[:c.End-1]), ",") {
+						insert = "default=None"
+					}
+					pos := Position(f, l, c.End-1)
+					edit := formatter.TextEdit{Range: formatter.Range{Start: pos, End: pos}, NewText: insert}
+					p.ReportFix(f, c.Line, []formatter.TextEdit{edit},
+						"option(%s) has no default value", strings.TrimSpace(c.Args[0]))
+				}
+			}
+		}
+	},
+}
+
+// DeprecatedAttribute reports the use of attributes decorated with
+// `@deprecated` in schema instantiations.
+var DeprecatedAttribute = &Rule{
+	ID:       "deprecated-attribute",
+	Doc:      "Deprecated schema attributes must not be set.",
+	Severity: Warning,
+	Check: func(p *Pass) {
+		for _, f := range p.Files() {
+			for _, l := range f.Lines {
+				for _, inst := range Instantiations(l) {
+					ref := p.LookupSchema(f, inst.Schema)
+					if ref == nil {
+						continue
+					}
+					deprecated := map[string]*source.Decorator{}
+					for _, s := range append([]*SchemaRef{ref}, p.baseChain(ref.File, ref.Schema)...) {
+						for _, a := range s.Schema.Attributes {
+							if d := a.Decorator("deprecated"); d != nil {
+								deprecated[a.Name] = d
+							}
+						}
+					}
+					for _, e := range inst.Entries {
+						if d, ok := deprecated[e.Key]; ok {
+							msg := "attribute %q of schema %s is deprecated"
+							if d.Args != "" {
+								msg += " (" + d.Args + ")"
+							}
+							p.Reportf(f, e.Line, msg, e.Key, inst.Schema)
+						}
+					}
+				}
+			}
+		}
+	},
+}
+
+var (
+	schemaNameRe = regexp.MustCompile(`^_?[A-Z][A-Za-z0-9]*$`)
+	varNameRe    = regexp.MustCompile(`^[_$]*[a-z][A-Za-z0-9_]*$`)
+)
+
+// NamingConvention reports names that do not follow the KCL conventions:
+// PascalCase schemas, mixins ending with Mixin, protocols ending with
+// Protocol, and variables, lambdas and import aliases starting with a lower
+// case letter.
+var NamingConvention = &Rule{
+	ID:       "naming-convention",
+	Doc:      "Names must follow the KCL naming conventions.",
+	Severity: Info,
+	Check: func(p *Pass) {
+		for _, f := range p.Files() {
+			for _, s := range f.Schemas {
+				switch {
+				case !schemaNameRe.MatchString(s.Name):
+					p.Reportf(f, s.Line, "%s name %q should be PascalCase", s.Keyword, s.Name)
+				case s.Keyword == "mixin" && !strings.HasSuffix(s.Name, "Mixin"):
+					p.Reportf(f, s.Line, "mixin name %q should end with Mixin", s.Name)
+				case s.Keyword == "protocol" && !strings.HasSuffix(s.Name, "Protocol"):
+					p.Reportf(f, s.Line, "protocol name %q should end with Protocol", s.Name)
+				}
+			}
+			for _, imp := range f.Imports {
+				if imp.Alias != "" && !varNameRe.MatchString(imp.Alias) {
+					p.Reportf(f, imp.Line, "import alias %q should start with a lower case letter", imp.Alias)
+				}
+			}
+			for _, st := range f.Statements {
+				if (st.Kind == source.AssignStmt || st.Kind == source.LambdaStmt) && !strings.Contains(st.Name, ".") && !varNameRe.MatchString(st.Name) {
+					p.Reportf(f, st.Start, "%s name %q should start with a lower case letter", st.Kind, st.Name)
+				}
+			}
+		}
+	},
+}
+
+// baseChain returns the base schemas of the schema, closest first.
+func (p *Pass) baseChain(f *source.File, s *source.Schema) []*SchemaRef {
+	var chain []*SchemaRef
+	seen := map[*source.Schema]bool{s: true}
+	for s.Base != "" {
+		base := p.LookupSchema(f, s.Base)
+		if base == nil || seen[base.Schema] {
+			break
+		}
+		seen[base.Schema] = true
+		chain = append(chain, base)
+		f, s = base.File, base.Schema
+	}
+	return chain
+}
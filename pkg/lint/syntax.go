@@ -0,0 +1,238 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package lint
+
+import (
+	"regexp"
+	"strings"
+
+	"kcl-lang.io/cli/pkg/source"
+)
+
+// References counts the references to each top-level name in the file,
+// excluding import statements. Dotted references such as `app.App` count as
+// references to their first name.
+func References(f *source.File) map[string]int {
+	refs := map[string]int{}
+	for _, l := range f.Lines {
+		if strings.HasPrefix(l.Text, "import ") {
+			continue
+		}
+		for _, id := range source.Idents(l.Text) {
+			root, _, _ := strings.Cut(id, ".")
+			refs[root]++
+		}
+	}
+	return refs
+}
+
+// Call is a function call found in a logical line.
+type Call struct {
+	// Line is the 1-based line of the function name.
+	Line int
+	// Start and End are the offsets of the call in the logical line text,
+	// from the function name to the closing parenthesis included.
+	Start int
+	End   int
+	// Args are the raw arguments of the call.
+	Args []string
+}
+
+var (
+	kwargRe    = regexp.MustCompile(`^([$\w]+)\s*=[^=]`)
+	defaultRe  = regexp.MustCompile(`^default\s*=[^=]`)
+	requiredRe = regexp.MustCompile(`^required\s*=\s*True\b`)
+)
+
+// HasDefault reports whether an option() call sets a default value, either
+// with the `default` keyword or as the fourth positional argument, or is
+// required.
+func (c *Call) HasDefault() bool {
+	positional := 0
+	for _, a := range c.Args {
+		a = strings.TrimSpace(a)
+		switch {
+		case defaultRe.MatchString(a), requiredRe.MatchString(a):
+			return true
+		case !kwargRe.MatchString(a):
+			positional++
+		}
+	}
+	return positional >= 4
+}
+
+// Calls returns the calls of the named function in the logical line.
+// Method calls such as `x.name()` are ignored.
+func Calls(l source.Line, name string) []*Call {
+	var calls []*Call
+	text := l.Text
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c == '"' || c == '\'' {
+			i = source.SkipString(text, i) - 1
+			continue
+		}
+		if !strings.HasPrefix(text[i:], name) || (i > 0 && (isIdentChar(text[i-1]) || text[i-1] == '.')) {
+			continue
+		}
+		j := i + len(name)
+		for j < len(text) && text[j] == ' ' {
+			j++
+		}
+		if j >= len(text) || text[j] != '(' {
+			continue
+		}
+		end := matching(text, j)
+		if end < 0 {
+			continue
+		}
+		var args []string
+		for _, a := range source.SplitTop(text[j+1:end], ",") {
+			if strings.TrimSpace(a) != "" {
+				args = append(args, a)
+			}
+		}
+		if len(args) > 0 {
+			calls = append(calls, &Call{
+				Line:  l.Start + strings.Count(text[:i], "\n"),
+				Start: i,
+				End:   end + 1,
+				Args:  args,
+			})
+		}
+		i = end
+	}
+	return calls
+}
+
+// Instantiation is a schema instantiation `Schema {...}` found in a logical
+// line.
+type Instantiation struct {
+	// Schema is the schema reference, e.g. `App` or `app.App`.
+	Schema string
+	// Entries are the top-level entries of the config.
+	Entries []*Entry
+}
+
+// Entry is an entry `key = value` of a config.
+type Entry struct {
+	Key string
+	// Line is the 1-based line of the entry.
+	Line int
+}
+
+var entryKeyRe = regexp.MustCompile(`^([$\w]+)\s*(?:\+=|=|:)`)
+
+// Instantiations returns the schema instantiations of the logical line,
+// including nested ones.
+func Instantiations(l source.Line) []*Instantiation {
+	var insts []*Instantiation
+	text := l.Text
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c == '"' || c == '\'' {
+			i = source.SkipString(text, i) - 1
+			continue
+		}
+		if c != '{' {
+			continue
+		}
+		ref := schemaRefBefore(text[:i])
+		end := matching(text, i)
+		if ref == "" || end < 0 {
+			continue
+		}
+		inst := &Instantiation{Schema: ref}
+		start := i + 1
+		for _, seg := range splitEntries(text[start:end]) {
+			if m := entryKeyRe.FindStringSubmatch(strings.TrimSpace(text[start+seg[0] : start+seg[1]])); m != nil {
+				lead := len(text[start+seg[0]:start+seg[1]]) - len(strings.TrimLeft(text[start+seg[0]:start+seg[1]], " \t\n"))
+				inst.Entries = append(inst.Entries, &Entry{
+					Key:  m[1],
+					Line: l.Start + strings.Count(text[:start+seg[0]+lead], "\n"),
+				})
+			}
+		}
+		insts = append(insts, inst)
+	}
+	return insts
+}
+
+// schemaRefBefore returns the schema reference preceding a `{`, skipping
+// the schema arguments, or "" when the brace does not start an
+// instantiation.
+func schemaRefBefore(text string) string {
+	text = strings.TrimRight(text, " \t\n")
+	if strings.HasSuffix(text, ")") {
+		depth := 0
+		for i := len(text) - 1; i >= 0; i-- {
+			if text[i] == ')' {
+				depth++
+			} else if text[i] == '(' {
+				if depth--; depth == 0 {
+					text = strings.TrimRight(text[:i], " \t\n")
+					break
+				}
+			}
+		}
+	}
+	i := len(text)
+	for i > 0 && (isIdentChar(text[i-1]) || text[i-1] == '.') {
+		i--
+	}
+	ref := text[i:]
+	if ref == "" || !(ref[0] == '_' || (ref[0] >= 'A' && ref[0] <= 'Z') || (ref[0] >= 'a' && ref[0] <= 'z')) {
+		return ""
+	}
+	before := strings.TrimRight(text[:i], " \t\n")
+	if strings.HasSuffix(before, "->") || strings.HasSuffix(before, "lambda") || strings.HasSuffix(before, ":") {
+		// Lambda return types and parameters.
+		return ""
+	}
+	return ref
+}
+
+// splitEntries returns the offsets of the comma or newline separated
+// top-level segments of a config body.
+func splitEntries(text string) [][2]int {
+	var segs [][2]int
+	depth, last := 0, 0
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; c {
+		case '"', '\'':
+			i = source.SkipString(text, i) - 1
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case ',', '\n':
+			if depth == 0 {
+				segs = append(segs, [2]int{last, i})
+				last = i + 1
+			}
+		}
+	}
+	return append(segs, [2]int{last, len(text)})
+}
+
+// matching returns the index of the bracket closing the one at open, or -1.
+func matching(text string, open int) int {
+	depth := 0
+	for i := open; i < len(text); i++ {
+		switch text[i] {
+		case '"', '\'':
+			i = source.SkipString(text, i) - 1
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
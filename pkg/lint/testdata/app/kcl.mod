@@ -0,0 +1,9 @@
+[package]
+name = "app"
+version = "0.1.0"
+
+[lint]
+exclude = ["gen/*"]
+
+[lint.rules]
+naming-convention = "error"
@@ -0,0 +1,109 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package mod
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-git/go-git/v5"
+	"github.com/goccy/go-yaml"
+)
+
+// IndexMetadataFile is the Artifact Hub metadata file of the modules of an
+// index, whose `createdAt` date is the date they were published.
+const IndexMetadataFile = "artifacthub-pkg.yaml"
+
+// GitIndex is a Git repository indexing modules, in a directory per module
+// containing its kcl.mod file, such as https://github.com/kcl-lang/modules.
+type GitIndex struct {
+	// URL is the URL of the repository.
+	URL string
+	// CacheDir is the directory of the clones of the indexes.
+	CacheDir string
+}
+
+// Search returns the modules of the index whose name contains the query. The
+// clone of the index is updated first.
+func (g *GitIndex) Search(query string) ([]*Module, error) {
+	dir, err := g.sync()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update the index %s: %w", g.URL, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var modules []*Module
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		var mod struct {
+			Package struct {
+				Name        string `toml:"name"`
+				Version     string `toml:"version"`
+				Description string `toml:"description"`
+			} `toml:"package"`
+		}
+		if _, err := toml.DecodeFile(filepath.Join(dir, e.Name(), ModFile), &mod); err != nil {
+			continue
+		}
+		name := mod.Package.Name
+		if name == "" {
+			name = e.Name()
+		}
+		if !matches(name, query) {
+			continue
+		}
+		// The publication date is left empty when the metadata is missing.
+		var metadata struct {
+			CreatedAt string `yaml:"createdAt"`
+		}
+		if data, err := os.ReadFile(filepath.Join(dir, e.Name(), IndexMetadataFile)); err == nil {
+			yaml.Unmarshal(data, &metadata)
+		}
+		modules = append(modules, &Module{
+			Name:        name,
+			Version:     mod.Package.Version,
+			Description: mod.Package.Description,
+			Published:   metadata.CreatedAt,
+			Source:      g.URL,
+		})
+	}
+	return modules, nil
+}
+
+// sync clones the index to the cache directory, or pulls it when already
+// cloned, and returns the directory of the clone.
+func (g *GitIndex) sync() (string, error) {
+	sum := sha256.Sum256([]byte(g.URL))
+	dir := filepath.Join(g.CacheDir, hex.EncodeToString(sum[:8]))
+	repo, err := git.PlainOpen(dir)
+	if err == nil {
+		var wt *git.Worktree
+		if wt, err = repo.Worktree(); err == nil {
+			err = wt.Pull(&git.PullOptions{Depth: 1, Force: true})
+		}
+		if errors.Is(err, git.NoErrAlreadyUpToDate) {
+			err = nil
+		}
+	}
+	if err != nil {
+		// Clone again the missing or broken clones.
+		if err := os.RemoveAll(dir); err != nil {
+			return "", err
+		}
+		if _, err := git.PlainClone(dir, false, &git.CloneOptions{URL: g.URL, Depth: 1}); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	return dir, nil
+}
@@ -0,0 +1,277 @@
+// Copyright The KCL Authors. All rights reserved.
+
+// Package mod edits the kcl.mod and kcl.mod.lock files of KCL modules in
+// place, keeping their layout and comments.
+package mod
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	// ModFile is the manifest file name of a KCL module.
+	ModFile = "kcl.mod"
+	// LockFile is the lock file name of a KCL module.
+	LockFile = "kcl.mod.lock"
+	// depsTable is the table of the dependencies in both files.
+	depsTable = "dependencies"
+)
+
+// Dep is a dependency declared in a kcl.mod file.
+type Dep struct {
+	Name string
+	// Path is the local path of the dependency, relative to the module, if
+	// it is a local dependency.
+	Path string
+}
+
+// LockedDep is a dependency recorded in a kcl.mod.lock file.
+type LockedDep struct {
+	Name string `toml:"name"`
+	// FullName is the name of the directory of the downloaded dependency,
+	// such as `k8s_1.28`.
+	FullName string `toml:"full_name"`
+	Version  string `toml:"version"`
+}
+
+// ReadDeps returns the dependencies declared in the kcl.mod file of the
+// module in dir, sorted by name.
+func ReadDeps(dir string) ([]Dep, error) {
+	var mod struct {
+		Dependencies map[string]any `toml:"dependencies"`
+	}
+	if _, err := toml.DecodeFile(filepath.Join(dir, ModFile), &mod); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Join(dir, ModFile), err)
+	}
+	deps := make([]Dep, 0, len(mod.Dependencies))
+	for name, v := range mod.Dependencies {
+		dep := Dep{Name: name}
+		if spec, ok := v.(map[string]any); ok {
+			dep.Path, _ = spec["path"].(string)
+		}
+		deps = append(deps, dep)
+	}
+	sort.Slice(deps, func(i, j int) bool { return deps[i].Name < deps[j].Name })
+	return deps, nil
+}
+
+// ReadLock returns the dependencies recorded in the kcl.mod.lock file of the
+// module in dir by name, and no dependencies when there is no lock file.
+func ReadLock(dir string) (map[string]LockedDep, error) {
+	var lock struct {
+		Dependencies map[string]LockedDep `toml:"dependencies"`
+	}
+	_, err := toml.DecodeFile(filepath.Join(dir, LockFile), &lock)
+	if os.IsNotExist(err) {
+		return map[string]LockedDep{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Join(dir, LockFile), err)
+	}
+	if lock.Dependencies == nil {
+		lock.Dependencies = map[string]LockedDep{}
+	}
+	return lock.Dependencies, nil
+}
+
+// removeEntries returns the TOML document without the keys of the table,
+// written either as `key = value` entries of the table, whose values may span
+// several lines, or as `[table.key]` sub-tables. The document is decoded
+// again to make sure that the removal kept it valid.
+func removeEntries(data []byte, table string, keys []string) ([]byte, error) {
+	remove := map[string]bool{}
+	for _, k := range keys {
+		remove[k] = true
+	}
+	var out bytes.Buffer
+	current, skipping := "", false
+	// The state of the value of the entry spanning several lines, if any.
+	var value *valueScanner
+	skippingValue := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if value != nil {
+			if value.scan(line) {
+				value = nil
+			}
+			if !skipping && !skippingValue {
+				out.WriteString(line)
+				out.WriteByte('\n')
+			}
+			continue
+		}
+		skippingValue = false
+		if strings.HasPrefix(trimmed, "[") {
+			current = header(trimmed)
+			key, ok := strings.CutPrefix(current, table+".")
+			skipping = ok && remove[unquote(key)]
+		} else if key, v, ok := strings.Cut(trimmed, "="); ok && !strings.HasPrefix(trimmed, "#") {
+			value = &valueScanner{}
+			if value.scan(v) {
+				value = nil
+			}
+			if !skipping && current == table && remove[unquote(strings.TrimSpace(key))] {
+				skippingValue = true
+				continue
+			}
+		}
+		if !skipping {
+			out.WriteString(line)
+			out.WriteByte('\n')
+		}
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' && out.Len() > 0 {
+		out.Truncate(out.Len() - 1)
+	}
+	var doc map[string]any
+	if _, err := toml.Decode(out.String(), &doc); err != nil {
+		return nil, fmt.Errorf("failed to remove the entries of [%s]: %w", table, err)
+	}
+	if entries, ok := doc[table].(map[string]any); ok {
+		for _, k := range keys {
+			if _, ok := entries[k]; ok {
+				return nil, fmt.Errorf("failed to remove the entry '%s' of [%s]", k, table)
+			}
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// valueScanner scans the lines of a TOML value, to find the line where it
+// ends.
+type valueScanner struct {
+	// depth is the number of open arrays and inline tables.
+	depth int
+	// multiline is the delimiter of the open multi-line string, if any.
+	multiline string
+}
+
+// scan scans a line of the value, and reports whether the value ends on it.
+func (v *valueScanner) scan(line string) bool {
+	for i := 0; i < len(line); i++ {
+		if v.multiline != "" {
+			if strings.HasPrefix(line[i:], v.multiline) && (v.multiline == "'''" || !escaped(line, i)) {
+				i += len(v.multiline) - 1
+				v.multiline = ""
+			}
+			continue
+		}
+		switch c := line[i]; c {
+		case '#':
+			return v.depth <= 0
+		case '[', '{':
+			v.depth++
+		case ']', '}':
+			v.depth--
+		case '"', '\'':
+			if delim := strings.Repeat(string(c), 3); strings.HasPrefix(line[i:], delim) {
+				v.multiline = delim
+				i += 2
+				continue
+			}
+			// Skip the single-line string.
+			for i++; i < len(line) && (line[i] != c || c == '"' && escaped(line, i)); i++ {
+			}
+		}
+	}
+	return v.depth <= 0 && v.multiline == ""
+}
+
+// escaped reports whether the character at i is escaped by an odd number of
+// backslashes.
+func escaped(line string, i int) bool {
+	n := 0
+	for j := i - 1; j >= 0 && line[j] == '\\'; j-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// header returns the name of the table of a `[name]` or `[[name]]` header
+// line, without its trailing comment.
+func header(line string) string {
+	name := strings.TrimLeft(line, "[")
+	if i := strings.Index(name, "]"); i >= 0 {
+		name = name[:i]
+	}
+	return strings.TrimSpace(name)
+}
+
+func unquote(key string) string {
+	if s, err := strconv.Unquote(key); err == nil {
+		return s
+	}
+	return strings.Trim(key, "'")
+}
+
+// writeFiles replaces the contents of the files, all or none: the contents
+// are written to temp files first, which are then renamed over the files.
+// When a rename fails, the files already replaced are restored.
+func writeFiles(files map[string][]byte) error {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	temps := map[string]string{}
+	cleanup := func() {
+		for _, t := range temps {
+			os.Remove(t)
+		}
+	}
+	for _, p := range paths {
+		mode := os.FileMode(0644)
+		if fi, err := os.Stat(p); err == nil {
+			mode = fi.Mode().Perm()
+		}
+		tmp, err := os.CreateTemp(filepath.Dir(p), "."+filepath.Base(p)+".*")
+		if err != nil {
+			cleanup()
+			return err
+		}
+		temps[p] = tmp.Name()
+		_, err = tmp.Write(files[p])
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chmod(tmp.Name(), mode)
+		}
+		if err != nil {
+			cleanup()
+			return fmt.Errorf("failed to write %s: %w", p, err)
+		}
+	}
+	originals := map[string][]byte{}
+	for _, p := range paths {
+		if data, err := os.ReadFile(p); err == nil {
+			originals[p] = data
+		}
+	}
+	for i, p := range paths {
+		if err := os.Rename(temps[p], p); err != nil {
+			cleanup()
+			for _, done := range paths[:i] {
+				if data, ok := originals[done]; ok {
+					os.WriteFile(done, data, 0644)
+				} else {
+					os.Remove(done)
+				}
+			}
+			return fmt.Errorf("failed to write %s: %w", p, err)
+		}
+		delete(temps, p)
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package mod
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"kcl-lang.io/cli/pkg/source"
+)
+
+// RemoveOptions are the options of Remove.
+type RemoveOptions struct {
+	// Path is the root directory of the module.
+	Path string
+	// Home is the directory of the downloaded dependencies, in which the
+	// manifests of the locked dependencies are looked up.
+	Home string
+	// Names are the names of the dependencies to remove.
+	Names []string
+	// Force removes the dependencies even if they are still imported by the
+	// sources of the module.
+	Force bool
+}
+
+// RemoveResult is the result of Remove.
+type RemoveResult struct {
+	// Removed are the dependencies removed from kcl.mod.
+	Removed []string
+	// Pruned are the dependencies removed from kcl.mod.lock, including the
+	// transitive dependencies no longer required.
+	Pruned []string
+	// Complete reports whether the manifests of all the remaining
+	// dependencies were found. Otherwise, the transitive dependencies are not
+	// pruned, as the ones still required are unknown.
+	Complete bool
+	// Skipped are the errors of the source files which could not be searched
+	// for imports, such as for syntax errors.
+	Skipped []error
+}
+
+// Remove removes the dependencies from the kcl.mod and kcl.mod.lock files of
+// the module at once, and prunes the locked dependencies no longer required
+// by the remaining ones.
+func Remove(o *RemoveOptions) (*RemoveResult, error) {
+	modPath := filepath.Join(o.Path, ModFile)
+	modData, err := os.ReadFile(modPath)
+	if err != nil {
+		return nil, err
+	}
+	deps, err := ReadDeps(o.Path)
+	if err != nil {
+		return nil, err
+	}
+	declared := map[string]bool{}
+	for _, d := range deps {
+		declared[d.Name] = true
+	}
+	removing := map[string]bool{}
+	for _, name := range o.Names {
+		if !declared[name] {
+			return nil, fmt.Errorf("dependency '%s' not found in %s", name, ModFile)
+		}
+		removing[name] = true
+	}
+	result := &RemoveResult{Removed: removingNames(o.Names)}
+	if !o.Force {
+		importers, skipped, err := Importers(o.Path, o.Names)
+		if err != nil {
+			return nil, err
+		}
+		result.Skipped = skipped
+		var used []string
+		for _, name := range o.Names {
+			if positions := importers[name]; len(positions) > 0 {
+				used = append(used, fmt.Sprintf("'%s' is imported by %s", name, strings.Join(positions, ", ")))
+			}
+		}
+		if len(used) > 0 {
+			return nil, fmt.Errorf("dependencies still in use, use --force to remove them:\n  %s", strings.Join(used, "\n  "))
+		}
+	}
+
+	modData, err = removeEntries(modData, depsTable, result.Removed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update %s: %w", modPath, err)
+	}
+	files := map[string][]byte{modPath: modData}
+
+	lockPath := filepath.Join(o.Path, LockFile)
+	if lockData, err := os.ReadFile(lockPath); err == nil {
+		lock, err := ReadLock(o.Path)
+		if err != nil {
+			return nil, err
+		}
+		var remaining []Dep
+		for _, d := range deps {
+			if !removing[d.Name] {
+				remaining = append(remaining, d)
+			}
+		}
+		var required map[string]bool
+		required, result.Complete = Required(o.Path, o.Home, remaining, lock)
+		for name := range lock {
+			if result.Complete && !required[name] || !result.Complete && removing[name] {
+				result.Pruned = append(result.Pruned, name)
+			}
+		}
+		sort.Strings(result.Pruned)
+		if files[lockPath], err = removeEntries(lockData, depsTable, result.Pruned); err != nil {
+			return nil, fmt.Errorf("failed to update %s: %w", lockPath, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	} else {
+		result.Complete = true
+	}
+	if err := writeFiles(files); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func removingNames(names []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Required returns the names of the locked dependencies required by the
+// dependencies of the module in root, transitively. The manifests of the
+// dependencies are found at their local paths, in the vendor directory of the
+// module or in home. It reports false when one of them is not found.
+func Required(root, home string, deps []Dep, lock map[string]LockedDep) (map[string]bool, bool) {
+	type item struct {
+		dep Dep
+		dir string
+	}
+	required := map[string]bool{}
+	complete := true
+	var queue []item
+	for _, d := range deps {
+		queue = append(queue, item{d, root})
+	}
+	for len(queue) > 0 {
+		it := queue[0]
+		queue = queue[1:]
+		if required[it.dep.Name] {
+			continue
+		}
+		required[it.dep.Name] = true
+		dir, ok := depDir(root, home, it.dir, it.dep, lock)
+		if !ok {
+			complete = false
+			continue
+		}
+		children, err := ReadDeps(dir)
+		if err != nil {
+			complete = false
+			continue
+		}
+		for _, c := range children {
+			queue = append(queue, item{c, dir})
+		}
+	}
+	return required, complete
+}
+
+// depDir returns the directory of a dependency declared by the module in
+// parent.
+func depDir(root, home, parent string, dep Dep, lock map[string]LockedDep) (string, bool) {
+	var candidates []string
+	if dep.Path != "" {
+		p := dep.Path
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(parent, p)
+		}
+		candidates = append(candidates, p)
+	} else if locked, ok := lock[dep.Name]; ok && locked.FullName != "" {
+		candidates = append(candidates, filepath.Join(root, "vendor", locked.FullName))
+		if home != "" {
+			candidates = append(candidates, filepath.Join(home, locked.FullName))
+		}
+	}
+	for _, dir := range candidates {
+		if _, err := os.Stat(filepath.Join(dir, ModFile)); err == nil {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// Importers returns the `file:line` positions of the imports of the
+// dependencies in the KCL files of the module in root, by dependency name.
+// The files of the vendor directory and of nested modules are not searched.
+// The files which cannot be parsed are skipped, and returned as errors.
+func Importers(root string, names []string) (map[string][]string, []error, error) {
+	modules := map[string]string{}
+	for _, name := range names {
+		// Dependency names are imported with underscores, such as
+		// `import my_dep` for `my-dep`.
+		modules[strings.ReplaceAll(name, "-", "_")] = name
+		modules[name] = name
+	}
+	importers := map[string][]string{}
+	var skipped []error
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == root {
+				return nil
+			}
+			if strings.HasPrefix(d.Name(), ".") || d.Name() == "vendor" {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, ModFile)); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !source.IsKclFile(path) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			rel = path
+		}
+		f, err := source.ReadFile(path)
+		if err != nil {
+			skipped = append(skipped, fmt.Errorf("skipped %s: %w", filepath.ToSlash(rel), err))
+			return nil
+		}
+		for _, imp := range f.Imports {
+			if strings.HasPrefix(imp.Path, ".") {
+				continue
+			}
+			first, _, _ := strings.Cut(imp.Path, ".")
+			if name, ok := modules[first]; ok {
+				importers[name] = append(importers[name], fmt.Sprintf("%s:%d", filepath.ToSlash(rel), imp.Line))
+			}
+		}
+		return nil
+	})
+	return importers, skipped, err
+}
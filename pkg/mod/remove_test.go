@@ -0,0 +1,265 @@
+package mod
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testModFile = `[package]
+name = "app"
+version = "0.0.1"
+
+[dependencies]
+# The kubernetes models.
+k8s = "1.28"
+helloworld = { oci = "oci://ghcr.io/kcl-lang/helloworld", tag = "0.1.0" }
+"my-lib" = { path = "../my-lib" }
+`
+
+const testLockFile = `[dependencies]
+  [dependencies.helloworld]
+    name = "helloworld"
+    full_name = "helloworld_0.1.0"
+    version = "0.1.0"
+  [dependencies.k8s]
+    name = "k8s"
+    full_name = "k8s_1.28"
+    version = "1.28"
+  [dependencies.konfig]
+    name = "konfig"
+    full_name = "konfig_0.4.0"
+    version = "0.4.0"
+  [dependencies.my-lib]
+    name = "my-lib"
+    full_name = "my-lib_0.0.1"
+    version = "0.0.1"
+`
+
+// setup writes the app module, the local my-lib module requiring k8s and the
+// downloaded helloworld module requiring konfig.
+func setup(t *testing.T, mainFile string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	home := filepath.Join(dir, "home")
+	files := map[string]string{
+		"app/kcl.mod":                   testModFile,
+		"app/kcl.mod.lock":              testLockFile,
+		"app/main.k":                    mainFile,
+		"my-lib/kcl.mod":                "[package]\nname = \"my-lib\"\n\n[dependencies]\nk8s = \"1.28\"\n",
+		"home/helloworld_0.1.0/kcl.mod": "[package]\nname = \"helloworld\"\n\n[dependencies]\nkonfig = \"0.4.0\"\n",
+		"home/konfig_0.4.0/kcl.mod":     "[package]\nname = \"konfig\"\n",
+		"home/k8s_1.28/kcl.mod":         "[package]\nname = \"k8s\"\n",
+		"app/vendor/unused/main.k":      "import helloworld\n",
+		"app/nested/kcl.mod":            "[package]\nname = \"nested\"\n",
+		"app/nested/main.k":             "import helloworld\n",
+		"app/.hidden/main.k":            "import helloworld\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, "app"), home
+}
+
+func TestRemove(t *testing.T) {
+	cases := []struct {
+		name     string
+		main     string
+		home     bool
+		names    []string
+		force    bool
+		err      string
+		removed  []string
+		pruned   []string
+		complete bool
+		skipped  int
+	}{
+		{
+			name:     "prune transitive",
+			main:     "import k8s.api.core.v1\n",
+			home:     true,
+			names:    []string{"helloworld"},
+			removed:  []string{"helloworld"},
+			pruned:   []string{"helloworld", "konfig"},
+			complete: true,
+		},
+		{
+			name:     "keep shared",
+			main:     "import helloworld\n",
+			home:     true,
+			names:    []string{"k8s"},
+			removed:  []string{"k8s"},
+			complete: true,
+		},
+		{
+			name:     "hyphenated name",
+			main:     "",
+			home:     true,
+			names:    []string{"my-lib", "k8s"},
+			removed:  []string{"k8s", "my-lib"},
+			pruned:   []string{"k8s", "my-lib"},
+			complete: true,
+		},
+		{
+			name:  "still imported",
+			main:  "import my_lib\nimport helloworld as hw\n",
+			home:  true,
+			names: []string{"my-lib", "helloworld", "k8s"},
+			err:   "'my-lib' is imported by main.k:1\n  'helloworld' is imported by main.k:2",
+		},
+		{
+			name:     "force",
+			main:     "import helloworld\n",
+			home:     true,
+			names:    []string{"helloworld"},
+			force:    true,
+			removed:  []string{"helloworld"},
+			pruned:   []string{"helloworld", "konfig"},
+			complete: true,
+		},
+		{
+			name:     "syntax error",
+			main:     "import helloworld\nschema {\n",
+			home:     true,
+			names:    []string{"helloworld"},
+			removed:  []string{"helloworld"},
+			pruned:   []string{"helloworld", "konfig"},
+			complete: true,
+			skipped:  1,
+		},
+		{
+			name:    "not downloaded",
+			main:    "",
+			names:   []string{"helloworld"},
+			removed: []string{"helloworld"},
+			pruned:  []string{"helloworld"},
+		},
+		{
+			name:  "unknown",
+			names: []string{"konfig"},
+			err:   "dependency 'konfig' not found in kcl.mod",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			root, home := setup(t, c.main)
+			if !c.home {
+				home = filepath.Join(t.TempDir(), "empty")
+			}
+			result, err := Remove(&RemoveOptions{Path: root, Home: home, Names: c.names, Force: c.force})
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("expected error %q, got %v", c.err, err)
+				}
+				if data, _ := os.ReadFile(filepath.Join(root, ModFile)); string(data) != testModFile {
+					t.Errorf("kcl.mod changed on error:\n%s", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result.Removed, c.removed) || !reflect.DeepEqual(result.Pruned, c.pruned) || result.Complete != c.complete || len(result.Skipped) != c.skipped {
+				t.Errorf("got %+v", result)
+			}
+			deps, err := ReadDeps(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, d := range deps {
+				for _, name := range c.removed {
+					if d.Name == name {
+						t.Errorf("%s still in kcl.mod", name)
+					}
+				}
+			}
+			lock, err := ReadLock(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lock) != 4-len(c.pruned) {
+				t.Errorf("unexpected lock entries %v", lock)
+			}
+			for _, name := range c.pruned {
+				if _, ok := lock[name]; ok {
+					t.Errorf("%s still in kcl.mod.lock", name)
+				}
+			}
+		})
+	}
+}
+
+func TestRemoveEntries(t *testing.T) {
+	got, err := removeEntries([]byte(testModFile), depsTable, []string{"helloworld", "my-lib"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[package]
+name = "app"
+version = "0.0.1"
+
+[dependencies]
+# The kubernetes models.
+k8s = "1.28"
+`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	got, err = removeEntries([]byte(testLockFile), depsTable, []string{"k8s", "my-lib"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `[dependencies]
+  [dependencies.helloworld]
+    name = "helloworld"
+    full_name = "helloworld_0.1.0"
+    version = "0.1.0"
+  [dependencies.konfig]
+    name = "konfig"
+    full_name = "konfig_0.4.0"
+    version = "0.4.0"
+`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRemoveMultilineEntries(t *testing.T) {
+	mod := `[dependencies]
+helloworld = { oci = "oci://ghcr.io/kcl-lang/helloworld", features = [
+    "a", # The [a] feature.
+    "b",
+] }
+k8s = "1.28"
+notes = """
+[dependencies.k8s]
+"""
+konfig = { git = "https://github.com/kcl-lang/konfig", tags = ["v0.4.0",
+    "]"] }
+`
+	got, err := removeEntries([]byte(mod), depsTable, []string{"helloworld", "konfig"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[dependencies]
+k8s = "1.28"
+notes = """
+[dependencies.k8s]
+"""
+`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	// The invalid documents are not returned.
+	if _, err := removeEntries([]byte("[dependencies]\nk8s = \"1.28\"\nkonfig = [\n"), depsTable, []string{"k8s"}); err == nil {
+		t.Error("expected an error for an invalid document")
+	}
+}
@@ -0,0 +1,356 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package mod
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/mod/semver"
+)
+
+const (
+	// DescriptionAnnotation is the manifest annotation of the description of
+	// the modules pushed to OCI registries.
+	DescriptionAnnotation = "org.kcllang.package.description"
+	// CreatedAnnotation is the manifest annotation of the date the modules
+	// were pushed to OCI registries.
+	CreatedAnnotation = "org.opencontainers.image.created"
+)
+
+// Module is a module found by a search.
+type Module struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+	// Published is the RFC 3339 date the version was published, if known.
+	Published string `json:"published"`
+	// Source is the registry repository or index the module was found in.
+	Source string `json:"source"`
+}
+
+// Searcher searches the modules of a registry or index.
+type Searcher interface {
+	// Search returns the modules whose name contains the query.
+	Search(query string) ([]*Module, error)
+}
+
+// Search returns the modules whose name contains the query, case
+// insensitively, in the sources, sorted by name and source. The modules
+// found by a source are kept when it also returns an error, such as for the
+// entries it skipped.
+func Search(query string, sources ...Searcher) ([]*Module, error) {
+	var modules []*Module
+	var errs []error
+	for _, s := range sources {
+		found, err := s.Search(query)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		modules = append(modules, found...)
+	}
+	sort.SliceStable(modules, func(i, j int) bool {
+		if modules[i].Name != modules[j].Name {
+			return modules[i].Name < modules[j].Name
+		}
+		return modules[i].Source < modules[j].Source
+	})
+	return modules, errors.Join(errs...)
+}
+
+func matches(name, query string) bool {
+	return strings.Contains(strings.ToLower(name), strings.ToLower(query))
+}
+
+// latest returns the latest version of the tags, by semantic version when
+// some of them are semantic versions.
+func latest(tags []string) string {
+	best := ""
+	for _, t := range tags {
+		v := "v" + strings.TrimPrefix(t, "v")
+		if semver.IsValid(v) && (best == "" || semver.Compare(v, "v"+strings.TrimPrefix(best, "v")) > 0) {
+			best = t
+		}
+	}
+	if best == "" && len(tags) > 0 {
+		return tags[len(tags)-1]
+	}
+	return best
+}
+
+// WriteJSON writes the modules as a JSON list.
+func WriteJSON(w io.Writer, modules []*Module) error {
+	if modules == nil {
+		modules = []*Module{}
+	}
+	data, err := json.MarshalIndent(modules, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// WriteTable writes the modules as a table, with the published dates
+// shortened to days.
+func WriteTable(w io.Writer, modules []*Module) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVERSION\tPUBLISHED\tDESCRIPTION")
+	for _, m := range modules {
+		published := m.Published
+		if t, err := time.Parse(time.RFC3339, published); err == nil {
+			published = t.Format(time.DateOnly)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", m.Name, m.Version, published, m.Description)
+	}
+	return tw.Flush()
+}
+
+// Registry is the repository of the modules in an OCI registry, searched
+// with the catalog, tags and manifests APIs of the distribution spec. Tokens
+// are requested when the registry challenges the requests.
+type Registry struct {
+	// URL is the URL of the registry, such as `https://ghcr.io`.
+	URL string
+	// Repo is the repository of the modules, such as `kcl-lang`.
+	Repo string
+	// Client is the HTTP client of the requests, http.DefaultClient if nil.
+	Client *http.Client
+	// Credential returns the credentials of the registry host, such as those
+	// stored by `kcl registry login`, or an empty username when there are
+	// none. Anonymous tokens are requested when nil.
+	Credential func(host string) (username, password string, err error)
+
+	// auth are the Authorization headers of the requests by scope.
+	auth map[string]string
+}
+
+// Search returns the modules of the repository whose name contains the query.
+// The modules whose tags or manifest cannot be read are skipped, and
+// returned as errors along with the other modules.
+func (r *Registry) Search(query string) ([]*Module, error) {
+	var repos []string
+	if err := r.list("/v2/_catalog?n=1000", "registry:catalog:*", "repositories", &repos); err != nil {
+		return nil, fmt.Errorf("failed to search %s: %w", r.URL, err)
+	}
+	prefix := ""
+	if r.Repo != "" {
+		prefix = strings.Trim(r.Repo, "/") + "/"
+	}
+	var modules []*Module
+	var errs []error
+	for _, repo := range repos {
+		name, ok := strings.CutPrefix(repo, prefix)
+		if !ok || strings.Contains(name, "/") || !matches(name, query) {
+			continue
+		}
+		m, err := r.module(repo, name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("skipped %s of %s: %w", repo, r.URL, err))
+			continue
+		}
+		if m != nil {
+			modules = append(modules, m)
+		}
+	}
+	return modules, errors.Join(errs...)
+}
+
+// module returns the latest version of the module in the repository, and
+// nil when it has no tags.
+func (r *Registry) module(repo, name string) (*Module, error) {
+	var tags []string
+	if err := r.list("/v2/"+repo+"/tags/list?n=1000", "repository:"+repo+":pull", "tags", &tags); err != nil {
+		return nil, err
+	}
+	version := latest(tags)
+	if version == "" {
+		return nil, nil
+	}
+	var manifest struct {
+		Annotations map[string]string `json:"annotations"`
+	}
+	resp, err := r.get("/v2/"+repo+"/manifests/"+version, "repository:"+repo+":pull",
+		"application/vnd.oci.image.manifest.v1+json", "application/vnd.docker.distribution.manifest.v2+json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest of %s:%s: %w", repo, version, err)
+	}
+	host := r.URL
+	if u, err := url.Parse(r.URL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	return &Module{
+		Name:        name,
+		Version:     version,
+		Description: manifest.Annotations[DescriptionAnnotation],
+		Published:   manifest.Annotations[CreatedAnnotation],
+		Source:      "oci://" + host + "/" + repo,
+	}, nil
+}
+
+var nextLinkRe = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="?next"?`)
+
+// list appends the items of the field of the pages of a paginated list.
+func (r *Registry) list(path, scope, field string, items *[]string) error {
+	for path != "" {
+		resp, err := r.get(path, scope, "application/json")
+		if err != nil {
+			return err
+		}
+		var page map[string]json.RawMessage
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("invalid response of %s: %w", path, err)
+		}
+		var pageItems []string
+		if raw, ok := page[field]; ok {
+			if err := json.Unmarshal(raw, &pageItems); err != nil {
+				return fmt.Errorf("invalid response of %s: %w", path, err)
+			}
+		}
+		*items = append(*items, pageItems...)
+		path = ""
+		if m := nextLinkRe.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+			path = m[1]
+		}
+	}
+	return nil
+}
+
+// get sends a GET request to the registry, with a token of the scope when
+// the registry challenges it.
+func (r *Registry) get(path, scope string, accept ...string) (*http.Response, error) {
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	target, err := url.Parse(r.URL)
+	if err != nil {
+		return nil, err
+	}
+	target, err = target.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	do := func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodGet, target.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range accept {
+			req.Header.Add("Accept", a)
+		}
+		if auth := r.auth[scope]; auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		return client.Do(req)
+	}
+	resp, err := do()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && r.auth[scope] == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := r.authorize(client, target.Host, challenge, scope); err != nil {
+			return nil, err
+		}
+		if resp, err = do(); err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: HTTP %s", target.Path, resp.Status)
+	}
+	return resp, nil
+}
+
+var challengeParamRe = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// authorize sets the Authorization header of the scope for the challenge of
+// the registry host: the credentials of the host for a Basic challenge, or a
+// token of the scope from the realm of a Bearer challenge, requested with the
+// credentials of the host when there are some and anonymously otherwise.
+func (r *Registry) authorize(client *http.Client, host, challenge, scope string) error {
+	username, password := "", ""
+	if r.Credential != nil {
+		var err error
+		if username, password, err = r.Credential(host); err != nil {
+			return fmt.Errorf("failed to load the credentials of %s: %w", host, err)
+		}
+	}
+	if r.auth == nil {
+		r.auth = map[string]string{}
+	}
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if strings.EqualFold(scheme, "Basic") {
+		if username == "" {
+			return fmt.Errorf("unauthorized, no credentials for %s, use 'kcl registry login' to add them", host)
+		}
+		r.auth[scope] = "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+		return nil
+	}
+	if !strings.EqualFold(scheme, "Bearer") {
+		return fmt.Errorf("unauthorized, unsupported challenge %q", challenge)
+	}
+	values := url.Values{}
+	realm := ""
+	for _, m := range challengeParamRe.FindAllStringSubmatch(params, -1) {
+		switch m[1] {
+		case "realm":
+			realm = m[2]
+		case "service":
+			values.Set("service", m[2])
+		}
+	}
+	if realm == "" {
+		return fmt.Errorf("unauthorized, no realm in challenge %q", challenge)
+	}
+	values.Set("scope", scope)
+	req, err := http.NewRequest(http.MethodGet, realm+"?"+values.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get a token from %s: HTTP %s", realm, resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("invalid token response of %s: %w", realm, err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return fmt.Errorf("no token in the response of %s", realm)
+	}
+	r.auth[scope] = "Bearer " + token.Token
+	return nil
+}
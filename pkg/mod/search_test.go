@@ -0,0 +1,214 @@
+package mod
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// newRegistry returns a registry stand-in serving the repositories with
+// their tags, paginating the catalog and requiring tokens, which are only
+// given to the user when not empty and to anyone otherwise.
+func newRegistry(t *testing.T, user string, tags map[string][]string, annotations map[string]map[string]string) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if u, p, _ := r.BasicAuth(); user != "" && (u != user || p != "secret") {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"token": "token:" + r.URL.Query().Get("scope")})
+			return
+		}
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer token:") {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/v2/_catalog":
+			var repos []string
+			for repo := range tags {
+				repos = append(repos, repo)
+			}
+			repos = append(repos, "other/k8s")
+			sort.Strings(repos)
+			if r.URL.Query().Get("last") == "" {
+				w.Header().Set("Link", `</v2/_catalog?last=x&n=1000>; rel="next"`)
+				json.NewEncoder(w).Encode(map[string][]string{"repositories": repos[:1]})
+			} else {
+				json.NewEncoder(w).Encode(map[string][]string{"repositories": repos[1:]})
+			}
+		case strings.HasSuffix(r.URL.Path, "/tags/list"):
+			repo := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v2/"), "/tags/list")
+			json.NewEncoder(w).Encode(map[string]any{"name": repo, "tags": tags[repo]})
+		case strings.Contains(r.URL.Path, "/manifests/"):
+			repo, tag, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v2/"), "/manifests/")
+			if strings.HasSuffix(repo, "-broken") {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"annotations": annotations[repo+":"+tag]})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRegistrySearch(t *testing.T) {
+	server := newRegistry(t, "", map[string][]string{
+		"kcl-lang/k8s":        {"1.27", "1.28.1", "1.28", "latest"},
+		"kcl-lang/helloworld": {"0.1.0"},
+		"kcl-lang/k8s-empty":  {},
+		"kcl-lang/k8s-broken": {"1.0"},
+	}, map[string]map[string]string{
+		"kcl-lang/k8s:1.28.1": {
+			DescriptionAnnotation: "Kubernetes models",
+			CreatedAnnotation:     "2024-01-02T03:04:05Z",
+		},
+	})
+	r := &Registry{URL: server.URL, Repo: "kcl-lang"}
+	// The broken repository is skipped, and reported.
+	modules, err := Search("K8S", r)
+	if err == nil || !strings.Contains(err.Error(), "kcl-lang/k8s-broken") {
+		t.Errorf("expected an error of the broken repository, got %v", err)
+	}
+	host := strings.TrimPrefix(server.URL, "http://")
+	want := []*Module{{
+		Name:        "k8s",
+		Version:     "1.28.1",
+		Description: "Kubernetes models",
+		Published:   "2024-01-02T03:04:05Z",
+		Source:      "oci://" + host + "/kcl-lang/k8s",
+	}}
+	if !reflect.DeepEqual(modules, want) {
+		t.Errorf("got %+v", modules[0])
+	}
+}
+
+func TestRegistrySearchCredentials(t *testing.T) {
+	server := newRegistry(t, "kcl", map[string][]string{"kcl-lang/k8s": {"1.28"}}, nil)
+	host := strings.TrimPrefix(server.URL, "http://")
+	if _, err := Search("k8s", &Registry{URL: server.URL, Repo: "kcl-lang"}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected an anonymous search to be unauthorized, got %v", err)
+	}
+	r := &Registry{
+		URL:  server.URL,
+		Repo: "kcl-lang",
+		Credential: func(h string) (string, string, error) {
+			if h != host {
+				t.Errorf("credentials of %s requested, want %s", h, host)
+			}
+			return "kcl", "secret", nil
+		},
+	}
+	modules, err := Search("k8s", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(modules) != 1 || modules[0].Name != "k8s" || modules[0].Version != "1.28" {
+		t.Errorf("got %+v", modules)
+	}
+}
+
+func TestRegistrySearchError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	_, err := Search("k8s", &Registry{URL: server.URL})
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected a 404 error, got %v", err)
+	}
+}
+
+func TestGitIndexSearch(t *testing.T) {
+	dir := t.TempDir()
+	origin := filepath.Join(dir, "origin")
+	files := map[string]string{
+		"k8s/kcl.mod":              "[package]\nname = \"k8s\"\nversion = \"1.28\"\ndescription = \"Kubernetes models\"\n",
+		"k8s/artifacthub-pkg.yaml": "version: 1.28\ncreatedAt: \"2024-01-02T03:04:05Z\"\n",
+		"konfig/kcl.mod":           "[package]\nname = \"konfig\"\nversion = \"0.4.0\"\n",
+		"README.md":                "# Modules\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(origin, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo, err := git.PlainInit(origin, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.AddGlob("."); err != nil {
+		t.Fatal(err)
+	}
+	when := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	sig := &object.Signature{Name: "kcl", Email: "kcl@example.com", When: when}
+	if _, err := wt.Commit("modules", &git.CommitOptions{Author: sig, Committer: sig}); err != nil {
+		t.Fatal(err)
+	}
+
+	index := &GitIndex{URL: origin, CacheDir: filepath.Join(dir, "cache")}
+	// The second search pulls the clone of the first one.
+	for i := 0; i < 2; i++ {
+		modules, err := Search("k", index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []*Module{
+			{Name: "k8s", Version: "1.28", Description: "Kubernetes models", Published: "2024-01-02T03:04:05Z", Source: origin},
+			{Name: "konfig", Version: "0.4.0", Source: origin},
+		}
+		if !reflect.DeepEqual(modules, want) {
+			t.Errorf("got %+v %+v", modules[0], modules[1])
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	modules := []*Module{
+		{Name: "k8s", Version: "1.28", Description: "Kubernetes models", Published: "2024-01-02T03:04:05Z", Source: "oci://ghcr.io/kcl-lang/k8s"},
+		{Name: "konfig", Version: "0.4.0", Source: "https://github.com/kcl-lang/modules"},
+	}
+	var buf bytes.Buffer
+	if err := WriteTable(&buf, modules); err != nil {
+		t.Fatal(err)
+	}
+	want := `NAME    VERSION  PUBLISHED   DESCRIPTION
+k8s     1.28     2024-01-02  Kubernetes models
+konfig  0.4.0
+`
+	got := regexp.MustCompile(` +\n`).ReplaceAllString(buf.String(), "\n")
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	buf.Reset()
+	if err := WriteJSON(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "[]\n" {
+		t.Errorf("got %q", buf.String())
+	}
+}
@@ -17,4 +17,18 @@ const (
 	OpenAPI         string = "openapi"
 	JsonSchema      string = "jsonschema"
 	TerraformSchema string = "terraformschema"
+	// Helm is the Helm chart import mode.
+	Helm string = "helm"
+	// K8s is the Kubernetes manifests import mode.
+	K8s string = "k8s"
+	// Protobuf is the protocol buffers import mode.
+	Protobuf string = "protobuf"
+	// Compose is the Docker Compose import mode.
+	Compose string = "compose"
+	// Text is the plain text output format.
+	Text string = "text"
+	// Table is the table output format.
+	Table string = "table"
+	// TypeScript is the TypeScript export mode.
+	TypeScript string = "typescript"
 )
@@ -0,0 +1,36 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package options
+
+import (
+	"encoding/json"
+
+	"kcl-lang.io/cli/pkg/doc"
+	"kcl-lang.io/kpm/pkg/api"
+)
+
+// LoadDocIndex returns the documentation of the package in path and of its
+// dependencies accepted by include, or all of them when include is nil. The
+// schemas are read from the spec exported by `api.GetKclPackage`.
+func LoadDocIndex(path string, include func(dep string) bool) (*doc.Index, error) {
+	deps, err := ResolveDepsFrom(path, true)
+	if err != nil {
+		return nil, err
+	}
+	for name := range deps {
+		if include != nil && !include(name) {
+			delete(deps, name)
+		}
+	}
+	return doc.Load(path, deps, func(dir string) ([]byte, error) {
+		pkg, err := api.GetKclPackage(dir)
+		if err != nil {
+			return nil, err
+		}
+		spec, err := pkg.ExportSwaggerV2Spec()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(spec)
+	})
+}
@@ -0,0 +1,106 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package options
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kcl-lang.io/cli/pkg/export"
+)
+
+// ExportOptions holds the options of the kcl export command.
+type ExportOptions struct {
+	// Mode is the export mode: jsonschema, openapi, crd, gostruct or
+	// typescript.
+	Mode string
+	// Path is the KCL package to export.
+	Path string
+	// Output is the output directory, or the output file of the bundle, `-`
+	// writing the bundle to Writer.
+	Output string
+	// Force overwrites the existing output files.
+	Force bool
+	// Bundle writes all the schemas to a single file.
+	Bundle bool
+	// Group and Version are the API group and version of the CRDs.
+	Group   string
+	Version string
+	// Package is the Go package name of the gostruct mode.
+	Package string
+	// Writer is the output of the bundle when Output is `-`.
+	Writer io.Writer
+}
+
+// NewExportOptions returns a new instance of ExportOptions with default values.
+func NewExportOptions() *ExportOptions {
+	return &ExportOptions{
+		Mode:    JsonSchema,
+		Path:    ".",
+		Group:   "kcl-lang.io",
+		Version: "v1",
+		Writer:  os.Stdout,
+	}
+}
+
+// Run runs the kcl export command with options.
+func (o *ExportOptions) Run() error {
+	mode := strings.ToLower(o.Mode)
+	valid := false
+	for _, m := range export.Modes {
+		valid = valid || m == mode
+	}
+	if !valid {
+		return fmt.Errorf("invalid mode: %s", o.Mode)
+	}
+	idx, err := LoadDocIndex(o.Path, nil)
+	if err != nil {
+		return err
+	}
+	bundle := o.Bundle || o.Output == "-"
+	files, err := export.Generate(idx, mode, export.Options{
+		Bundle:  bundle,
+		Group:   o.Group,
+		Version: o.Version,
+		Package: o.Package,
+	})
+	if err != nil {
+		return err
+	}
+	if o.Output == "-" {
+		for _, f := range files {
+			if _, err := o.Writer.Write(f.Data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// The bundle is written to the output file, the schema files to the
+	// output directory.
+	paths := make([]string, len(files))
+	for i, f := range files {
+		switch {
+		case bundle && o.Output != "":
+			paths[i] = o.Output
+		default:
+			paths[i] = filepath.Join(o.Output, f.Name)
+		}
+		if _, err := os.Stat(paths[i]); err == nil && !o.Force {
+			return fmt.Errorf("output file already exist, use --force to overwrite: %s", paths[i])
+		}
+	}
+	for i, f := range files {
+		if dir := filepath.Dir(paths[i]); dir != "." {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+		}
+		if err := os.WriteFile(paths[i], f.Data, 0644); err != nil {
+			return fmt.Errorf("failed to create output file: %s", paths[i])
+		}
+	}
+	return nil
+}
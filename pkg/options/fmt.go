@@ -0,0 +1,257 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package options
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kcl-lang.io/cli/pkg/diff"
+	"kcl-lang.io/cli/pkg/formatter"
+	"kcl-lang.io/cli/pkg/fs"
+	"kcl-lang.io/cli/pkg/source"
+	kcl "kcl-lang.io/kcl-go"
+)
+
+// StdinPath is the path argument reading the source from stdin.
+const StdinPath = "-"
+
+// FmtOptions is the options for the kcl fmt command.
+type FmtOptions struct {
+	// Paths are the files, directories or `./...` patterns to format.
+	Paths []string
+	// DryRun reports the files requiring formatting without modifying them.
+	DryRun bool
+	// Diff prints a unified diff of the changes instead of writing them.
+	Diff bool
+	// Excludes are the glob patterns of the files to skip.
+	Excludes []string
+	// Config is the formatter config file overriding the discovered one.
+	Config string
+	// Lines restricts the formatting to the top-level statements overlapping
+	// the `start:end` line range.
+	Lines string
+	// Output is the output format, `text` for the formatted code or `json`
+	// for the list of text edits of a line range.
+	Output string
+	// Reader is the input of the `-` path.
+	Reader io.Reader
+	// Writer is the output of the command.
+	Writer io.Writer
+
+	lineRange *formatter.LineRange
+	override  *formatter.Config
+	resolver  *formatter.Resolver
+}
+
+// NewFmtOptions returns a new instance of FmtOptions with default values.
+func NewFmtOptions() *FmtOptions {
+	return &FmtOptions{
+		Reader: os.Stdin,
+		Writer: os.Stdout,
+		Output: Text,
+	}
+}
+
+// Validate validates the fmt options.
+func (o *FmtOptions) Validate() error {
+	for _, p := range o.Paths {
+		if p == StdinPath && len(o.Paths) > 1 {
+			return fmt.Errorf("'-' reads from stdin and can't be combined with other paths")
+		}
+	}
+	if o.Output != Text && o.Output != Json {
+		return fmt.Errorf("invalid output format %q, must be one of text or json", o.Output)
+	}
+	if o.Output == Json && o.Lines == "" {
+		return fmt.Errorf("the json output requires a line range set with --lines")
+	}
+	if o.Lines != "" {
+		if len(o.Paths) != 1 || (o.Paths[0] != StdinPath && !fs.FileExists(o.Paths[0])) {
+			return fmt.Errorf("a line range requires a single file or '-' to format")
+		}
+		r, err := formatter.ParseLineRange(o.Lines)
+		if err != nil {
+			return err
+		}
+		o.lineRange = &r
+	}
+	return nil
+}
+
+// Run formats the paths and returns the files requiring formatting when no
+// file is written, or the formatted files otherwise.
+func (o *FmtOptions) Run() ([]string, error) {
+	if len(o.Paths) == 1 && o.Paths[0] == StdinPath {
+		return nil, o.formatStdin()
+	}
+	files, err := o.files()
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return changed, err
+		}
+		if o.Output == Json {
+			edits, err := o.edits(file, src)
+			if err != nil {
+				return changed, fmt.Errorf("failed to format %s: %w", file, err)
+			}
+			if len(edits) > 0 {
+				changed = append(changed, file)
+			}
+			return changed, o.writeEdits(edits)
+		}
+		formatted, err := o.format(file, src)
+		if err != nil {
+			return changed, fmt.Errorf("failed to format %s: %w", file, err)
+		}
+		if string(formatted) == string(src) {
+			continue
+		}
+		changed = append(changed, file)
+		if o.Diff {
+			fmt.Fprint(o.Writer, diff.Unified(file, file, string(src), string(formatted)))
+		}
+		if o.DryRun || o.Diff {
+			continue
+		}
+		if err := os.WriteFile(file, formatted, 0644); err != nil {
+			return changed, err
+		}
+	}
+	return changed, nil
+}
+
+// formatStdin formats the source read from stdin to the output.
+func (o *FmtOptions) formatStdin() error {
+	src, err := io.ReadAll(o.Reader)
+	if err != nil {
+		return err
+	}
+	if o.Output == Json {
+		edits, err := o.edits(".", src)
+		if err != nil {
+			return err
+		}
+		return o.writeEdits(edits)
+	}
+	formatted, err := o.format(".", src)
+	if err != nil {
+		return err
+	}
+	if o.Diff {
+		_, err = fmt.Fprint(o.Writer, diff.Unified("<stdin>", "<stdin>", string(src), string(formatted)))
+		return err
+	}
+	_, err = o.Writer.Write(formatted)
+	return err
+}
+
+// format formats the source of the file at path with the style of the
+// config applying to it, restricted to the line range when set.
+func (o *FmtOptions) format(path string, src []byte) ([]byte, error) {
+	if o.lineRange != nil {
+		edits, err := o.edits(path, src)
+		if err != nil {
+			return nil, err
+		}
+		return formatter.ApplyEdits(src, edits), nil
+	}
+	return o.FormatCode(path, src)
+}
+
+// edits returns the text edits formatting the line range of the source.
+func (o *FmtOptions) edits(path string, src []byte) ([]formatter.TextEdit, error) {
+	edits, err := formatter.FormatRange(path, src, *o.lineRange, func(code []byte) ([]byte, error) {
+		return o.FormatCode(path, code)
+	})
+	if edits == nil {
+		edits = []formatter.TextEdit{}
+	}
+	return edits, err
+}
+
+// writeEdits writes the text edits as a JSON list.
+func (o *FmtOptions) writeEdits(edits []formatter.TextEdit) error {
+	data, err := json.MarshalIndent(edits, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(o.Writer, string(data))
+	return err
+}
+
+// FormatCode formats code with the KCL formatter and the style of the config
+// applying to the file at path.
+func (o *FmtOptions) FormatCode(path string, code []byte) ([]byte, error) {
+	config, err := o.config(path)
+	if err != nil {
+		return nil, err
+	}
+	formatted, err := kcl.FormatCode(code)
+	if err != nil {
+		return nil, err
+	}
+	return formatter.Apply(formatted, config), nil
+}
+
+// config returns the formatter config of the file at path, which is the
+// --config file when set and the discovered config otherwise.
+func (o *FmtOptions) config(path string) (*formatter.Config, error) {
+	if o.Config != "" {
+		if o.override == nil {
+			c, err := formatter.LoadConfig(o.Config)
+			if err != nil {
+				return nil, err
+			}
+			if c == nil {
+				return nil, fmt.Errorf("no [fmt] section found in %s", o.Config)
+			}
+			o.override = c
+		}
+		return o.override, nil
+	}
+	if o.resolver == nil {
+		o.resolver = formatter.NewResolver()
+	}
+	return o.resolver.Resolve(path)
+}
+
+// files expands the paths into the KCL files to format. The `./...` suffix
+// selects the files of a directory recursively.
+func (o *FmtOptions) files() ([]string, error) {
+	var files []string
+	seen := map[string]bool{}
+	for _, p := range o.Paths {
+		recursive := false
+		if root, ok := strings.CutSuffix(p, "..."); ok {
+			p, recursive = filepath.Clean(root), true
+		}
+		matches, err := fs.ExpandIfFilePattern(p, recursive)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range matches {
+			if !source.IsKclFile(f) || seen[f] {
+				continue
+			}
+			excluded, err := fs.MatchAnyPattern(f, o.Excludes)
+			if err != nil {
+				return nil, err
+			}
+			if !excluded {
+				seen[f] = true
+				files = append(files, f)
+			}
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,75 @@
+package options
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestFmtOptions_Run(t *testing.T) {
+	dir := t.TempDir()
+	unformatted := "a=1\n"
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "main.k"), []byte(unformatted), 0644))
+	assert.NilError(t, os.MkdirAll(filepath.Join(dir, "gen"), 0755))
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "gen", "gen.k"), []byte(unformatted), 0644))
+
+	// diff output without modifying the files
+	var buf bytes.Buffer
+	o := NewFmtOptions()
+	o.Paths = []string{dir + "/..."}
+	o.Diff = true
+	o.Excludes = []string{"gen"}
+	o.Writer = &buf
+	changed, err := o.Run()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, changed, []string{filepath.Join(dir, "main.k")})
+	assert.Assert(t, strings.Contains(buf.String(), "-a=1\n+a = 1\n"))
+	src, err := os.ReadFile(filepath.Join(dir, "main.k"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(src), unformatted)
+
+	// stdin to stdout
+	buf.Reset()
+	o = NewFmtOptions()
+	o.Paths = []string{StdinPath}
+	o.Reader = strings.NewReader(unformatted)
+	o.Writer = &buf
+	_, err = o.Run()
+	assert.NilError(t, err)
+	assert.Equal(t, buf.String(), "a = 1\n")
+
+	o.Paths = []string{StdinPath, dir}
+	assert.ErrorContains(t, o.Validate(), "stdin")
+}
+
+func TestFmtOptions_RunLines(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.k")
+	assert.NilError(t, os.WriteFile(file, []byte("a=1\nb=2\nc=3\n"), 0644))
+
+	var buf bytes.Buffer
+	o := NewFmtOptions()
+	o.Paths = []string{file}
+	o.Lines = "2:2"
+	o.Output = Json
+	o.Writer = &buf
+	assert.NilError(t, o.Validate())
+	changed, err := o.Run()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, changed, []string{file})
+	assert.Assert(t, strings.Contains(buf.String(), `"newText": "b = 2\n"`))
+
+	o.Output = Text
+	_, err = o.Run()
+	assert.NilError(t, err)
+	src, err := os.ReadFile(file)
+	assert.NilError(t, err)
+	assert.Equal(t, string(src), "a=1\nb = 2\nc=3\n")
+
+	o.Paths = []string{dir}
+	assert.ErrorContains(t, o.Validate(), "single file")
+}
@@ -10,10 +10,12 @@ import (
 
 	"kcl-lang.io/cli/pkg/fs"
 	"kcl-lang.io/cli/pkg/import/crd"
-	"kcl-lang.io/kcl-go/pkg/logger"
+	"kcl-lang.io/cli/pkg/import/openapi"
 	"kcl-lang.io/kcl-go/pkg/tools/gen"
 	crdGen "kcl-lang.io/kcl-openapi/pkg/kube_resource/generator"
 	"kcl-lang.io/kcl-openapi/pkg/swagger/generator"
+	"kcl-lang.io/kpm/pkg/opt"
+	pkg "kcl-lang.io/kpm/pkg/package"
 )
 
 type ImportOptions struct {
@@ -24,6 +26,43 @@ type ImportOptions struct {
 	SkipValidation bool
 	ModelPackage   string
 	Recursive      bool
+	// OutputDir is the directory of the KCL package imported from a tree of
+	// configs, keeping its structure.
+	OutputDir string
+	// K8sVersion is the version of the k8s module added by the k8s mode, the
+	// latest by default.
+	K8sVersion string
+	// ProtoPath is the list of directories in which the imports of the
+	// protobuf mode are resolved.
+	ProtoPath []string
+	// InferSchema infers a single schema from the input data files instead
+	// of converting each of them to values.
+	InferSchema bool
+	// SchemaName is the name of the inferred schema.
+	SchemaName string
+	// Headers are the `Name: value` headers of the requests of URL inputs.
+	Headers []string
+	// Token is the bearer token of the requests of URL inputs.
+	Token string
+	// InsecureSkipTLSVerify skips the verification of the certificates of
+	// the servers of URL inputs.
+	InsecureSkipTLSVerify bool
+	// CAFile is the PEM file of the additional certificate authorities of the
+	// servers of URL inputs.
+	CAFile string
+	// CacheDir is the cache directory of the documents of URL inputs.
+	CacheDir string
+	// Offline reads the documents of URL inputs from the cache only.
+	Offline bool
+	// Include are the patterns of the names of the schemas generated by the
+	// openapi mode, with their dependencies. All schemas by default.
+	Include []string
+	// Exclude are the patterns of the names of the schemas not generated by
+	// the openapi mode, unless other schemas depend on them.
+	Exclude []string
+	// ComposePackage is the package of the compose schemas instantiated by
+	// the compose mode, instead of generated schemas.
+	ComposePackage string
 }
 
 // NewImportOptions returns a new instance of ImportOptions with default values.
@@ -42,17 +81,38 @@ func (o *ImportOptions) Run() error {
 	processedFiles := make([]string, 0, len(o.Files))
 	tempFiles := []string{}
 
+	// The fetcher, and its cache directory, are only set up for URL inputs.
+	var fetcher *fs.Fetcher
+	var err error
 	for _, f := range o.Files {
 		if fs.IsURL(f) {
-			// Fetch URL content to temp file
-			tempPath, err := fs.GenTempFileFromURL(f)
+			if fetcher == nil {
+				if fetcher, err = o.fetcher(); err != nil {
+					for _, tf := range tempFiles {
+						os.RemoveAll(tf)
+					}
+					return err
+				}
+			}
+			// Fetch URL content to temp file, with the documents it references
+			// for the schema modes.
+			fetcher.Trust(f)
+			var tempPath string
+			if mode == OpenAPI || mode == JsonSchema {
+				tempPath, err = os.MkdirTemp("", "kcl-import-*")
+				if err == nil {
+					tempFiles = append(tempFiles, tempPath)
+					tempPath, err = fetcher.Bundle(f, tempPath)
+				}
+			} else if tempPath, err = fetcher.TempFile(f); err == nil {
+				tempFiles = append(tempFiles, tempPath)
+			}
 			if err != nil {
 				for _, tf := range tempFiles {
-					os.Remove(tf)
+					os.RemoveAll(tf)
 				}
 				return err
 			}
-			tempFiles = append(tempFiles, tempPath)
 			processedFiles = append(processedFiles, tempPath)
 		} else {
 			processedFiles = append(processedFiles, f)
@@ -62,14 +122,36 @@ func (o *ImportOptions) Run() error {
 	// Ensure temp files are cleaned up when function returns
 	defer func() {
 		for _, tf := range tempFiles {
-			os.Remove(tf)
+			os.RemoveAll(tf)
 		}
 	}()
 
+	// Trees are expanded keeping the directories of their files.
+	if o.OutputDir != "" {
+		return o.importTree(processedFiles)
+	}
+
+	// Chart directories and archives are not expanded to their files.
+	if mode == Helm {
+		return o.importHelm(processedFiles)
+	}
+
 	files, err := fs.ExpandInputFiles(processedFiles, o.Recursive)
 	if err != nil {
 		return err
 	}
+	if o.InferSchema {
+		return o.inferSchema(files)
+	}
+	if mode == K8s {
+		return o.importK8s(files)
+	}
+	if mode == Protobuf {
+		return o.importProtobuf(files)
+	}
+	if mode == Compose {
+		return o.importCompose(files)
+	}
 	switch mode {
 	case Json:
 		opts.Mode = gen.ModeJson
@@ -108,24 +190,29 @@ func (o *ImportOptions) Run() error {
 					Spec: opts.Spec,
 				})
 				if err != nil {
-					logger.GetLogger().Error(err)
+					return fmt.Errorf("failed to get the specs of the CRD %s: %w", p, err)
 				}
 				// do not run validate spec on spec file generated from crd
 				opts.ValidateSpec = false
 			} else {
-				specs = []string{opts.Spec}
+				// Bundle, convert and select the schemas of the spec
+				spec, err := o.prepareOpenAPISpec(p)
+				if err != nil {
+					return err
+				}
+				defer os.Remove(spec)
+				specs = []string{spec}
 			}
 			// Generate specs to KCL files
 			for _, spec := range specs {
 				opts.Spec = spec
 				if err := generator.Generate(opts); err != nil {
-					logger.GetLogger().Error(err)
+					return fmt.Errorf("failed to generate KCL models from %s: %w", p, err)
 				}
 			}
-			// Group by the api version
+			// Group by the api group and version
 			if mode == Crd {
-				err := crd.GroupByKclFiles(opts.Target, opts.ModelPackage)
-				if err != nil {
+				if err := o.groupCrdFiles(p, opts.Target, opts.ModelPackage); err != nil {
 					return err
 				}
 			}
@@ -165,3 +252,62 @@ func (o *ImportOptions) Run() error {
 	}
 	return nil
 }
+
+// groupCrdFiles moves the KCL files generated from the CRD spec file to the
+// packages of their API group and version, and records the digests of the
+// definitions in the lock file of the model package.
+func (o *ImportOptions) groupCrdFiles(spec, target, modelPackage string) error {
+	data, err := os.ReadFile(spec)
+	if err != nil {
+		return err
+	}
+	crds, err := crd.Parse(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse the CRD %s: %w", spec, err)
+	}
+	if err := crd.GroupByKclFiles(target, modelPackage, crds); err != nil {
+		return err
+	}
+	baseDir := filepath.Join(target, modelPackage)
+	if err := ensureModFile(baseDir); err != nil {
+		return err
+	}
+	return crd.RecordDigests(baseDir, crds)
+}
+
+// ensureModFile creates the kcl.mod file of the package in dir, named after
+// the directory, when missing.
+func ensureModFile(dir string) error {
+	if fs.FileExists(filepath.Join(dir, "kcl.mod")) {
+		return nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	kclPkg := pkg.NewKclPkg(&opt.InitOptions{
+		Name:     filepath.Base(abs),
+		InitPath: dir,
+	})
+	return kclPkg.ModFile.StoreModFile()
+}
+
+// prepareOpenAPISpec writes the Swagger 2.0 spec of the OpenAPI spec file,
+// with the schemas of the other files it references and only the selected
+// schemas, to a temp file. The caller is responsible for removing it.
+func (o *ImportOptions) prepareOpenAPISpec(path string) (string, error) {
+	data, err := openapi.Prepare(path, o.Include, o.Exclude)
+	if err != nil {
+		return "", fmt.Errorf("invalid OpenAPI spec %s: %w", path, err)
+	}
+	tempFile, err := os.CreateTemp("", "kcl-openapi-*.json")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer tempFile.Close()
+	if _, err := tempFile.Write(data); err != nil {
+		os.Remove(tempFile.Name())
+		return "", err
+	}
+	return tempFile.Name(), nil
+}
@@ -0,0 +1,45 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package options
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kcl-lang.io/cli/pkg/import/compose"
+)
+
+// importCompose generates the KCL code instantiating the compose schemas
+// from each Docker Compose file, in a file named after it by default.
+func (o *ImportOptions) importCompose(files []string) error {
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		file, err := compose.Generate(data, o.ComposePackage)
+		if err != nil {
+			return fmt.Errorf("failed to convert the compose file %s: %w", f, err)
+		}
+		if o.Output == "-" {
+			if _, err := os.Stdout.Write(file.Bytes()); err != nil {
+				return err
+			}
+			continue
+		}
+		outputFile := o.Output
+		if outputFile == "" {
+			filename := filepath.Base(f)
+			outputFile = strings.TrimSuffix(filename, filepath.Ext(filename)) + ".k"
+		}
+		if _, err := os.Stat(outputFile); err == nil && !o.Force {
+			return fmt.Errorf("output file already exist, use --force to overwrite: %s", outputFile)
+		}
+		if err := os.WriteFile(outputFile, file.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to create output file: %s", outputFile)
+		}
+	}
+	return nil
+}
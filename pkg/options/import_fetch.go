@@ -0,0 +1,62 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package options
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kcl-lang.io/cli/pkg/fs"
+)
+
+const (
+	// importTokenEnv is the environment variable of the bearer token of the
+	// requests of URL inputs, used when no token flag is set.
+	importTokenEnv = "KCL_IMPORT_TOKEN"
+	// importHeadersEnv is the environment variable of the headers of the
+	// requests of URL inputs, one `Name: value` header per line.
+	importHeadersEnv = "KCL_IMPORT_HEADERS"
+)
+
+// fetcher returns the fetcher of the URL inputs. The headers and token are
+// read from the options, else from the environment, and the documents are
+// cached in the user cache directory by default.
+func (o *ImportOptions) fetcher() (*fs.Fetcher, error) {
+	f := &fs.Fetcher{
+		Headers:               http.Header{},
+		Token:                 o.Token,
+		InsecureSkipTLSVerify: o.InsecureSkipTLSVerify,
+		CAFile:                o.CAFile,
+		CacheDir:              o.CacheDir,
+		Offline:               o.Offline,
+	}
+	if f.Token == "" {
+		f.Token = os.Getenv(importTokenEnv)
+	}
+	headers := o.Headers
+	if len(headers) == 0 {
+		for _, line := range strings.Split(os.Getenv(importHeadersEnv), "\n") {
+			if strings.TrimSpace(line) != "" {
+				headers = append(headers, line)
+			}
+		}
+	}
+	for _, h := range headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid header %q, expected `Name: value`", h)
+		}
+		f.Headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	if f.CacheDir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		f.CacheDir = filepath.Join(cacheDir, "kcl", "import")
+	}
+	return f, nil
+}
@@ -0,0 +1,82 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package options
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"kcl-lang.io/cli/pkg/fs"
+	"kcl-lang.io/cli/pkg/import/helm"
+	"kcl-lang.io/kpm/pkg/opt"
+	pkg "kcl-lang.io/kpm/pkg/package"
+)
+
+// helmValuesFile is the KCL file of the Values schema in the package
+// generated from a Helm chart.
+const helmValuesFile = "values.k"
+
+// importHelm generates a KCL package with the Values schema of each chart
+// directory or `.tgz` chart. The package is written to the output directory,
+// or to a directory named after the chart when the output is not set or
+// several charts are imported.
+func (o *ImportOptions) importHelm(charts []string) error {
+	for _, path := range charts {
+		chart, err := helm.Load(path)
+		if err != nil {
+			return err
+		}
+		dir := o.Output
+		if dir == "" || dir == "-" {
+			dir = chart.Name
+		} else if len(charts) > 1 {
+			dir = filepath.Join(dir, chart.Name)
+		}
+		file, err := chart.Schemas()
+		if err != nil {
+			return fmt.Errorf("failed to import the chart %s: %w", path, err)
+		}
+		outputFile := filepath.Join(dir, helmValuesFile)
+		if _, err := os.Stat(outputFile); err == nil && !o.Force {
+			return fmt.Errorf("output file already exist, use --force to overwrite: %s", outputFile)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := storeChartModFile(dir, chart, o.Force); err != nil {
+			return err
+		}
+		if err := os.WriteFile(outputFile, file.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to create output file: %s", outputFile)
+		}
+	}
+	return nil
+}
+
+// storeChartModFile writes the kcl.mod file of the package of a chart with
+// the name, version and description of the chart. An existing kcl.mod file is
+// kept as is, and only gets the metadata of the chart with force, keeping its
+// dependencies.
+func storeChartModFile(dir string, chart *helm.Chart, force bool) error {
+	if !fs.FileExists(filepath.Join(dir, "kcl.mod")) {
+		kclPkg := pkg.NewKclPkg(&opt.InitOptions{
+			Name:     chart.Name,
+			InitPath: dir,
+		})
+		kclPkg.ModFile.Pkg.Version = chart.Version
+		kclPkg.ModFile.Pkg.Description = chart.Description
+		return kclPkg.ModFile.StoreModFile()
+	}
+	if !force {
+		return nil
+	}
+	kclPkg, err := pkg.LoadKclPkgWithOpts(pkg.WithPath(dir))
+	if err != nil {
+		return err
+	}
+	kclPkg.ModFile.Pkg.Name = chart.Name
+	kclPkg.ModFile.Pkg.Version = chart.Version
+	kclPkg.ModFile.Pkg.Description = chart.Description
+	return kclPkg.ModFile.StoreModFile()
+}
@@ -0,0 +1,69 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package options
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"kcl-lang.io/cli/pkg/import/schema"
+)
+
+const (
+	// defaultSchemaName is the name of the schema inferred from samples.
+	defaultSchemaName = "Config"
+	// inferEnumLimit is the maximum number of distinct string values of an
+	// attribute inferred as an enum.
+	inferEnumLimit = 5
+)
+
+// inferSchema generates a single schema describing all the YAML and JSON
+// sample data files.
+func (o *ImportOptions) inferSchema(files []string) error {
+	var samples []yaml.MapSlice
+	for _, f := range files {
+		switch strings.ToLower(filepath.Ext(f)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		s, err := schema.Samples(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse the sample %s: %w", f, err)
+		}
+		samples = append(samples, s...)
+	}
+	if len(samples) == 0 {
+		return fmt.Errorf("no samples found in %s", strings.Join(files, ", "))
+	}
+	name := o.SchemaName
+	if name == "" {
+		name = defaultSchemaName
+	}
+	b := schema.NewBuilder(nil)
+	b.EnumLimit = inferEnumLimit
+	b.InferSamples(name, samples)
+	file := b.File(fmt.Sprintf("This file was generated by kcl import from %d samples.", len(samples)))
+	if o.Output == "-" {
+		_, err := os.Stdout.Write(file.Bytes())
+		return err
+	}
+	outputFile := o.Output
+	if outputFile == "" {
+		outputFile = strings.ToLower(name) + ".k"
+	}
+	if _, err := os.Stat(outputFile); err == nil && !o.Force {
+		return fmt.Errorf("output file already exist, use --force to overwrite: %s", outputFile)
+	}
+	if err := os.WriteFile(outputFile, file.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to create output file: %s", outputFile)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package options
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kcl-lang.io/cli/pkg/import/k8s"
+	"kcl-lang.io/kpm/pkg/client"
+	"kcl-lang.io/kpm/pkg/downloader"
+	pkg "kcl-lang.io/kpm/pkg/package"
+)
+
+// k8sMainFile is the KCL file of the resources imported from Kubernetes
+// manifests.
+const k8sMainFile = "main.k"
+
+// importK8s generates the KCL code instantiating the schemas of the k8s
+// module from the Kubernetes manifests, in the `main.k` file of the output
+// package directory. The k8s module is added to the dependencies of the
+// package when used.
+func (o *ImportOptions) importK8s(files []string) error {
+	var manifests []*k8s.Manifest
+	for _, f := range files {
+		switch strings.ToLower(filepath.Ext(f)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		m, err := k8s.Parse(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse the manifests %s: %w", f, err)
+		}
+		manifests = append(manifests, m...)
+	}
+	if len(manifests) == 0 {
+		return fmt.Errorf("no Kubernetes manifests found in %s", strings.Join(files, ", "))
+	}
+	index, err := k8sIndex(o.K8sVersion)
+	if err != nil {
+		return err
+	}
+	for _, m := range manifests {
+		if _, ok := index.Package(m.APIVersion, m.Kind); !ok {
+			fmt.Fprintf(os.Stderr, "warning: %s %s %q is not declared by the k8s module, imported as a dict\n", m.APIVersion, m.Kind, m.Name)
+		}
+	}
+	file, usesK8s := k8s.Generate(manifests, index)
+	if o.Output == "-" {
+		_, err := os.Stdout.Write(file.Bytes())
+		return err
+	}
+	dir := o.Output
+	if dir == "" {
+		dir = "."
+	}
+	outputFile := filepath.Join(dir, k8sMainFile)
+	if _, err := os.Stat(outputFile); err == nil && !o.Force {
+		return fmt.Errorf("output file already exist, use --force to overwrite: %s", outputFile)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := ensureModFile(dir); err != nil {
+		return err
+	}
+	if err := os.WriteFile(outputFile, file.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to create output file: %s", outputFile)
+	}
+	if !usesK8s {
+		return nil
+	}
+	dep := k8s.Dependency
+	if o.K8sVersion != "" {
+		dep += ":" + o.K8sVersion
+	}
+	return addDependency(dir, dep)
+}
+
+// k8sIndex returns the index of the schemas of the k8s module at the version,
+// the latest one when empty. The module is downloaded to the package cache
+// through a temporary package depending on it.
+func k8sIndex(version string) (k8s.Index, error) {
+	dir, err := os.MkdirTemp("", "kcl-k8s-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	if err := ensureModFile(dir); err != nil {
+		return nil, err
+	}
+	dep := k8s.Dependency
+	if version != "" {
+		dep += ":" + version
+	}
+	if err := addDependency(dir, dep); err != nil {
+		return nil, fmt.Errorf("failed to download the k8s module: %w", err)
+	}
+	kclPkg, err := pkg.LoadKclPkgWithOpts(pkg.WithPath(dir))
+	if err != nil {
+		return nil, err
+	}
+	d, ok := kclPkg.Dependencies.Deps.Get(k8s.Dependency)
+	if !ok || d.LocalFullPath == "" {
+		return nil, fmt.Errorf("failed to locate the downloaded k8s module")
+	}
+	return k8s.LoadIndex(d.LocalFullPath)
+}
+
+// addDependency adds the dependency of a module spec such as `k8s:1.31` to
+// the package in dir, like `kcl mod add` does.
+func addDependency(dir, spec string) (err error) {
+	cli, err := client.NewKpmClient()
+	if err != nil {
+		return err
+	}
+	// Acquire the lock of the package cache.
+	if err = cli.AcquirePackageCacheLock(); err != nil {
+		return err
+	}
+	defer func() {
+		// release the lock of the package cache after the function returns.
+		releaseErr := cli.ReleasePackageCacheLock()
+		if releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+	}()
+	kclPkg, err := pkg.LoadKclPkgWithOpts(
+		pkg.WithPath(dir),
+		pkg.WithSettings(cli.GetSettings()),
+	)
+	if err != nil {
+		return err
+	}
+	modSpec := downloader.ModSpec{}
+	if err = modSpec.FromString(spec); err != nil {
+		return err
+	}
+	return cli.Add(
+		client.WithAddKclPkg(kclPkg),
+		client.WithAddSource(&downloader.Source{ModSpec: &modSpec}),
+	)
+}
@@ -0,0 +1,63 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package options
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kcl-lang.io/cli/pkg/import/protobuf"
+)
+
+// importProtobuf generates a KCL file of schemas and types per `.proto` file
+// and per imported file in the output package directory, or writes them to
+// the standard output.
+func (o *ImportOptions) importProtobuf(files []string) error {
+	var protoFiles []string
+	for _, f := range files {
+		if strings.ToLower(filepath.Ext(f)) == ".proto" {
+			protoFiles = append(protoFiles, f)
+		}
+	}
+	if len(protoFiles) == 0 {
+		return fmt.Errorf("no protobuf files found in %s", strings.Join(files, ", "))
+	}
+	parsed, err := protobuf.Load(protoFiles, o.ProtoPath)
+	if err != nil {
+		return err
+	}
+	outputs, err := protobuf.Generate(parsed)
+	if err != nil {
+		return err
+	}
+	if o.Output == "-" {
+		for _, out := range outputs {
+			if _, err := os.Stdout.Write(out.File.Bytes()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	dir := o.Output
+	if dir == "" {
+		dir = "."
+	}
+	for _, out := range outputs {
+		outputFile := filepath.Join(dir, out.Name)
+		if _, err := os.Stat(outputFile); err == nil && !o.Force {
+			return fmt.Errorf("output file already exist, use --force to overwrite: %s", outputFile)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, out := range outputs {
+		outputFile := filepath.Join(dir, out.Name)
+		if err := os.WriteFile(outputFile, out.File.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to create output file: %s", outputFile)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package options
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kcl-lang.io/cli/pkg/fs"
+	"kcl-lang.io/cli/pkg/import/tree"
+	"kcl-lang.io/kcl-go/pkg/tools/gen"
+)
+
+// importTree converts the JSON, YAML and TOML files of the input directories
+// to the KCL modules of a package in the output directory, keeping the
+// directory structure. The mode of each file is detected automatically. The
+// files that fail to convert are reported together once the others are
+// written.
+func (o *ImportOptions) importTree(inputs []string) error {
+	roots := map[string][]string{}
+	for _, input := range inputs {
+		if fs.IsDir(input) {
+			files, err := fs.GetAllFilesInFolder(input, o.Recursive)
+			if err != nil {
+				return err
+			}
+			roots[input] = append(roots[input], files...)
+			continue
+		}
+		files, err := fs.ExpandIfFilePattern(input, false)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			roots[f] = append(roots[f], f)
+		}
+	}
+	entries := tree.Plan(roots)
+	if len(entries) == 0 {
+		return fmt.Errorf("no %s files found in %s", strings.Join(tree.Extensions, ", "), strings.Join(inputs, ", "))
+	}
+	if err := os.MkdirAll(o.OutputDir, 0755); err != nil {
+		return err
+	}
+	var imported []*tree.Entry
+	var failures []string
+	var mainErr error
+	for _, e := range entries {
+		if err := o.importTreeEntry(e); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", e.Source, err))
+			continue
+		}
+		imported = append(imported, e)
+	}
+	if len(imported) > 0 {
+		if err := ensureModFile(o.OutputDir); err != nil {
+			return err
+		}
+		mainFile := filepath.Join(o.OutputDir, tree.MainFile)
+		if _, err := os.Stat(mainFile); err == nil && !o.Force {
+			mainErr = fmt.Errorf("output file already exist, use --force to overwrite: %s", mainFile)
+		} else if err := os.WriteFile(mainFile, tree.Main(imported).Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to create output file: %s", mainFile)
+		}
+	}
+	// The summary is only reported when files failed to import.
+	var failed error
+	if len(failures) > 0 {
+		failed = fmt.Errorf("failed to import %d of %d files:\n  %s", len(failures), len(entries), strings.Join(failures, "\n  "))
+	}
+	return errors.Join(failed, mainErr)
+}
+
+func (o *ImportOptions) importTreeEntry(e *tree.Entry) error {
+	outputFile := filepath.Join(o.OutputDir, e.Output)
+	if _, err := os.Stat(outputFile); err == nil && !o.Force {
+		return fmt.Errorf("output file already exist, use --force to overwrite: %s", outputFile)
+	}
+	if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
+		return err
+	}
+	outputWriter, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %s", outputFile)
+	}
+	err = gen.GenKcl(outputWriter, e.Source, nil, &gen.GenKclOptions{Mode: gen.ModeAuto})
+	if closeErr := outputWriter.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// Do not leave partial modules in the package.
+		os.Remove(outputFile)
+	}
+	return err
+}
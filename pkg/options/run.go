@@ -421,29 +421,37 @@ func CompileOptionFromCli(o *RunOptions) *opt.CompileOptions {
 // empty option.
 func LoadDepsFrom(path string, quiet bool) (*kcl.Option, error) {
 	o := kcl.NewOption()
+	depsMap, err := ResolveDepsFrom(path, quiet)
+	if err != nil {
+		return o, err
+	}
+	for depName, depPath := range depsMap {
+		o.Merge(kcl.WithExternalPkgs(fmt.Sprintf("%s=%s", depName, depPath)))
+	}
+	return o, nil
+}
+
+// ResolveDepsFrom finds `kcl.mod` recursively from the path and returns
+// the local paths of its resolved dependencies by name. If not found,
+// return an empty map.
+func ResolveDepsFrom(path string, quiet bool) (map[string]string, error) {
 	entry, errEvent := runner.FindRunEntryFrom([]string{path})
 	if errEvent != nil {
-		return o, errEvent
+		return nil, errEvent
 	}
-	if entry.IsLocalFileWithKclMod() {
-		cli, err := client.NewKpmClient()
-		if err != nil {
-			return o, err
-		}
-		if quiet {
-			cli.SetLogWriter(nil)
-		}
-		pkg, err := pkg.LoadKclPkg(entry.PackageSource())
-		if err != nil {
-			return o, err
-		}
-		depsMap, err := cli.ResolveDepsIntoMap(pkg)
-		if err != nil {
-			return o, err
-		}
-		for depName, depPath := range depsMap {
-			o.Merge(kcl.WithExternalPkgs(fmt.Sprintf("%s=%s", depName, depPath)))
-		}
+	if !entry.IsLocalFileWithKclMod() {
+		return map[string]string{}, nil
 	}
-	return o, nil
+	cli, err := client.NewKpmClient()
+	if err != nil {
+		return nil, err
+	}
+	if quiet {
+		cli.SetLogWriter(nil)
+	}
+	pkg, err := pkg.LoadKclPkg(entry.PackageSource())
+	if err != nil {
+		return nil, err
+	}
+	return cli.ResolveDepsIntoMap(pkg)
 }
@@ -0,0 +1,187 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package source
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// The types below decode the fields of the AST JSON of the KCL parser used
+// by this package. Statements and expressions are tagged by their `type`,
+// and only the types this package looks into are decoded.
+
+// node is an AST node with its position. Lines are 1-based and columns are
+// 0-based character offsets, the end column being exclusive.
+type node[T any] struct {
+	Node      T   `json:"node"`
+	Line      int `json:"line"`
+	Column    int `json:"column"`
+	EndLine   int `json:"end_line"`
+	EndColumn int `json:"end_column"`
+}
+
+type astModule struct {
+	Body []*node[astStmt] `json:"body"`
+}
+
+type astStmt struct {
+	Type   string
+	Import *astImport
+	Schema *astSchema
+	Rule   *astRule
+	Attr   *astAttr
+	Assign *astAssign
+	// Target is the target of the unification and augmented assignment
+	// statements.
+	Target *astTargeted
+}
+
+func (s *astStmt) UnmarshalJSON(data []byte) error {
+	var tag struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &tag); err != nil {
+		return err
+	}
+	s.Type = tag.Type
+	var v any
+	switch tag.Type {
+	case "Import":
+		s.Import = &astImport{}
+		v = s.Import
+	case "Schema":
+		s.Schema = &astSchema{}
+		v = s.Schema
+	case "Rule":
+		s.Rule = &astRule{}
+		v = s.Rule
+	case "SchemaAttr":
+		s.Attr = &astAttr{}
+		v = s.Attr
+	case "Assign":
+		s.Assign = &astAssign{}
+		v = s.Assign
+	case "Unification", "AugAssign":
+		s.Target = &astTargeted{}
+		v = s.Target
+	default:
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+type astImport struct {
+	Path    *node[string] `json:"path"`
+	Rawpath string        `json:"rawpath"`
+	Asname  *node[string] `json:"asname"`
+}
+
+type astSchema struct {
+	Doc         *node[string]          `json:"doc"`
+	Name        *node[string]          `json:"name"`
+	ParentName  *node[astIdentifier]   `json:"parent_name"`
+	ForHostName *node[astIdentifier]   `json:"for_host_name"`
+	IsMixin     bool                   `json:"is_mixin"`
+	IsProtocol  bool                   `json:"is_protocol"`
+	Mixins      []*node[astIdentifier] `json:"mixins"`
+	Body        []*node[astStmt]       `json:"body"`
+	Decorators  []*node[astCall]       `json:"decorators"`
+	Checks      []*node[astCheck]      `json:"checks"`
+}
+
+type astRule struct {
+	Doc         *node[string]          `json:"doc"`
+	Name        *node[string]          `json:"name"`
+	ParentRules []*node[astIdentifier] `json:"parent_rules"`
+	ForHostName *node[astIdentifier]   `json:"for_host_name"`
+	Decorators  []*node[astCall]       `json:"decorators"`
+	Checks      []*node[astCheck]      `json:"checks"`
+}
+
+type astAttr struct {
+	Name       *node[string]          `json:"name"`
+	Value      *node[astExpr]         `json:"value"`
+	IsOptional bool                   `json:"is_optional"`
+	Decorators []*node[astCall]       `json:"decorators"`
+	Ty         *node[json.RawMessage] `json:"ty"`
+}
+
+// astAssign is an assignment. Its targets, such as `a.b`, are read from the
+// source text.
+type astAssign struct {
+	Targets []*node[json.RawMessage] `json:"targets"`
+	Value   *node[astExpr]           `json:"value"`
+}
+
+type astTargeted struct {
+	Target *node[json.RawMessage] `json:"target"`
+}
+
+type astIdentifier struct {
+	Names []*node[string] `json:"names"`
+}
+
+// identText returns the dotted name of an identifier, such as `app.App`.
+func identText(id *node[astIdentifier]) string {
+	if id == nil {
+		return ""
+	}
+	names := make([]string, 0, len(id.Node.Names))
+	for _, n := range id.Node.Names {
+		names = append(names, n.Node)
+	}
+	return strings.Join(names, ".")
+}
+
+type astExpr struct {
+	Type       string
+	Identifier *astIdentifier
+	Lambda     *astLambda
+	StringLit  *astStringLit
+}
+
+func (e *astExpr) UnmarshalJSON(data []byte) error {
+	var tag struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &tag); err != nil {
+		return err
+	}
+	e.Type = tag.Type
+	var v any
+	switch tag.Type {
+	case "Identifier":
+		e.Identifier = &astIdentifier{}
+		v = e.Identifier
+	case "Lambda":
+		e.Lambda = &astLambda{}
+		v = e.Lambda
+	case "StringLit":
+		e.StringLit = &astStringLit{}
+		v = e.StringLit
+	default:
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+type astLambda struct {
+	Args *node[json.RawMessage] `json:"args"`
+	Body []*node[astStmt]       `json:"body"`
+}
+
+type astStringLit struct {
+	Value string `json:"value"`
+}
+
+// astCall is a call expression, such as a decorator.
+type astCall struct {
+	Func *node[astExpr] `json:"func"`
+}
+
+type astCheck struct {
+	Test   *node[astExpr] `json:"test"`
+	IfCond *node[astExpr] `json:"if_cond"`
+	Msg    *node[astExpr] `json:"msg"`
+}
@@ -0,0 +1,232 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package source
+
+import (
+	"strings"
+)
+
+// builder builds the structural view of a file from its AST.
+type builder struct {
+	f *File
+	// text are the physical lines of the source.
+	text []string
+}
+
+func (b *builder) statement(n *node[astStmt]) {
+	f := b.f
+	st := &Statement{Start: n.Line, End: n.EndLine}
+	s := n.Node
+	switch {
+	case s.Import != nil:
+		imp := &Import{Path: s.Import.Rawpath, Line: n.Line}
+		if imp.Path == "" && s.Import.Path != nil {
+			imp.Path = s.Import.Path.Node
+		}
+		if s.Import.Asname != nil {
+			imp.Alias = s.Import.Asname.Node
+		}
+		f.Imports = append(f.Imports, imp)
+		st.Kind, st.Name = ImportStmt, imp.Name()
+	case s.Schema != nil:
+		sc := b.schema(n, s.Schema)
+		f.Schemas = append(f.Schemas, sc)
+		st.Kind, st.Name = SchemaStmt, sc.Name
+		st.Start = decoratedStart(st.Start, s.Schema.Decorators)
+	case s.Rule != nil:
+		sc := b.rule(n, s.Rule)
+		f.Schemas = append(f.Schemas, sc)
+		st.Kind, st.Name = SchemaStmt, sc.Name
+		st.Start = decoratedStart(st.Start, s.Rule.Decorators)
+	case s.Assign != nil && len(s.Assign.Targets) > 0:
+		st.Kind, st.Name = AssignStmt, nodeText(b, s.Assign.Targets[0])
+		if v := s.Assign.Value; v != nil && v.Node.Lambda != nil {
+			f.Lambdas = append(f.Lambdas, b.lambda(n, st.Name, v.Node.Lambda))
+			st.Kind = LambdaStmt
+		}
+	case s.Target != nil && s.Target.Target != nil:
+		st.Kind, st.Name = AssignStmt, nodeText(b, s.Target.Target)
+	default:
+		st.Kind = ExprStmt
+	}
+	st.Lines = b.lines(st.Start, st.End)
+	f.Statements = append(f.Statements, st)
+}
+
+func (b *builder) schema(n *node[astStmt], s *astSchema) *Schema {
+	sc := &Schema{
+		Keyword:    "schema",
+		Name:       s.Name.Node,
+		Base:       identText(s.ParentName),
+		Protocol:   identText(s.ForHostName),
+		Doc:        docText(s.Doc),
+		Decorators: b.decorators(s.Decorators),
+		Checks:     b.checks(s.Checks),
+		Line:       s.Name.Line,
+		End:        n.EndLine,
+	}
+	if s.IsMixin {
+		sc.Keyword = "mixin"
+	} else if s.IsProtocol {
+		sc.Keyword = "protocol"
+	}
+	for _, m := range s.Mixins {
+		sc.Mixins = append(sc.Mixins, identText(m))
+	}
+	for _, st := range s.Body {
+		if attr := st.Node.Attr; attr != nil && attr.Name != nil {
+			a := &Attribute{
+				Name:       attr.Name.Node,
+				Optional:   attr.IsOptional,
+				Decorators: b.decorators(attr.Decorators),
+				Line:       attr.Name.Line,
+			}
+			if attr.Ty != nil {
+				a.Type = nodeText(b, attr.Ty)
+			}
+			if attr.Value != nil {
+				a.Default = nodeText(b, attr.Value)
+			}
+			sc.Attributes = append(sc.Attributes, a)
+		}
+	}
+	sc.Body = b.lines(sc.Line+1, sc.End)
+	return sc
+}
+
+func (b *builder) rule(n *node[astStmt], r *astRule) *Schema {
+	sc := &Schema{
+		Keyword:    "rule",
+		Name:       r.Name.Node,
+		Protocol:   identText(r.ForHostName),
+		Doc:        docText(r.Doc),
+		Decorators: b.decorators(r.Decorators),
+		Checks:     b.checks(r.Checks),
+		Line:       r.Name.Line,
+		End:        n.EndLine,
+	}
+	var parents []string
+	for _, p := range r.ParentRules {
+		parents = append(parents, identText(p))
+	}
+	sc.Base = strings.Join(parents, ", ")
+	sc.Body = b.lines(sc.Line+1, sc.End)
+	return sc
+}
+
+func (b *builder) lambda(n *node[astStmt], name string, l *astLambda) *Lambda {
+	lam := &Lambda{Name: name, Line: n.Line, End: n.EndLine}
+	if l.Args != nil {
+		lam.Params = nodeText(b, l.Args)
+	}
+	for _, st := range l.Body {
+		if st.Line > n.Line {
+			lam.Body = append(lam.Body, b.lines(st.Line, st.EndLine)...)
+			continue
+		}
+		// The statement shares the line of the lambda header.
+		lam.Body = append(lam.Body, Line{Start: st.Line, End: st.EndLine, Indent: st.Column, Text: nodeText(b, st)})
+	}
+	return lam
+}
+
+func (b *builder) decorators(calls []*node[astCall]) []*Decorator {
+	var decorators []*Decorator
+	for _, c := range calls {
+		fn := c.Node.Func
+		if fn == nil || fn.Node.Identifier == nil {
+			continue
+		}
+		d := &Decorator{Name: identText(&node[astIdentifier]{Node: *fn.Node.Identifier}), Line: c.Line}
+		args := strings.TrimSpace(b.slice(fn.EndLine, fn.EndColumn, c.EndLine, c.EndColumn))
+		if strings.HasPrefix(args, "(") && strings.HasSuffix(args, ")") {
+			d.Args = strings.TrimSpace(args[1 : len(args)-1])
+		}
+		decorators = append(decorators, d)
+	}
+	return decorators
+}
+
+func (b *builder) checks(checks []*node[astCheck]) []*Check {
+	var out []*Check
+	for _, c := range checks {
+		test := c.Node.Test
+		if test == nil {
+			continue
+		}
+		end := test
+		if c.Node.IfCond != nil {
+			end = c.Node.IfCond
+		}
+		ck := &Check{
+			Expr: b.slice(test.Line, test.Column, end.EndLine, end.EndColumn),
+			Line: test.Line,
+		}
+		if msg := c.Node.Msg; msg != nil && msg.Node.StringLit != nil {
+			ck.Message = msg.Node.StringLit.Value
+		}
+		out = append(out, ck)
+	}
+	return out
+}
+
+// lines returns the logical lines starting in the line range.
+func (b *builder) lines(start, end int) []Line {
+	var lines []Line
+	for _, l := range b.f.Lines {
+		if l.Start >= start && l.Start <= end {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// slice returns the source text of a range of positions, trimmed.
+func (b *builder) slice(line, col, endLine, endCol int) string {
+	if line < 1 || endLine < line || endLine > len(b.text) {
+		return ""
+	}
+	var sb strings.Builder
+	for l := line; l <= endLine; l++ {
+		r := []rune(strings.TrimSuffix(b.text[l-1], "\r"))
+		from, to := 0, len(r)
+		if l == line {
+			from = min(col, len(r))
+		}
+		if l == endLine {
+			to = min(endCol, len(r))
+		}
+		if from < to {
+			sb.WriteString(string(r[from:to]))
+		}
+		if l < endLine {
+			sb.WriteByte('\n')
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// nodeText returns the source text of a node.
+func nodeText[T any](b *builder, n *node[T]) string {
+	return b.slice(n.Line, n.Column, n.EndLine, n.EndColumn)
+}
+
+// decoratedStart returns the first line of a statement with its decorators.
+func decoratedStart(start int, decorators []*node[astCall]) int {
+	for _, d := range decorators {
+		start = min(start, d.Line)
+	}
+	return start
+}
+
+// docText returns the value of a docstring, which the parser keeps quoted.
+func docText(doc *node[string]) string {
+	if doc == nil {
+		return ""
+	}
+	text := strings.TrimSpace(doc.Node)
+	if s, ok := StringLiteral(text); ok {
+		return s
+	}
+	return text
+}
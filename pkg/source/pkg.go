@@ -0,0 +1,175 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	// KclFileExt is the file extension of KCL source files.
+	KclFileExt = ".k"
+	// TestFileSuffix is the file name suffix of KCL test files.
+	TestFileSuffix = "_test.k"
+	// ModFile is the KCL module manifest file name.
+	ModFile = "kcl.mod"
+)
+
+// Package is the set of KCL files in one directory.
+type Package struct {
+	// Dir is the package directory.
+	Dir string
+	// Files are the non-test source files of the package.
+	Files []*File
+	// TestFiles are the `*_test.k` files of the package.
+	TestFiles []*File
+}
+
+// IsTestFile reports whether path names a KCL test file.
+func IsTestFile(path string) bool {
+	return strings.HasSuffix(path, TestFileSuffix)
+}
+
+// IsKclFile reports whether path names a KCL source file.
+func IsKclFile(path string) bool {
+	return filepath.Ext(path) == KclFileExt
+}
+
+// LoadPackage reads and parses all the KCL files in dir.
+func LoadPackage(dir string) (*Package, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	p := &Package{Dir: dir}
+	for _, e := range entries {
+		if e.IsDir() || !IsKclFile(e.Name()) {
+			continue
+		}
+		f, err := ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if IsTestFile(e.Name()) {
+			p.TestFiles = append(p.TestFiles, f)
+		} else {
+			p.Files = append(p.Files, f)
+		}
+	}
+	return p, nil
+}
+
+// PackageDirs expands the package patterns accepted by `kcl test`, such as
+// `.`, `pkg` or `./...`, into the list of directories containing KCL files.
+func PackageDirs(patterns []string) ([]string, error) {
+	seen := map[string]bool{}
+	var dirs []string
+	add := func(dir string) {
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	for _, p := range patterns {
+		if root, ok := strings.CutSuffix(p, "..."); ok {
+			root = filepath.Clean(strings.TrimSuffix(root, "/"))
+			if root == "" {
+				root = "."
+			}
+			err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.IsDir() && path != root && skipDir(d.Name()) {
+					return filepath.SkipDir
+				}
+				if !d.IsDir() && IsKclFile(path) {
+					add(filepath.Dir(path))
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if IsKclFile(p) {
+			p = filepath.Dir(p)
+		}
+		add(filepath.Clean(p))
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+func skipDir(name string) bool {
+	return strings.HasPrefix(name, ".") || name == "vendor" || name == "__snapshots__"
+}
+
+// FindModRoot returns the closest directory at or above dir that contains a
+// kcl.mod file. It returns dir itself when no kcl.mod is found.
+func FindModRoot(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+	for d := abs; ; {
+		if _, err := os.Stat(filepath.Join(d, ModFile)); err == nil {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return abs
+		}
+		d = parent
+	}
+}
+
+// ModName returns the package name declared in the kcl.mod file of the
+// module root, or the base name of root when it is not declared.
+func ModName(root string) string {
+	var mod struct {
+		Package struct {
+			Name string `toml:"name"`
+		} `toml:"package"`
+	}
+	if _, err := toml.DecodeFile(filepath.Join(root, ModFile), &mod); err == nil && mod.Package.Name != "" {
+		return mod.Package.Name
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return filepath.Base(root)
+	}
+	return filepath.Base(abs)
+}
+
+// ResolveImport returns the local directory or file an import path refers to
+// when imported from a file in dir, using root as the package root. It
+// returns false for imports that are not local, such as system modules and
+// external dependencies.
+func ResolveImport(root, dir, importPath string) (string, bool) {
+	var base, rel string
+	if strings.HasPrefix(importPath, ".") {
+		trimmed := strings.TrimLeft(importPath, ".")
+		base = dir
+		for i := 1; i < len(importPath)-len(trimmed); i++ {
+			base = filepath.Dir(base)
+		}
+		rel = trimmed
+	} else {
+		base, rel = root, importPath
+	}
+	target := filepath.Join(base, filepath.FromSlash(strings.ReplaceAll(rel, ".", "/")))
+	if fi, err := os.Stat(target); err == nil && fi.IsDir() {
+		return target, true
+	}
+	if _, err := os.Stat(target + KclFileExt); err == nil {
+		return target + KclFileExt, true
+	}
+	return "", false
+}
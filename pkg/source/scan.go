@@ -0,0 +1,314 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package source
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Line is a logical line of KCL code. A logical line may span several
+// physical lines when it contains open brackets, multi-line strings or
+// explicit `\` continuations.
+type Line struct {
+	// Start is the 1-based physical line where the logical line begins.
+	Start int
+	// End is the 1-based physical line where the logical line ends.
+	End int
+	// Indent is the indentation width of the first physical line.
+	Indent int
+	// Offset is the byte offset of the first non-blank character.
+	Offset int
+	// Text is the code of the logical line with comments removed.
+	// Physical line breaks inside the logical line are preserved.
+	Text string
+}
+
+// Comment is a `#` comment found in KCL source.
+type Comment struct {
+	// Line is the 1-based physical line of the comment.
+	Line int
+	// Text is the comment text without the leading `#`.
+	Text string
+	// Trailing reports whether the comment follows code on the same line.
+	Trailing bool
+}
+
+// Scan splits KCL source into logical lines and comments. It never fails:
+// malformed input such as unterminated strings is scanned on a best-effort
+// basis so that tooling can still report on partially written files.
+func Scan(src []byte) ([]Line, []Comment) {
+	s := &scanner{src: string(src), line: 1}
+	s.run()
+	return s.lines, s.comments
+}
+
+type scanner struct {
+	src      string
+	pos      int
+	line     int
+	lines    []Line
+	comments []Comment
+
+	// Current logical line state.
+	buf      strings.Builder
+	start    int
+	indent   int
+	offset   int
+	depth    int
+	hasCode  bool
+	atLineSt bool
+}
+
+func (s *scanner) run() {
+	s.atLineSt = true
+	for s.pos < len(s.src) {
+		if s.atLineSt && s.depth == 0 && !s.hasCode {
+			s.beginLine()
+			if s.pos >= len(s.src) {
+				break
+			}
+		}
+		c := s.src[s.pos]
+		switch {
+		case c == '#':
+			s.scanComment()
+		case c == '"' || c == '\'':
+			s.scanString()
+		case c == '\\' && s.pos+1 < len(s.src) && (s.src[s.pos+1] == '\n' || s.src[s.pos+1] == '\r'):
+			// Explicit line continuation.
+			s.pos++
+			if s.src[s.pos] == '\r' && s.pos+1 < len(s.src) && s.src[s.pos+1] == '\n' {
+				s.pos++
+			}
+			s.pos++
+			s.line++
+			s.buf.WriteByte('\n')
+		case c == '\n':
+			s.pos++
+			if s.depth > 0 {
+				s.buf.WriteByte('\n')
+				s.line++
+				continue
+			}
+			s.flush(s.line)
+			s.line++
+			s.atLineSt = true
+		case c == '\r':
+			s.pos++
+		default:
+			switch c {
+			case '(', '[', '{':
+				s.depth++
+			case ')', ']', '}':
+				if s.depth > 0 {
+					s.depth--
+				}
+			}
+			if !unicode.IsSpace(rune(c)) {
+				s.hasCode = true
+			}
+			s.buf.WriteByte(c)
+			s.pos++
+		}
+	}
+	s.flush(s.line)
+}
+
+// beginLine consumes the indentation of a new physical line.
+func (s *scanner) beginLine() {
+	width := 0
+	for s.pos < len(s.src) {
+		switch s.src[s.pos] {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			s.indent = width
+			s.offset = s.pos
+			s.start = s.line
+			s.atLineSt = false
+			return
+		}
+		s.pos++
+	}
+	s.atLineSt = false
+}
+
+func (s *scanner) flush(end int) {
+	text := strings.TrimRight(s.buf.String(), " \t\n")
+	if s.hasCode && text != "" {
+		s.lines = append(s.lines, Line{
+			Start:  s.start,
+			End:    end,
+			Indent: s.indent,
+			Offset: s.offset,
+			Text:   text,
+		})
+	}
+	s.buf.Reset()
+	s.hasCode = false
+	s.depth = 0
+}
+
+func (s *scanner) scanComment() {
+	begin := s.pos + 1
+	for s.pos < len(s.src) && s.src[s.pos] != '\n' {
+		s.pos++
+	}
+	s.comments = append(s.comments, Comment{
+		Line:     s.line,
+		Text:     strings.TrimRight(s.src[begin:s.pos], "\r"),
+		Trailing: s.hasCode,
+	})
+}
+
+func (s *scanner) scanString() {
+	q := s.src[s.pos]
+	triple := strings.HasPrefix(s.src[s.pos:], strings.Repeat(string(q), 3))
+	s.hasCode = true
+	if triple {
+		s.buf.WriteString(s.src[s.pos : s.pos+3])
+		s.pos += 3
+	} else {
+		s.buf.WriteByte(q)
+		s.pos++
+	}
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case c == '\\' && s.pos+1 < len(s.src):
+			if s.src[s.pos+1] == '\n' {
+				s.line++
+			}
+			s.buf.WriteString(s.src[s.pos : s.pos+2])
+			s.pos += 2
+			continue
+		case c == '\n':
+			if !triple {
+				// Unterminated single-line string, end it here.
+				return
+			}
+			s.line++
+		case c == q:
+			if !triple {
+				s.buf.WriteByte(c)
+				s.pos++
+				return
+			}
+			if strings.HasPrefix(s.src[s.pos:], strings.Repeat(string(q), 3)) {
+				s.buf.WriteString(s.src[s.pos : s.pos+3])
+				s.pos += 3
+				return
+			}
+		}
+		s.buf.WriteByte(c)
+		s.pos++
+	}
+}
+
+// SplitTop splits text on sep when sep appears outside of brackets and
+// string literals. Multi-character separators are matched literally.
+func SplitTop(text, sep string) []string {
+	var parts []string
+	depth := 0
+	last := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch c {
+		case '"', '\'':
+			i = SkipString(text, i) - 1
+			continue
+		}
+		if depth == 0 && i >= last && strings.HasPrefix(text[i:], sep) {
+			parts = append(parts, text[last:i])
+			last = i + len(sep)
+		}
+		switch c {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+	return append(parts, text[last:])
+}
+
+// SkipString returns the index just past the string literal starting at
+// index i of text, or len(text) when the literal is unterminated.
+func SkipString(text string, i int) int {
+	q := text[i]
+	n := 1
+	if strings.HasPrefix(text[i:], strings.Repeat(string(q), 3)) {
+		n = 3
+	}
+	closing := strings.Repeat(string(q), n)
+	for j := i + n; j < len(text); j++ {
+		if text[j] == '\\' {
+			j++
+			continue
+		}
+		if strings.HasPrefix(text[j:], closing) {
+			return j + n
+		}
+	}
+	return len(text)
+}
+
+// Idents returns the identifiers referenced in a piece of KCL code in order
+// of appearance. Dotted references such as `app.App` are returned as a
+// single identifier. String literal contents are ignored, except for the
+// expressions interpolated with `${...}`.
+func Idents(text string) []string {
+	var idents []string
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '"' || c == '\'':
+			end := SkipString(text, i)
+			idents = append(idents, interpolations(text[i:end])...)
+			i = end
+		case c == '$' || c == '_' || isLetter(c):
+			j := i + 1
+			for j < len(text) && (isIdentChar(text[j]) || (text[j] == '.' && j+1 < len(text) && (isLetter(text[j+1]) || text[j+1] == '_' || text[j+1] == '$'))) {
+				j++
+			}
+			// Skip attribute access on call results or literals, e.g. `a().b`.
+			if i == 0 || (text[i-1] != '.' && !isIdentChar(text[i-1])) {
+				idents = append(idents, text[i:j])
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+	return idents
+}
+
+func interpolations(lit string) []string {
+	var idents []string
+	for {
+		i := strings.Index(lit, "${")
+		if i < 0 {
+			return idents
+		}
+		lit = lit[i+2:]
+		j := strings.Index(lit, "}")
+		if j < 0 {
+			return idents
+		}
+		idents = append(idents, Idents(lit[:j])...)
+		lit = lit[j+1:]
+	}
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isLetter(c) || (c >= '0' && c <= '9') || c == '_' || c == '$'
+}
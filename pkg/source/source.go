@@ -0,0 +1,252 @@
+// Copyright The KCL Authors. All rights reserved.
+
+// Package source provides a structural view of KCL source files for CLI
+// tooling such as reachability, linting and dependency tracking. The view is
+// built from the AST of the KCL parser: the top-level statements of a file
+// (imports, schemas, lambdas and assignments) with their line ranges. The
+// logical lines and comments of the file are kept along with it for the
+// tools editing the source text.
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"kcl-lang.io/kcl-go/pkg/parser"
+)
+
+// StmtKind is the kind of a top-level statement.
+type StmtKind string
+
+const (
+	ImportStmt StmtKind = "import"
+	SchemaStmt StmtKind = "schema"
+	LambdaStmt StmtKind = "lambda"
+	AssignStmt StmtKind = "assign"
+	ExprStmt   StmtKind = "expr"
+)
+
+// File is the structural view of a KCL source file.
+type File struct {
+	// Path is the file path the source was read from, if any.
+	Path string
+	// Src is the raw file content.
+	Src []byte
+	// Lines are the logical lines of the file.
+	Lines []Line
+	// Comments are all the comments of the file.
+	Comments []Comment
+	// Statements are the top-level statements in source order.
+	Statements []*Statement
+	// Imports are the import statements of the file.
+	Imports []*Import
+	// Schemas are the schema, mixin, protocol and rule definitions.
+	Schemas []*Schema
+	// Lambdas are the top-level lambda assignments.
+	Lambdas []*Lambda
+}
+
+// Statement is a top-level statement together with its nested body.
+type Statement struct {
+	Kind StmtKind
+	// Name is the imported module, schema, lambda or assigned variable name.
+	Name string
+	// Start and End are the 1-based physical line range of the statement,
+	// including its decorators and body.
+	Start int
+	End   int
+	// Lines are the logical lines of the statement.
+	Lines []Line
+}
+
+// Import is a KCL import statement.
+type Import struct {
+	// Path is the dotted import path, e.g. `k8s.api.apps.v1` or `.sub`.
+	Path string
+	// Alias is the `as` name of the import, if any.
+	Alias string
+	// Line is the 1-based line of the import.
+	Line int
+}
+
+// Name returns the name the import is bound to in the importing file.
+func (i *Import) Name() string {
+	if i.Alias != "" {
+		return i.Alias
+	}
+	parts := strings.Split(strings.TrimLeft(i.Path, "."), ".")
+	return parts[len(parts)-1]
+}
+
+// Schema is a schema-like definition: `schema`, `mixin`, `protocol` or `rule`.
+type Schema struct {
+	// Keyword is one of schema, mixin, protocol or rule.
+	Keyword string
+	Name    string
+	// Base is the parent schema reference, e.g. `base.Base`.
+	Base string
+	// Protocol is the `for` protocol reference of mixins and rules.
+	Protocol string
+	// Mixins are the references listed in the `mixin [...]` statement.
+	Mixins []string
+	// Doc is the docstring of the schema.
+	Doc string
+	// Decorators are the decorators applied to the schema.
+	Decorators []*Decorator
+	// Attributes are the attributes declared in the schema body.
+	Attributes []*Attribute
+	// Checks are the expressions of the `check` block.
+	Checks []*Check
+	// Body are the logical lines of the schema body.
+	Body []Line
+	// Line is the 1-based line of the schema header.
+	Line int
+	// End is the 1-based last line of the schema body.
+	End int
+}
+
+// Attribute is a schema attribute declaration.
+type Attribute struct {
+	Name     string
+	Type     string
+	Default  string
+	Optional bool
+	// Decorators are the decorators applied to the attribute.
+	Decorators []*Decorator
+	// Line is the 1-based line of the declaration.
+	Line int
+}
+
+// Decorator is a decorator such as `@deprecated(version="1.16")`.
+type Decorator struct {
+	Name string
+	Args string
+	Line int
+}
+
+// Check is an expression of a schema `check` block.
+type Check struct {
+	// Expr is the checked expression, including any `if` guard.
+	Expr string
+	// Message is the error message literal, if any.
+	Message string
+	Line    int
+}
+
+// Lambda is a top-level lambda assignment `name = lambda ... { ... }`.
+type Lambda struct {
+	Name string
+	// Params is the raw parameter list text.
+	Params string
+	// Body are the logical lines of the lambda body.
+	Body []Line
+	Line int
+	End  int
+}
+
+var stringLitRe = regexp.MustCompile(`^[rRbBfF]?("""|'''|"|')`)
+
+// ReadFile reads and parses the KCL file at path.
+func ReadFile(path string) (*File, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFile(path, src)
+}
+
+// Parse parses KCL source into a File. The File of a source with syntax
+// errors only has its lines and comments.
+func Parse(src []byte) *File {
+	f, err := ParseFile("", src)
+	if err != nil {
+		lines, comments := Scan(src)
+		return &File{Src: src, Lines: lines, Comments: comments}
+	}
+	return f
+}
+
+// ParseFile parses the KCL source of the file at path with the KCL parser
+// into a File.
+func ParseFile(path string, src []byte) (*File, error) {
+	astJSON, err := parser.ParseFileASTJson(path, string(src))
+	if err != nil {
+		return nil, err
+	}
+	var m astModule
+	if err := json.Unmarshal([]byte(astJSON), &m); err != nil {
+		return nil, fmt.Errorf("invalid AST of %s: %w", path, err)
+	}
+	lines, comments := Scan(src)
+	f := &File{Path: path, Src: src, Lines: lines, Comments: comments}
+	b := &builder{f: f, text: strings.Split(string(src), "\n")}
+	for _, n := range m.Body {
+		b.statement(n)
+	}
+	return f, nil
+}
+
+// Schema returns the schema named name defined in the file, or nil.
+func (f *File) Schema(name string) *Schema {
+	for _, s := range f.Schemas {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Lambda returns the top-level lambda named name defined in the file, or nil.
+func (f *File) Lambda(name string) *Lambda {
+	for _, l := range f.Lambdas {
+		if l.Name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// Attribute returns the attribute named name declared in the schema, or nil.
+func (s *Schema) Attribute(name string) *Attribute {
+	for _, a := range s.Attributes {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// Decorator returns the decorator named name, or nil.
+func (a *Attribute) Decorator(name string) *Decorator {
+	for _, d := range a.Decorators {
+		if d.Name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// StringLiteral returns the value of text when it is a single string
+// literal. Escape sequences other than quotes are kept verbatim.
+func StringLiteral(text string) (string, bool) {
+	m := stringLitRe.FindStringSubmatch(text)
+	if m == nil {
+		return "", false
+	}
+	q := m[1]
+	body := text[len(m[0]):]
+	if !strings.HasSuffix(body, q) || len(body) < len(q) {
+		return "", false
+	}
+	body = body[:len(body)-len(q)]
+	if len(q) == 1 && strings.Contains(strings.ReplaceAll(body, `\`+q, ""), q) {
+		return "", false
+	}
+	if len(q) == 3 {
+		return strings.TrimSpace(body), true
+	}
+	return strings.ReplaceAll(body, `\`+q, q), true
+}
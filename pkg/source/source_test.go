@@ -0,0 +1,154 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testSource = `import app
+import .sub.base as b
+
+# The port model.
+@info(version="v1")
+schema Port(b.Base):
+    """The port model."""
+    port: int
+    protocol: "TCP" | "UDP" = "TCP"  # protocol
+    @deprecated(strict=False)
+    targetPort?: int | str
+    labels?: {str:str} = {
+        app = "nginx"
+    }
+
+    check:
+        1 <= port <= 65535, "port must be between 1 and 65535"
+        protocol in ["TCP", "UDP"] if targetPort
+
+render = lambda p: Port -> {str:} {
+    _name = "${p.protocol}-svc"
+    {name = _name}
+}
+
+config = app.App {
+    name = "app"
+}
+`
+
+func TestParse(t *testing.T) {
+	f, err := ParseFile("main.k", []byte(testSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(f.Imports), 2; got != want {
+		t.Fatalf("imports: got %d, want %d", got, want)
+	}
+	if imp := f.Imports[1]; imp.Path != ".sub.base" || imp.Name() != "b" || imp.Line != 2 {
+		t.Errorf("unexpected import: %+v", imp)
+	}
+
+	s := f.Schema("Port")
+	if s == nil {
+		t.Fatal("schema Port not found")
+	}
+	if s.Base != "b.Base" || s.Doc != "The port model." || s.Line != 6 || s.End != 18 {
+		t.Errorf("unexpected schema: %+v", s)
+	}
+	if len(s.Decorators) != 1 || s.Decorators[0].Name != "info" {
+		t.Errorf("unexpected schema decorators: %+v", s.Decorators)
+	}
+	var names []string
+	for _, a := range s.Attributes {
+		names = append(names, a.Name)
+	}
+	if want := []string{"port", "protocol", "targetPort", "labels"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("attributes: got %v, want %v", names, want)
+	}
+	if a := s.Attribute("protocol"); a.Type != `"TCP" | "UDP"` || a.Default != `"TCP"` {
+		t.Errorf("unexpected attribute: %+v", a)
+	}
+	if a := s.Attribute("targetPort"); !a.Optional || a.Decorator("deprecated") == nil || a.Line != 11 {
+		t.Errorf("unexpected attribute: %+v", a)
+	}
+	if len(s.Checks) != 2 {
+		t.Fatalf("checks: got %d, want 2", len(s.Checks))
+	}
+	if c := s.Checks[0]; c.Expr != "1 <= port <= 65535" || c.Message != "port must be between 1 and 65535" || c.Line != 17 {
+		t.Errorf("unexpected check: %+v", c)
+	}
+
+	lam := f.Lambda("render")
+	if lam == nil {
+		t.Fatal("lambda render not found")
+	}
+	if lam.Params != "p: Port" || len(lam.Body) != 2 || lam.Body[0].Start != 21 || lam.Body[1].Start != 22 {
+		t.Errorf("unexpected lambda: %+v", lam)
+	}
+
+	var kinds []StmtKind
+	for _, st := range f.Statements {
+		kinds = append(kinds, st.Kind)
+	}
+	want := []StmtKind{ImportStmt, ImportStmt, SchemaStmt, LambdaStmt, AssignStmt}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("statements: got %v, want %v", kinds, want)
+	}
+	if st := f.Statements[2]; st.Start != 5 || st.End != 18 {
+		t.Errorf("unexpected schema statement range: %d-%d", st.Start, st.End)
+	}
+}
+
+func TestScanComments(t *testing.T) {
+	lines, comments := Scan([]byte("a = 1 # one\n# two\nb = \"#no\"\n"))
+	if len(lines) != 2 || lines[1].Text != `b = "#no"` {
+		t.Errorf("unexpected lines: %+v", lines)
+	}
+	want := []Comment{{Line: 1, Text: " one", Trailing: true}, {Line: 2, Text: " two"}}
+	if !reflect.DeepEqual(comments, want) {
+		t.Errorf("comments: got %+v, want %+v", comments, want)
+	}
+}
+
+func TestIdents(t *testing.T) {
+	got := Idents(`app.App {name = "${prefix}-x", n = len(items)}`)
+	want := []string{"app.App", "name", "prefix", "n", "len", "items"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestPackageDirsAndResolveImport(t *testing.T) {
+	root := t.TempDir()
+	for _, p := range []string{"kcl.mod", "main.k", "sub/sub.k", "sub/base.k", ".hidden/x.k"} {
+		path := filepath.Join(root, p)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dirs, err := PackageDirs([]string{root + "/..."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{root, filepath.Join(root, "sub")}; !reflect.DeepEqual(dirs, want) {
+		t.Errorf("got %v, want %v", dirs, want)
+	}
+	if got := FindModRoot(filepath.Join(root, "sub")); got != root {
+		t.Errorf("FindModRoot: got %s, want %s", got, root)
+	}
+	if got, ok := ResolveImport(root, root, "sub"); !ok || got != filepath.Join(root, "sub") {
+		t.Errorf("ResolveImport(sub): got %s, %v", got, ok)
+	}
+	if got, ok := ResolveImport(root, filepath.Join(root, "sub"), ".base"); !ok || got != filepath.Join(root, "sub", "base.k") {
+		t.Errorf("ResolveImport(.base): got %s, %v", got, ok)
+	}
+	if _, ok := ResolveImport(root, root, "k8s.api.apps.v1"); ok {
+		t.Error("ResolveImport(k8s...) should not resolve")
+	}
+}
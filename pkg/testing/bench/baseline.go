@@ -0,0 +1,62 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Baseline is the JSON document storing benchmark results for comparison.
+type Baseline struct {
+	Benchmarks []*Result `json:"benchmarks"`
+}
+
+// SaveBaseline writes the results to a baseline JSON file.
+func SaveBaseline(path string, results []*Result) error {
+	sorted := append([]*Result(nil), results...)
+	sortResults(sorted)
+	data, err := json.MarshalIndent(&Baseline{Benchmarks: sorted}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// LoadBaseline reads a baseline JSON file.
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b := &Baseline{}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("invalid benchmark baseline %s: %w", path, err)
+	}
+	return b, nil
+}
+
+// Compare writes the change of each result relative to the baseline.
+// Benchmarks missing from the baseline are reported as new.
+func Compare(w io.Writer, baseline *Baseline, results []*Result) {
+	old := map[string]*Result{}
+	for _, r := range baseline.Benchmarks {
+		old[r.ID] = r
+	}
+	width := 0
+	for _, r := range results {
+		width = max(width, len(r.ID))
+	}
+	fmt.Fprintf(w, "%-*s\t%14s\t%14s\t%8s\n", width, "benchmark", "old ns/op", "new ns/op", "delta")
+	for _, r := range results {
+		prev, ok := old[r.ID]
+		if !ok || prev.NsPerOp == 0 {
+			fmt.Fprintf(w, "%-*s\t%14s\t%14.1f\t%8s\n", width, r.ID, "-", r.NsPerOp, "new")
+			continue
+		}
+		delta := (r.NsPerOp - prev.NsPerOp) * 100 / prev.NsPerOp
+		fmt.Fprintf(w, "%-*s\t%14.1f\t%14.1f\t%+7.2f%%\n", width, r.ID, prev.NsPerOp, r.NsPerOp, delta)
+	}
+}
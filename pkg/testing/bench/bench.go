@@ -0,0 +1,214 @@
+// Copyright The KCL Authors. All rights reserved.
+
+// Package bench runs the `bench_*` lambdas declared in KCL test files.
+//
+// A benchmark program evaluates the lambda n times in a single compilation:
+//
+//	_kcl_bench = [bench_render() for _ in range(n)]
+//
+// The cost of compiling and evaluating the package itself is measured with
+// n = 0 and subtracted, so the reported ns/op only accounts for the lambda.
+package bench
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"kcl-lang.io/cli/pkg/source"
+)
+
+// FuncPrefix is the name prefix of benchmark lambdas.
+const FuncPrefix = "bench_"
+
+const (
+	// DefaultBudget is the default time budget of a benchmark.
+	DefaultBudget = time.Second
+	// maxN is the maximum number of iterations of a benchmark.
+	maxN = 1_000_000_000
+	// baselineRuns is the number of runs used to measure the overhead.
+	baselineRuns = 3
+)
+
+// Benchmark is a benchmark lambda declared in a test file.
+type Benchmark struct {
+	// Name is the lambda name.
+	Name string
+	// Dir is the package directory.
+	Dir string
+	// Pkg is the slash separated path of the package relative to the module
+	// root, which does not depend on how the package was named.
+	Pkg string
+	// File is the test file declaring the benchmark.
+	File string
+	// Files are the package files compiled with the benchmark.
+	Files []string
+}
+
+// ID returns the package qualified benchmark name used in reports and
+// baselines.
+func (b *Benchmark) ID() string {
+	return b.Pkg + ":" + b.Name
+}
+
+// Program returns the KCL code evaluating the benchmark n times.
+func (b *Benchmark) Program(n int) string {
+	return fmt.Sprintf("_kcl_bench = [%s() for _ in range(%d)]\n", b.Name, n)
+}
+
+// Discover returns the benchmarks of the packages in dirs whose name
+// matches the pattern.
+func Discover(dirs []string, pattern string) ([]*Benchmark, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid benchmark pattern %q: %w", pattern, err)
+	}
+	var benches []*Benchmark
+	for _, dir := range dirs {
+		pkg, err := source.LoadPackage(dir)
+		if err != nil {
+			return nil, err
+		}
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(source.FindModRoot(abs), abs)
+		if err != nil {
+			return nil, err
+		}
+		var files []string
+		for _, f := range pkg.Files {
+			files = append(files, f.Path)
+		}
+		for _, f := range pkg.TestFiles {
+			files = append(files, f.Path)
+		}
+		for _, f := range pkg.TestFiles {
+			for _, lam := range f.Lambdas {
+				if !strings.HasPrefix(lam.Name, FuncPrefix) || !re.MatchString(lam.Name) {
+					continue
+				}
+				benches = append(benches, &Benchmark{
+					Name:  lam.Name,
+					Dir:   dir,
+					Pkg:   filepath.ToSlash(rel),
+					File:  f.Path,
+					Files: files,
+				})
+			}
+		}
+	}
+	return benches, nil
+}
+
+// Exec evaluates the benchmark program with n iterations.
+type Exec func(b *Benchmark, n int) error
+
+// Result is the measurement of a benchmark.
+type Result struct {
+	ID string `json:"name"`
+	// N is the number of iterations of the measured run.
+	N int `json:"n"`
+	// NsPerOp is the average time of an iteration in nanoseconds.
+	NsPerOp float64 `json:"ns_per_op"`
+	// AllocsPerOp and BytesPerOp are the average Go heap allocations of an
+	// iteration. Allocations made by the native KCL runtime are not visible
+	// to the Go runtime, so they are only reported when non-zero.
+	AllocsPerOp uint64 `json:"allocs_per_op,omitempty"`
+	BytesPerOp  uint64 `json:"bytes_per_op,omitempty"`
+}
+
+type measure struct {
+	elapsed time.Duration
+	mallocs uint64
+	bytes   uint64
+}
+
+func run(exec Exec, b *Benchmark, n int) (measure, error) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	if err := exec(b, n); err != nil {
+		return measure{}, err
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	return measure{
+		elapsed: elapsed,
+		mallocs: after.Mallocs - before.Mallocs,
+		bytes:   after.TotalAlloc - before.TotalAlloc,
+	}, nil
+}
+
+// Run measures the benchmark, increasing the number of iterations until the
+// lambda evaluation time exceeds the budget.
+func Run(b *Benchmark, exec Exec, budget time.Duration) (*Result, error) {
+	base, err := run(exec, b, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := 1; i < baselineRuns; i++ {
+		m, err := run(exec, b, 0)
+		if err != nil {
+			return nil, err
+		}
+		if m.elapsed < base.elapsed {
+			base = m
+		}
+	}
+	n := 1
+	for {
+		m, err := run(exec, b, n)
+		if err != nil {
+			return nil, err
+		}
+		d := max(m.elapsed-base.elapsed, 0)
+		if d >= budget || n >= maxN {
+			r := &Result{ID: b.ID(), N: n, NsPerOp: float64(d.Nanoseconds()) / float64(n)}
+			if m.mallocs > base.mallocs {
+				r.AllocsPerOp = (m.mallocs - base.mallocs) / uint64(n)
+				r.BytesPerOp = (m.bytes - base.bytes) / uint64(n)
+			}
+			return r, nil
+		}
+		n = predictN(n, d, budget)
+	}
+}
+
+// predictN returns the next number of iterations, aiming 20% past the
+// budget while growing at most 100x and at least by one per round.
+func predictN(n int, d, budget time.Duration) int {
+	next := n * 100
+	if d > 0 {
+		next = int(float64(n) * float64(budget) * 1.2 / float64(d))
+	}
+	next = min(next, n*100, maxN)
+	return max(next, n+1)
+}
+
+// Report writes the results in the `go test -bench` layout.
+func Report(w io.Writer, results []*Result) {
+	width := 0
+	for _, r := range results {
+		width = max(width, len(r.ID))
+	}
+	for _, r := range results {
+		fmt.Fprintf(w, "%-*s\t%10d\t%14.1f ns/op", width, r.ID, r.N, r.NsPerOp)
+		if r.AllocsPerOp > 0 {
+			fmt.Fprintf(w, "\t%10d B/op\t%8d allocs/op", r.BytesPerOp, r.AllocsPerOp)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// sortResults orders results by benchmark ID.
+func sortResults(results []*Result) {
+	sort.Slice(results, func(i, j int) bool { return results[i].ID < results[j].ID })
+}
@@ -0,0 +1,111 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package bench
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiscover(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "pkg", "app")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "kcl.mod"), []byte("[package]\nname = \"app\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"main.k":      "render = lambda {1}\n",
+		"main_test.k": "bench_render = lambda {\n    render()\n}\nbench_other = lambda {\n    1\n}\ntest_render = lambda {\n    assert render() == 1\n}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	benches, err := Discover([]string{dir + "/../app"}, "render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(benches) != 1 || benches[0].Name != "bench_render" || len(benches[0].Files) != 2 {
+		t.Fatalf("unexpected benchmarks: %+v", benches)
+	}
+	// The ID does not depend on how the package directory is named.
+	if got, want := benches[0].ID(), "pkg/app:bench_render"; got != want {
+		t.Errorf("id: got %q, want %q", got, want)
+	}
+	if got, want := benches[0].Program(3), "_kcl_bench = [bench_render() for _ in range(3)]\n"; got != want {
+		t.Errorf("program: got %q, want %q", got, want)
+	}
+	if _, err := Discover([]string{dir}, "("); err == nil {
+		t.Error("expected an invalid pattern error")
+	}
+}
+
+func TestRun(t *testing.T) {
+	b := &Benchmark{Name: "bench_x", Dir: "./pkg", Pkg: "pkg"}
+	var calls []int
+	// Each iteration costs 1ms on top of a fixed 2ms overhead.
+	exec := func(_ *Benchmark, n int) error {
+		calls = append(calls, n)
+		time.Sleep(2*time.Millisecond + time.Duration(n)*time.Millisecond)
+		return nil
+	}
+	r, err := Run(b, exec, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ID != "pkg:bench_x" || r.N < 2 {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	if r.NsPerOp < float64(500*time.Microsecond) || r.NsPerOp > float64(3*time.Millisecond) {
+		t.Errorf("unexpected ns/op: %.0f", r.NsPerOp)
+	}
+	if calls[0] != 0 || calls[1] != 0 || calls[2] != 0 {
+		t.Errorf("expected the overhead to be measured first, got calls %v", calls)
+	}
+}
+
+func TestPredictN(t *testing.T) {
+	tests := []struct {
+		n      int
+		d      time.Duration
+		budget time.Duration
+		want   int
+	}{
+		{n: 1, d: 0, budget: time.Second, want: 100},
+		{n: 10, d: 100 * time.Millisecond, budget: time.Second, want: 120},
+		{n: 10, d: time.Millisecond, budget: time.Second, want: 1000},
+		{n: 10, d: 2 * time.Second, budget: time.Second, want: 11},
+	}
+	for _, tt := range tests {
+		if got := predictN(tt.n, tt.d, tt.budget); got != tt.want {
+			t.Errorf("predictN(%d, %s, %s) = %d, want %d", tt.n, tt.d, tt.budget, got, tt.want)
+		}
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bench.json")
+	if err := SaveBaseline(path, []*Result{{ID: "pkg:bench_a", N: 10, NsPerOp: 100}}); err != nil {
+		t.Fatal(err)
+	}
+	baseline, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	Compare(&buf, baseline, []*Result{
+		{ID: "pkg:bench_a", NsPerOp: 150},
+		{ID: "pkg:bench_b", NsPerOp: 10},
+	})
+	out := buf.String()
+	if !strings.Contains(out, "+50.00%") || !strings.Contains(out, "new") {
+		t.Errorf("unexpected comparison:\n%s", out)
+	}
+}
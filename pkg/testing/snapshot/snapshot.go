@@ -0,0 +1,270 @@
+// Copyright The KCL Authors. All rights reserved.
+
+// Package snapshot implements golden file testing for KCL packages.
+//
+// A snapshot is declared with a `# kcl-test: snapshot <name> [entry...]`
+// comment. In a regular KCL file the entries default to the file itself, in
+// a `*_test.k` file they default to the non-test files of the package. The
+// rendered YAML of the entries is compared with `__snapshots__/<name>.yaml`
+// in the package directory.
+package snapshot
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"kcl-lang.io/cli/pkg/diff"
+	yamlfmt "kcl-lang.io/cli/pkg/format/yaml"
+	"kcl-lang.io/cli/pkg/source"
+)
+
+// Dir is the name of the directory holding the snapshots of a package.
+const Dir = "__snapshots__"
+
+var directiveRe = regexp.MustCompile(`^\s*kcl-test:\s*snapshot(?:\s+(.*))?$`)
+var nameRe = regexp.MustCompile(`^[\w.-]+$`)
+
+// Snapshot is a snapshot declaration.
+type Snapshot struct {
+	// Name is the snapshot name, the file name without the `.yaml` suffix.
+	Name string
+	// Dir is the directory of the package declaring the snapshot.
+	Dir string
+	// Entries are the KCL files rendered for the snapshot.
+	Entries []string
+	// Pos is the `file:line` position of the declaration.
+	Pos string
+}
+
+// Path returns the snapshot file path.
+func (s *Snapshot) Path() string {
+	return filepath.Join(s.Dir, Dir, s.Name+".yaml")
+}
+
+// Renderer renders the KCL entries into a YAML stream.
+type Renderer func(entries []string) (string, error)
+
+// Result is the outcome of a snapshot comparison.
+type Result struct {
+	Snapshot *Snapshot
+	// Diff is the unified diff between the snapshot and the rendered output.
+	Diff string
+	// Missing reports whether the snapshot file does not exist.
+	Missing bool
+	// Updated reports whether the snapshot file was rewritten.
+	Updated bool
+	// Err is the render or comparison error, if any.
+	Err error
+}
+
+// Pass reports whether the snapshot matched or was updated.
+func (r *Result) Pass() bool {
+	return r.Err == nil && !r.Missing && r.Diff == ""
+}
+
+// Discover returns the snapshots declared in the KCL files of dirs.
+func Discover(dirs []string) ([]*Snapshot, error) {
+	var snaps []*Snapshot
+	for _, dir := range dirs {
+		pkg, err := source.LoadPackage(dir)
+		if err != nil {
+			return nil, err
+		}
+		var pkgFiles []string
+		for _, f := range pkg.Files {
+			pkgFiles = append(pkgFiles, f.Path)
+		}
+		files := append(append([]*source.File(nil), pkg.Files...), pkg.TestFiles...)
+		seen := map[string]string{}
+		for _, f := range files {
+			for _, c := range f.Comments {
+				m := directiveRe.FindStringSubmatch(c.Text)
+				if m == nil {
+					continue
+				}
+				pos := fmt.Sprintf("%s:%d", f.Path, c.Line)
+				fields := strings.Fields(m[1])
+				s := &Snapshot{Dir: dir, Pos: pos}
+				if len(fields) > 0 {
+					s.Name = fields[0]
+				} else {
+					s.Name = strings.TrimSuffix(filepath.Base(f.Path), filepath.Ext(f.Path))
+				}
+				if !nameRe.MatchString(s.Name) {
+					return nil, fmt.Errorf("%s: invalid snapshot name %q", pos, s.Name)
+				}
+				if prev, ok := seen[s.Name]; ok {
+					return nil, fmt.Errorf("%s: snapshot %q is already declared at %s", pos, s.Name, prev)
+				}
+				seen[s.Name] = pos
+				switch {
+				case len(fields) > 1:
+					for _, e := range fields[1:] {
+						s.Entries = append(s.Entries, filepath.Join(dir, e))
+					}
+				case source.IsTestFile(f.Path):
+					s.Entries = pkgFiles
+				default:
+					s.Entries = []string{f.Path}
+				}
+				snaps = append(snaps, s)
+			}
+		}
+	}
+	return snaps, nil
+}
+
+// Run renders every snapshot and compares it with the stored snapshot file.
+// When update is set, mismatching and missing snapshot files are rewritten.
+func Run(snaps []*Snapshot, render Renderer, update bool) []*Result {
+	var results []*Result
+	for _, s := range snaps {
+		results = append(results, check(s, render, update))
+	}
+	return results
+}
+
+func check(s *Snapshot, render Renderer, update bool) *Result {
+	r := &Result{Snapshot: s}
+	got, err := render(s.Entries)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	if !strings.HasSuffix(got, "\n") {
+		got += "\n"
+	}
+	want, err := os.ReadFile(s.Path())
+	switch {
+	case os.IsNotExist(err):
+		r.Missing = true
+	case err != nil:
+		r.Err = err
+		return r
+	default:
+		r.Diff, r.Err = Compare(s.Path(), string(want), got)
+		if r.Err != nil {
+			return r
+		}
+	}
+	if update && !r.Pass() {
+		if err := os.MkdirAll(filepath.Dir(s.Path()), 0755); err != nil {
+			r.Err = err
+			return r
+		}
+		if err := os.WriteFile(s.Path(), []byte(got), 0644); err != nil {
+			r.Err = err
+			return r
+		}
+		r.Missing, r.Diff, r.Updated = false, "", true
+	}
+	return r
+}
+
+// Compare compares two YAML streams semantically: mapping key order and
+// scalar formatting are ignored. It returns an empty string when both are
+// equal, or a unified diff of their normalized forms otherwise.
+func Compare(name, want, got string) (string, error) {
+	wantNorm, err := normalize(want)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse snapshot %s: %w", name, err)
+	}
+	gotNorm, err := normalize(got)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse rendered output: %w", err)
+	}
+	return diff.Unified(name, "rendered", wantNorm, gotNorm), nil
+}
+
+// normalize parses a YAML stream and prints it back with sorted keys and
+// canonical scalars.
+func normalize(stream string) (string, error) {
+	if strings.TrimSpace(stream) == "" {
+		return "", nil
+	}
+	docs, err := yamlfmt.ParseStream(stream)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for i, doc := range docs {
+		if i > 0 {
+			sb.WriteString("---\n")
+		}
+		out, err := yaml.MarshalWithOptions(canonical(doc), yaml.Indent(2), yaml.IndentSequence(true))
+		if err != nil {
+			return "", err
+		}
+		sb.Write(out)
+	}
+	return sb.String(), nil
+}
+
+// canonical converts numbers to a single representation so that e.g. `1`
+// and `1.0` compare equal. Integral values are represented as int64.
+func canonical(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, val := range v {
+			out[k] = canonical(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = canonical(val)
+		}
+		return out
+	case uint64:
+		if v <= math.MaxInt64 {
+			return int64(v)
+		}
+		return v
+	case int:
+		return int64(v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// Report writes a human readable report of the results and returns the
+// number of failed snapshots.
+func Report(w io.Writer, results []*Result) int {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Snapshot.Path() < results[j].Snapshot.Path()
+	})
+	failed := 0
+	for _, r := range results {
+		name := r.Snapshot.Name
+		switch {
+		case r.Err != nil:
+			failed++
+			fmt.Fprintf(w, "snapshot %s: FAIL\n  %s: %v\n", name, r.Snapshot.Pos, r.Err)
+		case r.Updated:
+			fmt.Fprintf(w, "snapshot %s: UPDATED\n", name)
+		case r.Missing:
+			failed++
+			fmt.Fprintf(w, "snapshot %s: FAIL\n  %s: snapshot file %s does not exist, run with --update-snapshots to create it\n",
+				name, r.Snapshot.Pos, r.Snapshot.Path())
+		case r.Diff != "":
+			failed++
+			fmt.Fprintf(w, "snapshot %s: FAIL\n  %s: rendered output does not match %s\n%s", name, r.Snapshot.Pos, r.Snapshot.Path(), r.Diff)
+		default:
+			fmt.Fprintf(w, "snapshot %s: PASS\n", name)
+		}
+	}
+	return failed
+}
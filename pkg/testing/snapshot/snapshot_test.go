@@ -0,0 +1,102 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "app.k"), "# kcl-test: snapshot\napp = 1\n")
+	writeFile(t, filepath.Join(dir, "base.k"), "base = 1\n")
+	writeFile(t, filepath.Join(dir, "main_test.k"), "# kcl-test: snapshot all\n# kcl-test: snapshot base base.k\n")
+
+	snaps, err := Discover([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string][]string{}
+	for _, s := range snaps {
+		got[s.Name] = s.Entries
+	}
+	want := map[string][]string{
+		"app":  {filepath.Join(dir, "app.k")},
+		"all":  {filepath.Join(dir, "app.k"), filepath.Join(dir, "base.k")},
+		"base": {filepath.Join(dir, "base.k")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	writeFile(t, filepath.Join(dir, "dup.k"), "# kcl-test: snapshot app\n")
+	if _, err := Discover([]string{dir}); err == nil || !strings.Contains(err.Error(), "already declared") {
+		t.Errorf("expected a duplicate declaration error, got %v", err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	diff, err := Compare("want", "b: 2\na: 1.0\nc:\n  - x\n", "a: 1\nc: [x]\nb: 2\n")
+	if err != nil || diff != "" {
+		t.Errorf("expected no difference, got %q, %v", diff, err)
+	}
+	diff, err = Compare("want", "a: 1\nb: 2\n", "a: 1\nb: 3\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, "-b: 2\n+b: 3\n") {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	s := &Snapshot{Name: "app", Dir: dir, Entries: []string{"app.k"}}
+	render := func(entries []string) (string, error) {
+		return "name: app\nreplicas: 2", nil
+	}
+
+	results := Run([]*Snapshot{s}, render, false)
+	if !results[0].Missing {
+		t.Fatalf("expected a missing snapshot, got %+v", results[0])
+	}
+
+	results = Run([]*Snapshot{s}, render, true)
+	if !results[0].Updated || !results[0].Pass() {
+		t.Fatalf("expected an updated snapshot, got %+v", results[0])
+	}
+	content, err := os.ReadFile(s.Path())
+	if err != nil || string(content) != "name: app\nreplicas: 2\n" {
+		t.Errorf("unexpected snapshot content %q, %v", content, err)
+	}
+
+	writeFile(t, s.Path(), "replicas: 2\nname: app\n")
+	results = Run([]*Snapshot{s}, render, false)
+	if !results[0].Pass() {
+		t.Errorf("expected key order to be ignored, got %+v", results[0])
+	}
+
+	results = append(results, Run([]*Snapshot{s}, func([]string) (string, error) {
+		return "", errors.New("compile error")
+	}, false)...)
+	var buf bytes.Buffer
+	if failed := Report(&buf, results); failed != 1 {
+		t.Errorf("got %d failures, want 1:\n%s", failed, buf.String())
+	}
+}
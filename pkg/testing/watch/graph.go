@@ -0,0 +1,118 @@
+// Copyright The KCL Authors. All rights reserved.
+
+// Package watch tracks the KCL files of a workspace and selects the packages
+// affected by file changes through an in-memory import graph.
+package watch
+
+import (
+	"path/filepath"
+	"sort"
+
+	"kcl-lang.io/cli/pkg/source"
+)
+
+// Graph is the local import graph of the KCL packages in a workspace. Nodes
+// are absolute package directories.
+type Graph struct {
+	root string
+	// imports maps a package to the local packages it imports.
+	imports map[string]map[string]bool
+}
+
+// NewGraph builds the import graph of all the KCL packages under root.
+func NewGraph(root string) (*Graph, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	g := &Graph{root: abs, imports: map[string]map[string]bool{}}
+	dirs, err := source.PackageDirs([]string{abs + string(filepath.Separator) + "..."})
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		if err := g.Update(dir); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// Update re-reads the imports of the package in dir. Packages that no longer
+// exist or contain no KCL files are removed from the graph.
+func (g *Graph) Update(dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	pkg, err := source.LoadPackage(dir)
+	if err != nil || len(pkg.Files)+len(pkg.TestFiles) == 0 {
+		delete(g.imports, dir)
+		return nil
+	}
+	deps := map[string]bool{}
+	for _, f := range append(pkg.Files, pkg.TestFiles...) {
+		for _, imp := range f.Imports {
+			target, ok := source.ResolveImport(g.root, dir, imp.Path)
+			if !ok {
+				continue
+			}
+			if source.IsKclFile(target) {
+				target = filepath.Dir(target)
+			}
+			if target != dir {
+				deps[target] = true
+			}
+		}
+	}
+	g.imports[dir] = deps
+	return nil
+}
+
+// Imports returns the local packages directly imported by the package dir.
+func (g *Graph) Imports(dir string) []string {
+	var deps []string
+	for d := range g.imports[dir] {
+		deps = append(deps, d)
+	}
+	sort.Strings(deps)
+	return deps
+}
+
+// Affected returns the packages among candidates that are changed or that
+// transitively import one of the changed packages. All the paths are
+// compared in their absolute form; the candidates are returned as given.
+func (g *Graph) Affected(changed, candidates []string) []string {
+	importers := map[string][]string{}
+	for dir, deps := range g.imports {
+		for dep := range deps {
+			importers[dep] = append(importers[dep], dir)
+		}
+	}
+	// Walk the reverse imports from the changed packages.
+	dirty := map[string]bool{}
+	var queue []string
+	for _, c := range changed {
+		if abs, err := filepath.Abs(c); err == nil && !dirty[abs] {
+			dirty[abs] = true
+			queue = append(queue, abs)
+		}
+	}
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+		for _, importer := range importers[dir] {
+			if !dirty[importer] {
+				dirty[importer] = true
+				queue = append(queue, importer)
+			}
+		}
+	}
+	var affected []string
+	for _, c := range candidates {
+		if abs, err := filepath.Abs(c); err == nil && dirty[abs] {
+			affected = append(affected, c)
+		}
+	}
+	return affected
+}
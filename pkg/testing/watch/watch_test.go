@@ -0,0 +1,121 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newWorkspace(t *testing.T) string {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "kcl.mod"), "[package]\nname = \"ws\"\n")
+	writeFile(t, filepath.Join(root, "base", "base.k"), "schema Base:\n    name: str\n")
+	writeFile(t, filepath.Join(root, "app", "app.k"), "import base\n\nschema App(base.Base):\n    replicas: int\n")
+	writeFile(t, filepath.Join(root, "app", "app_test.k"), "test_app = lambda {\n    assert App {name = \"a\", replicas = 1}\n}\n")
+	writeFile(t, filepath.Join(root, "web", "web.k"), "import app\n\nweb = app.App {name = \"web\", replicas = 1}\n")
+	writeFile(t, filepath.Join(root, "other", "other.k"), "other = 1\n")
+	return root
+}
+
+func TestGraphAffected(t *testing.T) {
+	root := newWorkspace(t)
+	g, err := NewGraph(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := g.Imports(filepath.Join(root, "web")), []string{filepath.Join(root, "app")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("imports: got %v, want %v", got, want)
+	}
+	candidates := []string{
+		filepath.Join(root, "app"),
+		filepath.Join(root, "base"),
+		filepath.Join(root, "other"),
+		filepath.Join(root, "web"),
+	}
+	got := g.Affected([]string{filepath.Join(root, "base")}, candidates)
+	want := []string{filepath.Join(root, "app"), filepath.Join(root, "base"), filepath.Join(root, "web")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("affected: got %v, want %v", got, want)
+	}
+}
+
+func TestGraphAffectedCycle(t *testing.T) {
+	root := t.TempDir()
+	a, b, c := filepath.Join(root, "a"), filepath.Join(root, "b"), filepath.Join(root, "c")
+	// a and b import each other, and b imports c.
+	g := &Graph{root: root, imports: map[string]map[string]bool{
+		a: {b: true},
+		b: {a: true, c: true},
+		c: {},
+	}}
+	// Each run iterates the maps in another order.
+	for i := 0; i < 20; i++ {
+		if got, want := g.Affected([]string{c}, []string{a, b}), []string{a, b}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("affected: got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestWatcherPoll(t *testing.T) {
+	root := newWorkspace(t)
+	w, err := New(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	candidates := []string{filepath.Join(root, "app"), filepath.Join(root, "other")}
+
+	change, err := w.Poll()
+	if err != nil || len(change.Files) != 0 {
+		t.Fatalf("expected no change, got %+v, %v", change, err)
+	}
+
+	// Make the web package import the other package, then change other.
+	later := time.Now().Add(time.Second)
+	web := filepath.Join(root, "web", "web.k")
+	writeFile(t, web, "import app\nimport other\n\nweb = app.App {name = \"web\", replicas = other.other}\n")
+	if err := os.Chtimes(web, later, later); err != nil {
+		t.Fatal(err)
+	}
+	change, err = w.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(root, "web")}; !reflect.DeepEqual(change.Dirs, want) {
+		t.Errorf("changed dirs: got %v, want %v", change.Dirs, want)
+	}
+	if got := w.Graph.Imports(filepath.Join(root, "web")); len(got) != 2 {
+		t.Errorf("expected the graph to be updated, got %v", got)
+	}
+
+	os.Remove(filepath.Join(root, "base", "base.k"))
+	change, err = w.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := w.Affected(change, candidates), []string{filepath.Join(root, "app")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("affected: got %v, want %v", got, want)
+	}
+
+	writeFile(t, filepath.Join(root, "kcl.mod.lock"), "")
+	change, err = w.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !change.Manifest || !reflect.DeepEqual(w.Affected(change, candidates), candidates) {
+		t.Errorf("expected a manifest change to affect every package, got %+v", change)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"kcl-lang.io/cli/pkg/source"
+)
+
+// DefaultInterval is the default polling interval of the watcher.
+const DefaultInterval = 500 * time.Millisecond
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watcher polls a workspace for changes of KCL files and module manifests
+// and keeps the import graph of the workspace up to date.
+type Watcher struct {
+	Root     string
+	Interval time.Duration
+	Graph    *Graph
+	files    map[string]fileState
+}
+
+// New returns a watcher of the workspace rooted at root.
+func New(root string) (*Watcher, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	g, err := NewGraph(abs)
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{Root: abs, Interval: DefaultInterval, Graph: g}
+	w.files, err = w.scan()
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Change is the set of changes found by the watcher.
+type Change struct {
+	// Files are the added, modified or removed files.
+	Files []string
+	// Dirs are the package directories containing changed KCL files.
+	Dirs []string
+	// Manifest reports whether a kcl.mod or kcl.mod.lock file changed,
+	// which may affect every package of the workspace.
+	Manifest bool
+}
+
+// Wait blocks until files change or the context is done. The import graph is
+// updated for the changed packages before Wait returns.
+func (w *Watcher) Wait(ctx context.Context) (*Change, error) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+		change, err := w.Poll()
+		if err != nil {
+			return nil, err
+		}
+		if len(change.Files) > 0 {
+			return change, nil
+		}
+	}
+}
+
+// Poll compares the workspace with the last known state and returns the
+// changes found since the previous call.
+func (w *Watcher) Poll() (*Change, error) {
+	current, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+	change := &Change{}
+	dirs := map[string]bool{}
+	record := func(path string) {
+		change.Files = append(change.Files, path)
+		base := filepath.Base(path)
+		if base == source.ModFile || strings.HasPrefix(base, source.ModFile+".") {
+			change.Manifest = true
+		} else {
+			dirs[filepath.Dir(path)] = true
+		}
+	}
+	for path, st := range current {
+		if old, ok := w.files[path]; !ok || !old.modTime.Equal(st.modTime) || old.size != st.size {
+			record(path)
+		}
+	}
+	for path := range w.files {
+		if _, ok := current[path]; !ok {
+			record(path)
+		}
+	}
+	w.files = current
+	for dir := range dirs {
+		change.Dirs = append(change.Dirs, dir)
+		if err := w.Graph.Update(dir); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(change.Files)
+	sort.Strings(change.Dirs)
+	return change, nil
+}
+
+// Affected returns the packages among candidates affected by the change.
+func (w *Watcher) Affected(change *Change, candidates []string) []string {
+	if change.Manifest {
+		return candidates
+	}
+	return w.Graph.Affected(change.Dirs, candidates)
+}
+
+func (w *Watcher) scan() (map[string]fileState, error) {
+	files := map[string]fileState{}
+	err := filepath.WalkDir(w.Root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != w.Root && (strings.HasPrefix(name, ".") || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !source.IsKclFile(name) && name != source.ModFile && name != source.ModFile+".lock" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,109 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package roundtrip
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	original := `{
+  "title": "Config",
+  "type": "object",
+  "required": ["name"],
+  "additionalProperties": false,
+  "properties": {
+    "name": {"type": "string", "description": "The name.", "pattern": "^[a-z]+$"},
+    "port": {"type": "integer", "minimum": 1, "maximum": 65535},
+    "mode": {"type": "string", "enum": ["b", "a"], "format": "hostname"},
+    "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+    "owner": {"$ref": "#/definitions/person"},
+    "server": {"type": "object", "properties": {"host": {"type": "string"}}},
+    "removed": {"type": "string"}
+  },
+  "definitions": {
+    "person": {"type": "object", "properties": {"email": {"type": "string"}}}
+  }
+}`
+	exported := `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "app",
+  "$defs": {
+    "models.Config": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string", "pattern": "^[a-z]+$"},
+        "port": {"type": "integer", "minimum": 1, "exclusiveMaximum": 65536},
+        "mode": {"type": "string", "enum": ["a", "b"]},
+        "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+        "owner": {"$ref": "#/$defs/models.Person"},
+        "server": {"$ref": "#/$defs/models.ConfigServer"}
+      },
+      "required": ["name", "port"]
+    },
+    "models.Person": {"type": "object", "properties": {"email": {"type": "string"}}},
+    "models.ConfigServer": {"type": "object", "properties": {"host": {"type": "string"}}}
+  }
+}`
+	o, err := Load([]byte(original), "original")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Load([]byte(exported), "exported")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, l := range Compare(o, r) {
+		got = append(got, l.String())
+	}
+	want := []string{
+		"Config: additionalProperties lost",
+		"Config.mode: format lost",
+		"Config.name: description lost",
+		"Config.port: required added",
+		"Config.port: maximum lost",
+		"Config.removed: property lost",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Compare() = %#v, want %#v", got, want)
+	}
+}
+
+func TestLoadCRD(t *testing.T) {
+	crd := `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+spec:
+  names:
+    kind: Widget
+  versions:
+    - name: v1
+      schema:
+        openAPIV3Schema:
+          type: object
+          properties:
+            spec:
+              type: object
+              properties:
+                size:
+                  type: integer
+                  minimum: 0
+                  exclusiveMinimum: true
+`
+	schemas, err := Load([]byte(crd), "widget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range schemas {
+		names = append(names, s.Name)
+	}
+	if want := []string{"Widget", "WidgetSpec"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("Load() schemas = %v, want %v", names, want)
+	}
+	size := schemas[1].Properties["size"]
+	if want := map[string]any{"type": "integer", "exclusiveMinimum": float64(0)}; !reflect.DeepEqual(size.Keywords, want) {
+		t.Errorf("Load() size keywords = %v, want %v", size.Keywords, want)
+	}
+}
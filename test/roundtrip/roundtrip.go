@@ -0,0 +1,439 @@
+// Copyright The KCL Authors. All rights reserved.
+
+// Package roundtrip compares the schemas imported by `kcl import` and
+// exported back by `kcl export` with the original schemas, and reports the
+// constructs lost on the way.
+package roundtrip
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Schema is the normalized form of an object schema.
+type Schema struct {
+	Name        string
+	Description string
+	Properties  map[string]*Property
+	Required    map[string]bool
+	// Keywords are the other schema level keywords, such as
+	// `additionalProperties` or `oneOf`.
+	Keywords map[string]any
+}
+
+// Property is the normalized form of a property schema.
+type Property struct {
+	Description string
+	// Ref is the name of the referenced schema, if any.
+	Ref string
+	// Items is the normalized item type of arrays.
+	Items *Property
+	// Keywords are the other keywords, such as `type`, `enum` or `minimum`.
+	Keywords map[string]any
+}
+
+// ignored are the keywords without semantic impact on the compared values.
+var ignored = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true, "examples": true, "example": true,
+	"x-kcl-checks": true, "x-kcl-type": true, "x-kcl-decorators": true,
+}
+
+// Load returns the normalized object schemas of a document: a JSON Schema
+// with its `definitions` and `$defs`, a Swagger or OpenAPI document, or
+// Kubernetes CRDs. Inline object properties become schemas named after their
+// parent and property in PascalCase, as `kcl import` names them.
+func Load(data []byte, name string) ([]*Schema, error) {
+	var docs []map[string]any
+	for _, part := range regexp.MustCompile(`(?m)^---\s*$`).Split(string(data), -1) {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		var doc map[string]any
+		if err := yaml.Unmarshal([]byte(part), &doc); err != nil {
+			return nil, err
+		}
+		docs = append(docs, normalizeValue(doc).(map[string]any))
+	}
+	l := &loader{schemas: map[string]*Schema{}}
+	for _, doc := range docs {
+		if doc["kind"] == "CustomResourceDefinition" {
+			spec, _ := doc["spec"].(map[string]any)
+			names, _ := spec["names"].(map[string]any)
+			versions, _ := spec["versions"].([]any)
+			for _, v := range versions {
+				if obj, ok := lookup(v, "schema", "openAPIV3Schema").(map[string]any); ok {
+					l.add(fmt.Sprint(names["kind"]), obj)
+				}
+			}
+			continue
+		}
+		for _, key := range [][]string{{"definitions"}, {"$defs"}, {"components", "schemas"}} {
+			defs, _ := lookup(doc, key...).(map[string]any)
+			for _, n := range sortedKeys(defs) {
+				if obj, ok := defs[n].(map[string]any); ok {
+					l.add(n, obj)
+				}
+			}
+		}
+		if _, ok := doc["properties"]; ok {
+			root := name
+			if title, ok := doc["title"].(string); ok {
+				root = title
+			}
+			l.add(root, doc)
+		}
+	}
+	schemas := make([]*Schema, 0, len(l.schemas))
+	for _, s := range l.schemas {
+		schemas = append(schemas, s)
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Name < schemas[j].Name })
+	return schemas, nil
+}
+
+type loader struct {
+	schemas map[string]*Schema
+}
+
+func (l *loader) add(name string, obj map[string]any) {
+	s := &Schema{Name: name, Properties: map[string]*Property{}, Required: map[string]bool{}, Keywords: map[string]any{}}
+	l.schemas[name] = s
+	for k, v := range obj {
+		switch {
+		case k == "description":
+			s.Description, _ = v.(string)
+		case k == "properties":
+			props, _ := v.(map[string]any)
+			for p, ps := range props {
+				if m, ok := ps.(map[string]any); ok {
+					s.Properties[p] = l.property(name+pascal(p), m)
+				}
+			}
+		case k == "required":
+			list, _ := v.([]any)
+			for _, r := range list {
+				s.Required[fmt.Sprint(r)] = true
+			}
+		case k == "type" || k == "definitions" || k == "$defs" || ignored[k]:
+		default:
+			s.Keywords[k] = refNames(v)
+		}
+	}
+}
+
+func (l *loader) property(name string, obj map[string]any) *Property {
+	p := &Property{Keywords: map[string]any{}}
+	if _, ok := obj["properties"]; ok {
+		l.add(name, obj)
+		p.Ref = name
+		if desc, ok := obj["description"].(string); ok {
+			p.Description = desc
+		}
+		return p
+	}
+	for k, v := range obj {
+		switch {
+		case k == "description":
+			p.Description, _ = v.(string)
+		case k == "$ref":
+			ref := fmt.Sprint(v)
+			p.Ref = ref[strings.LastIndex(ref, "/")+1:]
+		case k == "items":
+			if m, ok := v.(map[string]any); ok {
+				p.Items = l.property(name+"Item", m)
+			}
+		case k == "enum":
+			list, _ := v.([]any)
+			values := make([]string, len(list))
+			for i, e := range list {
+				values[i] = fmt.Sprint(e)
+			}
+			sort.Strings(values)
+			p.Keywords[k] = values
+		case k == "exclusiveMinimum" || k == "exclusiveMaximum":
+			// OpenAPI 3.0 exclusive bounds are booleans applied to the
+			// minimum and maximum.
+			if b, ok := v.(bool); ok {
+				if b {
+					bound := map[string]string{"exclusiveMinimum": "minimum", "exclusiveMaximum": "maximum"}[k]
+					p.Keywords[k] = obj[bound]
+				}
+				continue
+			}
+			p.Keywords[k] = v
+		case ignored[k]:
+		default:
+			p.Keywords[k] = refNames(v)
+		}
+	}
+	if b, ok := obj["exclusiveMinimum"].(bool); ok && b {
+		delete(p.Keywords, "minimum")
+	}
+	if b, ok := obj["exclusiveMaximum"].(bool); ok && b {
+		delete(p.Keywords, "maximum")
+	}
+	return p
+}
+
+// Loss is a construct of an original schema lost or changed by the round
+// trip.
+type Loss struct {
+	// Path is the schema name, followed by the property name if any.
+	Path   string
+	Detail string
+}
+
+// String formats the loss as `path: detail`.
+func (l Loss) String() string {
+	return l.Path + ": " + l.Detail
+}
+
+// Compare returns the constructs of the original schemas that the round trip
+// lost or changed, sorted by path. The original schemas are matched with the
+// round tripped ones by name, or by their property names.
+func Compare(original, roundTripped []*Schema) []Loss {
+	match := matchSchemas(original, roundTripped)
+	var losses []Loss
+	add := func(path, format string, args ...any) {
+		losses = append(losses, Loss{Path: path, Detail: fmt.Sprintf(format, args...)})
+	}
+	for _, o := range original {
+		r := match[o.Name]
+		if r == nil {
+			add(o.Name, "schema lost")
+			continue
+		}
+		if o.Description != "" && r.Description == "" {
+			add(o.Name, "description lost")
+		}
+		for _, k := range sortedKeys(o.Keywords) {
+			if got, ok := r.Keywords[k]; !ok {
+				add(o.Name, "%s lost", k)
+			} else if !reflect.DeepEqual(got, o.Keywords[k]) {
+				add(o.Name, "%s changed: %s -> %s", k, format(o.Keywords[k]), format(got))
+			}
+		}
+		for _, name := range sortedKeys(o.Properties) {
+			path := o.Name + "." + name
+			rp, ok := r.Properties[name]
+			if !ok {
+				add(path, "property lost")
+				continue
+			}
+			if o.Required[name] && !r.Required[name] {
+				add(path, "required lost")
+			} else if !o.Required[name] && r.Required[name] {
+				add(path, "required added")
+			}
+			for _, d := range compareProperty(o.Properties[name], rp, match) {
+				add(path, "%s", d)
+			}
+		}
+	}
+	sort.SliceStable(losses, func(i, j int) bool { return losses[i].Path < losses[j].Path })
+	return losses
+}
+
+func compareProperty(o, r *Property, match map[string]*Schema) []string {
+	var diffs []string
+	if o.Description != "" && r.Description == "" {
+		diffs = append(diffs, "description lost")
+	}
+	if o.Ref != "" {
+		if target := match[o.Ref]; target == nil || normalizeName(target.Name) != normalizeName(r.Ref) {
+			diffs = append(diffs, fmt.Sprintf("reference changed: %s -> %s", o.Ref, orNone(r.Ref)))
+		}
+	}
+	if o.Items != nil {
+		if r.Items == nil {
+			diffs = append(diffs, "items lost")
+		} else {
+			for _, d := range compareProperty(o.Items, r.Items, match) {
+				diffs = append(diffs, "items "+d)
+			}
+		}
+	}
+	for _, k := range sortedKeys(o.Keywords) {
+		got, ok := r.Keywords[k]
+		switch {
+		case k == "type" && o.Keywords[k] == "object" && r.Ref != "":
+			// A reference to a schema is an object.
+		case !ok:
+			diffs = append(diffs, k+" lost")
+		case !reflect.DeepEqual(got, o.Keywords[k]):
+			diffs = append(diffs, fmt.Sprintf("%s changed: %s -> %s", k, format(o.Keywords[k]), format(got)))
+		}
+	}
+	return diffs
+}
+
+// matchSchemas maps the original schema names to the round tripped schemas
+// with the same normalized name, or else sharing the most property names.
+func matchSchemas(original, roundTripped []*Schema) map[string]*Schema {
+	match := map[string]*Schema{}
+	used := map[*Schema]bool{}
+	for _, o := range original {
+		for _, r := range roundTripped {
+			if !used[r] && normalizeName(o.Name) == normalizeName(r.Name) {
+				match[o.Name], used[r] = r, true
+				break
+			}
+		}
+	}
+	for _, o := range original {
+		if match[o.Name] != nil {
+			continue
+		}
+		var best *Schema
+		bestScore := 0.5
+		for _, r := range roundTripped {
+			if used[r] {
+				continue
+			}
+			if score := similarity(o, r); score >= bestScore {
+				best, bestScore = r, score
+			}
+		}
+		if best != nil {
+			match[o.Name], used[best] = best, true
+		}
+	}
+	return match
+}
+
+var nonAlnum = regexp.MustCompile(`[^a-z0-9]`)
+
+// normalizeName returns the lower case alphanumeric name of a schema without
+// its package.
+func normalizeName(name string) string {
+	name = name[strings.LastIndex(name, ".")+1:]
+	return nonAlnum.ReplaceAllString(strings.ToLower(name), "")
+}
+
+// similarity returns the Jaccard index of the property names.
+func similarity(a, b *Schema) float64 {
+	union := map[string]bool{}
+	common := 0
+	for p := range a.Properties {
+		union[p] = true
+		if _, ok := b.Properties[p]; ok {
+			common++
+		}
+	}
+	for p := range b.Properties {
+		union[p] = true
+	}
+	if len(union) == 0 {
+		return 0
+	}
+	return float64(common) / float64(len(union))
+}
+
+// Report formats the losses of a file, one per line.
+func Report(losses []Loss) string {
+	var b strings.Builder
+	for _, l := range losses {
+		b.WriteString(l.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// normalizeValue converts YAML values to their JSON equivalents so that the
+// values of both formats compare equal.
+func normalizeValue(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+// refNames returns a copy of v with the `$ref` values replaced by the
+// normalized names of the referenced schemas, so that references compare
+// equal whatever the location of the definitions.
+func refNames(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, val := range v {
+			if ref, ok := val.(string); ok && k == "$ref" {
+				out[k] = normalizeName(ref[strings.LastIndex(ref, "/")+1:])
+			} else if !ignored[k] && k != "description" {
+				out[k] = refNames(val)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = refNames(val)
+		}
+		return out
+	}
+	return v
+}
+
+func lookup(v any, keys ...string) any {
+	for _, k := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
+
+func format(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// pascal converts a snake case, kebab case or camel case name to PascalCase.
+func pascal(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '_' || r == '-' || r == '.' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
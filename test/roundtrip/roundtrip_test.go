@@ -0,0 +1,127 @@
+// Copyright The KCL Authors. All rights reserved.
+
+package roundtrip
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"kcl-lang.io/cli/pkg/options"
+)
+
+var update = flag.Bool("update", false, "update the golden lossy construct reports")
+
+// TestRoundTrip imports each fixture of testdata/<mode> with the import mode
+// named by its directory, exports the imported module as a JSON Schema bundle
+// and reports the constructs of the fixture lost on the way. Schemas,
+// properties, references and required properties must survive the round
+// trip, and the report of a fixture must match its golden `<fixture>.lossy`
+// file; run the test with -update to record the reports.
+func TestRoundTrip(t *testing.T) {
+	modes := map[string]string{
+		"jsonschema": options.JsonSchema,
+		"openapi":    options.OpenAPI,
+		"crd":        options.Crd,
+	}
+	for dir, mode := range modes {
+		fixtures, err := filepath.Glob(filepath.Join("testdata", dir, "*"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, fixture := range fixtures {
+			if filepath.Ext(fixture) == ".lossy" {
+				continue
+			}
+			fixture, mode := fixture, mode
+			t.Run(filepath.Join(dir, filepath.Base(fixture)), func(t *testing.T) {
+				losses := roundTrip(t, fixture, mode)
+				for _, l := range losses {
+					if structural(l) {
+						t.Errorf("%s: %s", fixture, l)
+					}
+				}
+				report := Report(losses)
+				t.Logf("lossy constructs of %s:\n%s", fixture, report)
+				golden := strings.TrimSuffix(fixture, filepath.Ext(fixture)) + ".lossy"
+				if *update {
+					if err := os.WriteFile(golden, []byte(report), 0644); err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+				want, err := os.ReadFile(golden)
+				if os.IsNotExist(err) {
+					t.Fatalf("missing golden file %s, run with -update to record it", golden)
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				if report != string(want) {
+					t.Errorf("lossy constructs of %s changed, run with -update if expected.\ngot:\n%s\nwant:\n%s", fixture, report, want)
+				}
+			})
+		}
+	}
+}
+
+// structural reports whether a loss changes the shape of the values accepted
+// by the schemas, rather than a keyword KCL cannot express.
+func structural(l Loss) bool {
+	switch l.Detail {
+	case "schema lost", "property lost", "required lost", "required added":
+		return true
+	}
+	detail := l.Detail
+	for strings.HasPrefix(detail, "items ") {
+		detail = strings.TrimPrefix(detail, "items ")
+	}
+	return detail == "lost" || strings.HasPrefix(detail, "reference changed")
+}
+
+// roundTrip imports the fixture into a new module, exports it and returns the
+// lossy constructs.
+func roundTrip(t *testing.T, fixture, mode string) []Loss {
+	t.Helper()
+	original, err := os.ReadFile(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	module := t.TempDir()
+	if err := os.WriteFile(filepath.Join(module, "kcl.mod"), []byte("[package]\nname = \"roundtrip\"\nversion = \"0.0.1\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	imp := options.NewImportOptions()
+	imp.Mode = mode
+	imp.Files = []string{fixture}
+	imp.Force = true
+	if mode == options.JsonSchema {
+		imp.Output = filepath.Join(module, "models.k")
+	} else {
+		imp.Output = module
+		imp.ModelPackage = "models"
+	}
+	if err := imp.Run(); err != nil {
+		t.Fatalf("failed to import %s: %v", fixture, err)
+	}
+	var exported bytes.Buffer
+	exp := options.NewExportOptions()
+	exp.Path = module
+	exp.Output = "-"
+	exp.Writer = &exported
+	if err := exp.Run(); err != nil {
+		t.Fatalf("failed to export %s: %v", fixture, err)
+	}
+	name := strings.TrimSuffix(filepath.Base(fixture), filepath.Ext(fixture))
+	want, err := Load(original, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load(exported.Bytes(), name)
+	if err != nil {
+		t.Fatalf("invalid export of %s: %v", fixture, err)
+	}
+	return Compare(want, got)
+}